@@ -2,14 +2,21 @@ package api
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
 	"time"
 
 	"github.com/danielgtaylor/huma/v2"
 	"github.com/danielgtaylor/huma/v2/adapters/humachi"
 	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
 
 	"github.com/gdg-garage/garage-trip-chores/chores"
 	"github.com/gdg-garage/garage-trip-chores/storage"
@@ -17,10 +24,13 @@ import (
 )
 
 type Config struct {
-	Port    int      `json:"port"`
-	Host    string   `json:"host"`
-	Cors    bool     `json:"cors"`
-	ApiKeys []string `json:"apikeys"`
+	Port    int         `json:"port"`
+	Host    string      `json:"host"`
+	Cors    bool        `json:"cors"`
+	ApiKeys []string    `json:"apikeys"`
+	OAuth   OAuthConfig `json:"oauth"`
+	// How long an Idempotency-Key is remembered.
+	IdempotencyHours int `json:"idempotencyhours"`
 }
 
 type Api struct {
@@ -29,7 +39,6 @@ type Api struct {
 	chores         *chores.ChoresLogic
 	ui             *ui.Ui
 	conf           Config
-	hub            *WsHub
 	authorizedKeys map[string]struct{}
 }
 
@@ -45,19 +54,9 @@ func NewApi(s *storage.Storage, logger *slog.Logger, c *chores.ChoresLogic, ui *
 		chores:         c,
 		ui:             ui,
 		conf:           conf,
-		hub:            NewWsHub(logger),
 		authorizedKeys: auth,
 	}
 
-	go api.hub.Run()
-
-	go func() {
-		sub := api.storage.Events.Subscribe()
-		for event := range sub {
-			api.hub.BroadcastEvent(event)
-		}
-	}()
-
 	return api
 }
 
@@ -70,7 +69,8 @@ func (a *Api) SetupRoutes() *chi.Mux {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			w.Header().Set("Access-Control-Allow-Origin", "*")
 			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
-			w.Header().Set("Access-Control-Allow-Headers", "Accept, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization")
+			w.Header().Set("Access-Control-Allow-Headers", "Accept, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, If-Match, Idempotency-Key")
+			w.Header().Set("Access-Control-Expose-Headers", "ETag, Next-Cursor, Idempotent-Replayed")
 			if r.Method == http.MethodOptions {
 				w.WriteHeader(http.StatusOK)
 				return
@@ -79,48 +79,31 @@ func (a *Api) SetupRoutes() *chi.Mux {
 		})
 	})
 
-	// API Auth middleware
-	authMiddleware := func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			if len(a.authorizedKeys) == 0 {
-				next.ServeHTTP(w, r)
-				return
-			}
-
-			// Skip auth for OpenAPI, AsyncAPI docs, and health checks
-			if r.URL.Path == "/openapi.json" || r.URL.Path == "/openapi.yaml" || r.URL.Path == "/docs" || r.URL.Path == "/ws/docs" || r.URL.Path == "/ws/asyncapi.yaml" || r.URL.Path == "/health" {
-				next.ServeHTTP(w, r)
-				return
-			}
-			authHeader := r.Header.Get("Authorization")
-			token := ""
-			if len(authHeader) > 7 && authHeader[:7] == "Bearer " {
-				token = authHeader[7:]
-			}
-			if _, ok := a.authorizedKeys[token]; !ok {
-				http.Error(w, "Unauthorized", http.StatusUnauthorized)
-				return
-			}
-			next.ServeHTTP(w, r)
-		})
-	}
-
-	router.Use(authMiddleware)
+	router.Use(a.authMiddleware)
 
 	// Setup Huma
 	config := huma.DefaultConfig("Garage Trip Chores API", "1.0.0")
-	if len(a.authorizedKeys) > 0 {
+	if a.authRequired() {
 		config.Components.SecuritySchemes = map[string]*huma.SecurityScheme{
 			"bearerAuth": {
 				Type:         "http",
 				Scheme:       "bearer",
 				BearerFormat: "API Key",
-				Description:  "Enter your API key provided in the configuration",
+				Description:  "Enter your API key, created by POST /apikeys or the apikey command",
 			},
 		}
 		config.Security = []map[string][]string{
 			{"bearerAuth": {}},
 		}
+		if a.conf.OAuth.enabled() {
+			config.Components.SecuritySchemes["sessionCookie"] = &huma.SecurityScheme{
+				Type:        "apiKey",
+				In:          "cookie",
+				Name:        sessionCookie,
+				Description: "Log in with Discord at /auth/login",
+			}
+			config.Security = append(config.Security, map[string][]string{"sessionCookie": {}})
+		}
 	}
 	api := humachi.New(router, config)
 
@@ -131,6 +114,13 @@ func (a *Api) SetupRoutes() *chi.Mux {
 	router.Get("/api/ws", func(w http.ResponseWriter, r *http.Request) {
 		a.ServeWs(w, r)
 	})
+	router.Get("/events", a.ServeEvents)
+
+	if a.conf.OAuth.enabled() {
+		router.Get("/auth/login", a.oauthLogin)
+		router.Get("/auth/callback", a.oauthCallback)
+		router.Post("/auth/logout", a.oauthLogout)
+	}
 
 	router.Get("/ws/asyncapi.yaml", func(w http.ResponseWriter, r *http.Request) {
 		http.ServeFile(w, r, "docs/asyncapi.yaml")
@@ -162,20 +152,79 @@ func (a *Api) SetupRoutes() *chi.Mux {
 		return &HealthResponse{Body: HealthData{Status: "ok"}}, nil
 	})
 
+	// Current identity
+	huma.Register(api, huma.Operation{
+		OperationID: "get-me",
+		Method:      http.MethodGet,
+		Path:        "/auth/me",
+		Summary:     "Get the user and scopes of the key or login session",
+	}, a.me)
+
 	// Tasks Endpoint
 	huma.Register(api, huma.Operation{
 		OperationID: "get-tasks",
 		Method:      http.MethodGet,
 		Path:        "/tasks",
-		Summary:     "Get all tasks",
-	}, func(ctx context.Context, input *struct{}) (*TasksResponse, error) {
-		choresList, err := a.storage.GetChores()
+		Summary:     "Get the tasks matching the filters, a page at a time",
+		Description: "Without `limit` and `cursor` all the matching tasks are returned. A page is requested by `limit`, the `Next-Cursor` header of a full page is passed as `cursor` to get the next one.",
+	}, func(ctx context.Context, input *TasksInput) (*TasksPageResponse, error) {
+		filter, err := input.filter()
+		if err != nil {
+			return nil, err
+		}
+		limit := input.Limit
+		if limit == 0 && input.Cursor != "" {
+			limit = defaultTasksPage
+		}
+		if limit > 0 {
+			// One more task than the page is fetched to know whether there is a next page.
+			filter.Limit = limit + 1
+		}
+		choresList, err := a.storage.FindChores(filter)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, huma.Error400BadRequest("the cursor does not point to a task")
+		}
+		if err != nil {
+			return nil, err
+		}
+		next := ""
+		if limit > 0 && len(choresList) > limit {
+			choresList = choresList[:limit]
+			next = encodeCursor(choresList[len(choresList)-1].ID)
+		}
+		ids := []uint{}
+		for _, c := range choresList {
+			ids = append(ids, c.ID)
+		}
+		assignments, err := a.storage.GetChoresAssignments(ids)
+		if err != nil {
+			return nil, err
+		}
+		waitlists, err := a.storage.GetWaitlists(ids)
 		if err != nil {
 			return nil, err
 		}
 		var resp []TaskData
 		for _, c := range choresList {
-			resp = append(resp, toTaskData(c))
+			resp = append(resp, toTaskData(c, assignments, waitlists))
+		}
+		return &TasksPageResponse{NextCursor: next, Body: resp}, nil
+	})
+
+	// Get Drafts
+	huma.Register(api, huma.Operation{
+		OperationID: "get-drafts",
+		Method:      http.MethodGet,
+		Path:        "/drafts",
+		Summary:     "Get the tasks waiting for their not before time to be published",
+	}, func(ctx context.Context, input *struct{}) (*TasksResponse, error) {
+		drafts, err := a.storage.GetDrafts()
+		if err != nil {
+			return nil, err
+		}
+		resp := []TaskData{}
+		for _, c := range drafts {
+			resp = append(resp, toTaskData(c, nil, nil))
 		}
 		return &TasksResponse{Body: resp}, nil
 	})
@@ -191,7 +240,7 @@ func (a *Api) SetupRoutes() *chi.Mux {
 		if err != nil {
 			return nil, err
 		}
-		return &TaskCreateResponse{Body: toTaskData(chore)}, nil
+		return a.taskResponse(chore)
 	})
 
 	// Create Task (with bidirectional Discord sync)
@@ -200,48 +249,15 @@ func (a *Api) SetupRoutes() *chi.Mux {
 		Method:      http.MethodPost,
 		Path:        "/tasks",
 		Summary:     "Create a new task and publish to Discord",
-	}, func(ctx context.Context, input *CreateTaskInput) (*TaskCreateResponse, error) {
-		workers := input.Body.NecessaryWorkers
-		if workers == 0 {
-			workers = 1
-		}
-		estTime := input.Body.EstimatedTimeMin
-		if estTime == 0 {
-			estTime = 10
-		}
-		timeoutMin := input.Body.AssignmentTimeoutMin
-		if timeoutMin == 0 {
-			timeoutMin = 15
-		}
-		deadline := input.Body.Deadline
-		if deadline == nil {
-			d := time.Now().Add(24 * time.Hour)
-			deadline = &d
-		}
-
-		chore := storage.Chore{
-			Name:                 input.Body.Name,
-			NecessaryWorkers:     workers,
-			EstimatedTimeMin:     estTime,
-			AssignmentTimeoutMin: timeoutMin,
-			Deadline:             deadline,
-			CreatorId:            "API",
-			Created:              time.Now(),
-		}
-		if len(input.Body.NecessaryCapabilities) > 0 {
-			chore.SetCapabilities(input.Body.NecessaryCapabilities)
-		}
-
-		saved, _, err := a.ui.PublishChore(chore)
-		if err != nil {
-			a.logger.Warn("Failed to publish chore to Discord", "error", err)
-			saved, err = a.storage.SaveChore(chore)
+	}, func(ctx context.Context, input *CreateTaskInput) (*TaskCreatedResponse, error) {
+		if input.IdempotencyKey == "" {
+			resp, err := a.createTask(input.Body)
 			if err != nil {
 				return nil, err
 			}
+			return &TaskCreatedResponse{ETag: resp.ETag, Body: resp.Body}, nil
 		}
-
-		return &TaskCreateResponse{Body: toTaskData(saved)}, nil
+		return a.createTaskIdempotent(ctx, input)
 	})
 
 	// Edit / Update Task
@@ -250,12 +266,20 @@ func (a *Api) SetupRoutes() *chi.Mux {
 		Method:      http.MethodPut,
 		Path:        "/tasks/{id}",
 		Summary:     "Update task details",
+		Description: "Omitted and zero fields keep their current value, use PATCH to set a field to zero.",
 	}, func(ctx context.Context, input *UpdateTaskInput) (*TaskCreateResponse, error) {
-		updated, err := a.ui.EditChoreDetails(uint(input.ID), input.Body.Name, input.Body.NecessaryWorkers, input.Body.EstimatedTimeMin, input.Body.AssignmentTimeoutMin, input.Body.Deadline, input.Body.NecessaryCapabilities)
-		if err != nil {
-			return nil, err
-		}
-		return &TaskCreateResponse{Body: toTaskData(updated)}, nil
+		return a.patchTask(uint(input.ID), input.IfMatch, input.Body.patch())
+	})
+
+	// Partially update Task
+	huma.Register(api, huma.Operation{
+		OperationID: "patch-task",
+		Method:      http.MethodPatch,
+		Path:        "/tasks/{id}",
+		Summary:     "Change the given task details",
+		Description: "Only the fields present in the body are changed, zero values included.",
+	}, func(ctx context.Context, input *PatchTaskInput) (*TaskCreateResponse, error) {
+		return a.patchTask(uint(input.ID), input.IfMatch, input.Body)
 	})
 
 	// Schedule Task
@@ -270,6 +294,9 @@ func (a *Api) SetupRoutes() *chi.Mux {
 			return nil, err
 		}
 		_, _, err = a.ui.PublishChore(chore)
+		if errors.Is(err, chores.ErrInvalidTransition) || errors.Is(err, storage.ErrVersionConflict) {
+			return nil, huma.Error409Conflict(err.Error())
+		}
 		return nil, err
 	})
 
@@ -281,9 +308,29 @@ func (a *Api) SetupRoutes() *chi.Mux {
 		Summary:     "Cancel/Delete a task",
 	}, func(ctx context.Context, input *TaskActionInput) (*struct{}, error) {
 		_, err := a.ui.CancelChore(uint(input.ID))
+		if errors.Is(err, chores.ErrInvalidTransition) {
+			return nil, huma.Error409Conflict(err.Error())
+		}
 		return nil, err
 	})
 
+	// Reopen task
+	huma.Register(api, huma.Operation{
+		OperationID: "reopen-task",
+		Method:      http.MethodPost,
+		Path:        "/tasks/{id}/reopen",
+		Summary:     "Reopen a done or cancelled task",
+	}, func(ctx context.Context, input *TaskActionInput) (*TaskCreateResponse, error) {
+		chore, err := a.ui.ReopenChore(uint(input.ID))
+		if errors.Is(err, chores.ErrInvalidTransition) {
+			return nil, huma.Error409Conflict(err.Error())
+		}
+		if err != nil {
+			return nil, err
+		}
+		return a.taskResponse(chore)
+	})
+
 	// Complete task
 	huma.Register(api, huma.Operation{
 		OperationID: "complete-task",
@@ -292,6 +339,9 @@ func (a *Api) SetupRoutes() *chi.Mux {
 		Summary:     "Mark a task as completed",
 	}, func(ctx context.Context, input *TaskActionInput) (*struct{}, error) {
 		_, err := a.ui.CompleteChore(uint(input.ID))
+		if errors.Is(err, chores.ErrInvalidTransition) {
+			return nil, huma.Error409Conflict(err.Error())
+		}
 		return nil, err
 	})
 
@@ -301,9 +351,53 @@ func (a *Api) SetupRoutes() *chi.Mux {
 		Method:      http.MethodPost,
 		Path:        "/tasks/{id}/ack",
 		Summary:     "Acknowledge / claim a task for a user",
-	}, func(ctx context.Context, input *TaskUserActionInput) (*struct{}, error) {
-		_, _, err := a.ui.AckChore(uint(input.ID), input.Body.UserId)
-		return nil, err
+	}, func(ctx context.Context, input *TaskUserActionInput) (*TaskAckResponse, error) {
+		userId, err := actingUser(ctx, input.Body.UserId)
+		if err != nil {
+			return nil, err
+		}
+		if input.Body.Confirm {
+			_, _, err = a.ui.AckChoreConfirmed(uint(input.ID), userId)
+		} else {
+			_, _, err = a.ui.AckChore(uint(input.ID), userId)
+		}
+		var waitlisted *storage.WaitlistedError
+		if errors.As(err, &waitlisted) {
+			return &TaskAckResponse{
+				Status: http.StatusAccepted,
+				Body:   &WaitlistData{TaskId: waitlisted.ChoreId, UserId: userId, Position: waitlisted.Position},
+			}, nil
+		}
+		if errors.Is(err, chores.ErrAckRefused) {
+			return nil, huma.Error403Forbidden(err.Error())
+		}
+		if errors.Is(err, chores.ErrResourceLocked) || errors.Is(err, chores.ErrInvalidTransition) || errors.Is(err, chores.ErrAuctionOpen) {
+			return nil, huma.Error409Conflict(err.Error())
+		}
+		if errors.Is(err, chores.ErrAckNeedsConfirmation) {
+			return nil, huma.Error409Conflict(err.Error() + ", repeat the request with confirm set to true")
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &TaskAckResponse{Status: http.StatusNoContent}, nil
+	})
+
+	// Assign users to Task
+	huma.Register(api, huma.Operation{
+		OperationID: "assign-task",
+		Method:      http.MethodPost,
+		Path:        "/tasks/{id}/assign",
+		Summary:     "Assign specific users to a task, bypassing the automatic assignment",
+	}, func(ctx context.Context, input *TaskAssignInput) (*TaskCreateResponse, error) {
+		chore, _, err := a.ui.AssignUsers(uint(input.ID), input.Body.UserIds)
+		if errors.Is(err, chores.ErrChoreDraft) {
+			return nil, huma.Error409Conflict(err.Error())
+		}
+		if err != nil {
+			return nil, err
+		}
+		return a.taskResponse(chore)
 	})
 
 	// Reject Task
@@ -313,7 +407,14 @@ func (a *Api) SetupRoutes() *chi.Mux {
 		Path:        "/tasks/{id}/reject",
 		Summary:     "Reject a task assignment for a user",
 	}, func(ctx context.Context, input *TaskUserActionInput) (*struct{}, error) {
-		_, err := a.ui.RejectChore(uint(input.ID), input.Body.UserId)
+		userId, err := actingUser(ctx, input.Body.UserId)
+		if err != nil {
+			return nil, err
+		}
+		_, err = a.ui.RejectChore(uint(input.ID), userId)
+		if errors.Is(err, storage.ErrAssignmentInactive) {
+			return nil, huma.Error409Conflict(err.Error())
+		}
 		return nil, err
 	})
 
@@ -324,112 +425,606 @@ func (a *Api) SetupRoutes() *chi.Mux {
 		Path:        "/tasks/{id}/help",
 		Summary:     "Log work on a completed task",
 	}, func(ctx context.Context, input *TaskUserActionInput) (*struct{}, error) {
-		_, err := a.ui.HelpedChore(uint(input.ID), input.Body.UserId)
+		userId, err := actingUser(ctx, input.Body.UserId)
+		if err != nil {
+			return nil, err
+		}
+		_, err = a.ui.HelpedChore(uint(input.ID), userId)
 		return nil, err
 	})
 
-	// Stats Endpoint
+	// Hand off Task
 	huma.Register(api, huma.Operation{
-		OperationID: "get-stats",
-		Method:      http.MethodGet,
-		Path:        "/stats",
-		Summary:     "Get user chore stats",
-	}, func(ctx context.Context, input *struct{}) (*StatsResponse, error) {
-		aggregatedStats, err := a.storage.GetAggregatedStats()
+		OperationID: "handoff-task",
+		Method:      http.MethodPost,
+		Path:        "/tasks/{id}/handoff",
+		Summary:     "Offer a task assignment to another user, it moves once they accept",
+	}, func(ctx context.Context, input *TaskHandoffInput) (*TransferResponse, error) {
+		userId, err := actingUser(ctx, input.Body.UserId)
+		if err != nil {
+			return nil, err
+		}
+		t, err := a.ui.OfferHandoff(uint(input.ID), userId, input.Body.ToUserId)
+		if errors.Is(err, storage.ErrAlreadyAssigned) {
+			return nil, huma.Error409Conflict(err.Error())
+		}
 		if err != nil {
 			return nil, err
 		}
+		return &TransferResponse{Body: toTransferData(t)}, nil
+	})
 
-		usersStats := map[string]UserStats{}
-		for k, s := range aggregatedStats {
-			usersStats[k] = UserStats{
-				WorkedCount:     s.WorkedCount,
-				WorkedMin:       s.WorkedMin,
-				AssignedMin:     s.AssignedMin,
-				AssignedCount:   s.AssignedCount,
-				TotalMin:        s.TotalMin,
-				TotalCount:      s.TotalCount,
-				PresentTicks:    s.PresentTicks,
-				NormalizedTotal: s.NormalizedTotal,
-			}
+	// Swap Task
+	huma.Register(api, huma.Operation{
+		OperationID: "swap-task",
+		Method:      http.MethodPost,
+		Path:        "/tasks/{id}/swap",
+		Summary:     "Offer to swap a task assignment for an assignment of another user",
+	}, func(ctx context.Context, input *TaskSwapInput) (*TransferResponse, error) {
+		userId, err := actingUser(ctx, input.Body.UserId)
+		if err != nil {
+			return nil, err
+		}
+		t, err := a.ui.OfferSwap(uint(input.ID), userId, input.Body.ToUserId, input.Body.SwapTaskId)
+		if errors.Is(err, storage.ErrAlreadyAssigned) {
+			return nil, huma.Error409Conflict(err.Error())
+		}
+		if err != nil {
+			return nil, err
 		}
+		return &TransferResponse{Body: toTransferData(t)}, nil
+	})
 
-		return &StatsResponse{Body: usersStats}, nil
+	// Bid on Task
+	huma.Register(api, huma.Operation{
+		OperationID: "bid-task",
+		Method:      http.MethodPost,
+		Path:        "/tasks/{id}/bids",
+		Summary:     "Bid the minutes of credit a user wants for an auctioned task, a new bid replaces the previous one",
+	}, func(ctx context.Context, input *TaskBidInput) (*BidResponse, error) {
+		userId, err := actingUser(ctx, input.Body.UserId)
+		if err != nil {
+			return nil, err
+		}
+		b, err := a.ui.PlaceBid(uint(input.ID), userId, input.Body.TimeMin)
+		if errors.Is(err, chores.ErrAuctionNotOpen) {
+			return nil, huma.Error409Conflict(err.Error())
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &BidResponse{Body: toBidData(b)}, nil
 	})
 
-	// Get Users
+	// Get Bids
 	huma.Register(api, huma.Operation{
-		OperationID: "get-users",
+		OperationID: "get-bids",
 		Method:      http.MethodGet,
-		Path:        "/users",
-		Summary:     "Get all present users",
-	}, func(ctx context.Context, input *struct{}) (*UsersResponse, error) {
-		users, err := a.storage.GetPresentUsers()
+		Path:        "/tasks/{id}/bids",
+		Summary:     "Get the bids on an auctioned task, lowest first",
+	}, func(ctx context.Context, input *TaskActionInput) (*BidsResponse, error) {
+		bids, err := a.storage.GetBids(uint(input.ID))
 		if err != nil {
 			return nil, err
 		}
-		var resp []UserData
-		for _, u := range users {
-			resp = append(resp, UserData{
-				DiscordId:    u.DiscordId,
-				Handle:       u.Handle,
-				Capabilities: u.Capabilities,
-			})
+		resp := []BidData{}
+		for _, b := range bids {
+			resp = append(resp, toBidData(b))
 		}
-		return &UsersResponse{Body: resp}, nil
+		return &BidsResponse{Body: resp}, nil
 	})
 
-	// Get Task Stats
+	// Close Auction
 	huma.Register(api, huma.Operation{
-		OperationID: "get-task-stats",
-		Method:      http.MethodGet,
-		Path:        "/tasks/{id}/stats",
-		Summary:     "Get stats for a specific task",
-	}, func(ctx context.Context, input *TaskActionInput) (*TaskStatsResponse, error) {
-		worklogs, err := a.storage.GetWorkLogsForChore(uint(input.ID))
+		OperationID: "close-auction",
+		Method:      http.MethodPost,
+		Path:        "/tasks/{id}/close_auction",
+		Summary:     "Close the bidding window early and assign the task to the lowest bidders",
+	}, func(ctx context.Context, input *TaskActionInput) (*TaskCreateResponse, error) {
+		chore, _, err := a.ui.CloseAuction(uint(input.ID))
+		if errors.Is(err, chores.ErrAuctionNotOpen) {
+			return nil, huma.Error409Conflict(err.Error())
+		}
 		if err != nil {
 			return nil, err
 		}
+		return a.taskResponse(chore)
+	})
 
-		var totalTime uint
-		workerIdMap := make(map[string]struct{})
-		for _, log := range worklogs {
-			totalTime += log.TimeSpentMin
-			workerIdMap[log.UserId] = struct{}{}
+	// Get pending Transfers
+	huma.Register(api, huma.Operation{
+		OperationID: "get-transfers",
+		Method:      http.MethodGet,
+		Path:        "/transfers",
+		Summary:     "Get hand-off and swap offers waiting for an answer",
+	}, func(ctx context.Context, input *TransfersInput) (*TransfersResponse, error) {
+		userId, err := actingUser(ctx, input.UserId)
+		if err != nil {
+			return nil, err
+		}
+		transfers, err := a.storage.GetPendingTransfers(userId)
+		if err != nil {
+			return nil, err
+		}
+		resp := []TransferData{}
+		for _, t := range transfers {
+			resp = append(resp, toTransferData(t))
 		}
+		return &TransfersResponse{Body: resp}, nil
+	})
 
-		return &TaskStatsResponse{
-			Body: TaskStatsData{
-				TotalTimeMin: totalTime,
-				WorkerCount:  uint(len(workerIdMap)),
-			},
-		}, nil
+	// Accept Transfer
+	huma.Register(api, huma.Operation{
+		OperationID: "accept-transfer",
+		Method:      http.MethodPost,
+		Path:        "/transfers/{id}/accept",
+		Summary:     "Accept a hand-off or swap offer",
+	}, func(ctx context.Context, input *TransferActionInput) (*TransferResponse, error) {
+		userId, err := actingUser(ctx, input.Body.UserId)
+		if err != nil {
+			return nil, err
+		}
+		t, err := a.ui.AcceptTransfer(uint(input.ID), userId)
+		if errors.Is(err, storage.ErrAlreadyAssigned) {
+			return nil, huma.Error409Conflict(err.Error())
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &TransferResponse{Body: toTransferData(t)}, nil
 	})
 
-	return router
-}
+	// Decline Transfer
+	huma.Register(api, huma.Operation{
+		OperationID: "decline-transfer",
+		Method:      http.MethodPost,
+		Path:        "/transfers/{id}/decline",
+		Summary:     "Decline a hand-off or swap offer",
+	}, func(ctx context.Context, input *TransferActionInput) (*TransferResponse, error) {
+		userId, err := actingUser(ctx, input.Body.UserId)
+		if err != nil {
+			return nil, err
+		}
+		t, err := a.ui.DeclineTransfer(uint(input.ID), userId)
+		if err != nil {
+			return nil, err
+		}
+		return &TransferResponse{Body: toTransferData(t)}, nil
+	})
 
-func (a *Api) Run(ctx context.Context) error {
-	router := a.SetupRoutes()
-	addr := fmt.Sprintf("%s:%d", a.conf.Host, a.conf.Port)
-	if a.conf.Host == "" {
-		addr = fmt.Sprintf(":%d", a.conf.Port)
-	}
-	srv := &http.Server{
-		Addr:    addr,
-		Handler: router,
-	}
+	// List API Keys
+	huma.Register(api, huma.Operation{
+		OperationID: "get-apikeys",
+		Method:      http.MethodGet,
+		Path:        "/apikeys",
+		Summary:     "List the API keys, without their secrets",
+	}, func(ctx context.Context, input *struct{}) (*ApiKeysResponse, error) {
+		keys, err := a.storage.GetApiKeys()
+		if err != nil {
+			return nil, err
+		}
+		resp := []ApiKeyData{}
+		for _, k := range keys {
+			resp = append(resp, toApiKeyData(k))
+		}
+		return &ApiKeysResponse{Body: resp}, nil
+	})
 
-	go func() {
-		<-ctx.Done()
-		srv.Shutdown(context.Background())
-	}()
+	// Create API Key
+	huma.Register(api, huma.Operation{
+		OperationID: "create-apikey",
+		Method:      http.MethodPost,
+		Path:        "/apikeys",
+		Summary:     "Create an API key, its secret is returned only once",
+	}, func(ctx context.Context, input *ApiKeyCreateInput) (*ApiKeyCreateResponse, error) {
+		key := storage.ApiKey{Name: input.Body.Name, UserId: input.Body.UserId}
+		key.SetScopes(input.Body.Scopes)
+		key, secret, err := a.storage.CreateApiKey(key)
+		if errors.Is(err, storage.ErrInvalidScope) {
+			return nil, huma.Error400BadRequest(err.Error())
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &ApiKeyCreateResponse{Body: ApiKeyCreatedData{ApiKeyData: toApiKeyData(key), Secret: secret}}, nil
+	})
 
-	a.logger.Info("Starting REST API", "addr", addr)
-	return srv.ListenAndServe()
-}
+	// Revoke API Key
+	huma.Register(api, huma.Operation{
+		OperationID: "revoke-apikey",
+		Method:      http.MethodDelete,
+		Path:        "/apikeys/{id}",
+		Summary:     "Revoke an API key",
+	}, func(ctx context.Context, input *TaskActionInput) (*ApiKeyResponse, error) {
+		key, err := a.storage.RevokeApiKey(uint(input.ID))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, huma.Error404NotFound("API key not found")
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &ApiKeyResponse{Body: toApiKeyData(key)}, nil
+	})
 
-// Schemas
+	// List Webhooks
+	huma.Register(api, huma.Operation{
+		OperationID: "get-webhooks",
+		Method:      http.MethodGet,
+		Path:        "/webhooks",
+		Summary:     "List the webhook subscriptions",
+	}, func(ctx context.Context, input *struct{}) (*WebhooksResponse, error) {
+		hooks, err := a.storage.GetWebhooks()
+		if err != nil {
+			return nil, err
+		}
+		resp := []WebhookData{}
+		for _, w := range hooks {
+			resp = append(resp, toWebhookData(w, false))
+		}
+		return &WebhooksResponse{Body: resp}, nil
+	})
+
+	// Create Webhook
+	huma.Register(api, huma.Operation{
+		OperationID: "create-webhook",
+		Method:      http.MethodPost,
+		Path:        "/webhooks",
+		Summary:     "Subscribe a URL to the events, its signing secret is returned only once",
+	}, func(ctx context.Context, input *WebhookCreateInput) (*WebhookResponse, error) {
+		u, err := url.Parse(input.Body.Url)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, huma.Error400BadRequest("the webhook URL has to be an absolute http(s) URL")
+		}
+		for _, t := range input.Body.EventTypes {
+			if !slices.Contains(storage.EventTypes, storage.EventType(t)) {
+				return nil, huma.Error400BadRequest(fmt.Sprintf("unknown event type %q", t))
+			}
+		}
+		w := storage.Webhook{Url: input.Body.Url, Secret: input.Body.Secret}
+		w.SetEventTypes(input.Body.EventTypes)
+		w, err = a.storage.SaveWebhook(w)
+		if err != nil {
+			return nil, err
+		}
+		return &WebhookResponse{Body: toWebhookData(w, true)}, nil
+	})
+
+	// Remove Webhook
+	huma.Register(api, huma.Operation{
+		OperationID: "remove-webhook",
+		Method:      http.MethodDelete,
+		Path:        "/webhooks/{id}",
+		Summary:     "Remove a webhook subscription and its delivery log",
+	}, func(ctx context.Context, input *TaskActionInput) (*struct{}, error) {
+		err := a.storage.RemoveWebhook(uint(input.ID))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, huma.Error404NotFound("webhook not found")
+		}
+		return nil, err
+	})
+
+	// Webhook Delivery Log
+	huma.Register(api, huma.Operation{
+		OperationID: "get-webhook-deliveries",
+		Method:      http.MethodGet,
+		Path:        "/webhooks/{id}/deliveries",
+		Summary:     "Get the delivery log of a webhook, newest first",
+	}, func(ctx context.Context, input *DeliveriesInput) (*DeliveriesResponse, error) {
+		deliveries, err := a.storage.GetDeliveries(uint(input.ID), storage.DeliveryStatus(input.Status), input.Limit)
+		if err != nil {
+			return nil, err
+		}
+		resp := []DeliveryData{}
+		for _, d := range deliveries {
+			resp = append(resp, toDeliveryData(d))
+		}
+		return &DeliveriesResponse{Body: resp}, nil
+	})
+
+	// Send Test Event
+	huma.Register(api, huma.Operation{
+		OperationID: "test-webhook",
+		Method:      http.MethodPost,
+		Path:        "/webhooks/{id}/test",
+		Summary:     "Queue a webhook_test event for the webhook, check its delivery in the log",
+	}, func(ctx context.Context, input *TaskActionInput) (*DeliveryResponse, error) {
+		w, err := a.storage.GetWebhook(uint(input.ID))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, huma.Error404NotFound("webhook not found")
+		}
+		if err != nil {
+			return nil, err
+		}
+		payload, err := json.Marshal(storage.Event{Type: storage.WebhookTest})
+		if err != nil {
+			return nil, err
+		}
+		d, err := a.storage.QueueDelivery(w.ID, storage.WebhookTest, string(payload))
+		if err != nil {
+			return nil, err
+		}
+		return &DeliveryResponse{Body: toDeliveryData(d)}, nil
+	})
+
+	// Retry Delivery
+	huma.Register(api, huma.Operation{
+		OperationID: "retry-webhook-delivery",
+		Method:      http.MethodPost,
+		Path:        "/webhooks/{id}/deliveries/{delivery_id}/retry",
+		Summary:     "Send a delivery again, e.g. a dead-lettered one",
+	}, func(ctx context.Context, input *DeliveryActionInput) (*DeliveryResponse, error) {
+		d, err := a.storage.GetDelivery(uint(input.DeliveryId))
+		if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && d.WebhookId != uint(input.ID)) {
+			return nil, huma.Error404NotFound("delivery not found")
+		}
+		if err != nil {
+			return nil, err
+		}
+		d, err = a.storage.RetryDelivery(d.ID)
+		if err != nil {
+			return nil, err
+		}
+		return &DeliveryResponse{Body: toDeliveryData(d)}, nil
+	})
+
+	// Stats Endpoint
+	huma.Register(api, huma.Operation{
+		OperationID: "get-stats",
+		Method:      http.MethodGet,
+		Path:        "/stats",
+		Summary:     "Get user chore stats",
+	}, func(ctx context.Context, input *struct{}) (*StatsResponse, error) {
+		aggregatedStats, err := a.storage.GetAggregatedStats()
+		if err != nil {
+			return nil, err
+		}
+
+		usersStats := map[string]UserStats{}
+		for k, s := range aggregatedStats {
+			usersStats[k] = UserStats{
+				WorkedCount:     s.WorkedCount,
+				WorkedMin:       s.WorkedMin,
+				AssignedMin:     s.AssignedMin,
+				AssignedCount:   s.AssignedCount,
+				TotalMin:        s.TotalMin,
+				TotalCount:      s.TotalCount,
+				PresentTicks:    s.PresentTicks,
+				NormalizedTotal: s.NormalizedTotal,
+			}
+		}
+
+		return &StatsResponse{Body: usersStats}, nil
+	})
+
+	// Get Users
+	huma.Register(api, huma.Operation{
+		OperationID: "get-users",
+		Method:      http.MethodGet,
+		Path:        "/users",
+		Summary:     "Get all present users",
+	}, func(ctx context.Context, input *struct{}) (*UsersResponse, error) {
+		users, err := a.storage.GetPresentUsers()
+		if err != nil {
+			return nil, err
+		}
+		var resp []UserData
+		for _, u := range users {
+			resp = append(resp, UserData{
+				DiscordId:    u.DiscordId,
+				Handle:       u.Handle,
+				Capabilities: u.Capabilities,
+			})
+		}
+		return &UsersResponse{Body: resp}, nil
+	})
+
+	// Get Resources
+	huma.Register(api, huma.Operation{
+		OperationID: "get-resources",
+		Method:      http.MethodGet,
+		Path:        "/resources",
+		Summary:     "Get the resource catalogue with the tasks holding the resources",
+	}, func(ctx context.Context, input *struct{}) (*ResourcesResponse, error) {
+		resources, err := a.storage.GetResources()
+		if err != nil {
+			return nil, err
+		}
+		locks, err := a.storage.GetResourceLocks()
+		if err != nil {
+			return nil, err
+		}
+		resp := []ResourceData{}
+		for _, r := range resources {
+			resp = append(resp, toResourceData(r, locks))
+		}
+		return &ResourcesResponse{Body: resp}, nil
+	})
+
+	// Create Resource
+	huma.Register(api, huma.Operation{
+		OperationID: "create-resource",
+		Method:      http.MethodPost,
+		Path:        "/resources",
+		Summary:     "Add a shared resource (vehicle, tool, room) tasks can require",
+	}, func(ctx context.Context, input *CreateResourceInput) (*ResourceResponse, error) {
+		resource, err := a.ui.CreateResource(input.Body.Name, input.Body.Kind)
+		if err != nil {
+			return nil, huma.Error400BadRequest(err.Error())
+		}
+		return &ResourceResponse{Body: toResourceData(resource, nil)}, nil
+	})
+
+	// Get Duties
+	huma.Register(api, huma.Operation{
+		OperationID: "get-duties",
+		Method:      http.MethodGet,
+		Path:        "/duties",
+		Summary:     "Get all duties",
+	}, func(ctx context.Context, input *struct{}) (*DutiesResponse, error) {
+		duties, err := a.storage.GetDuties()
+		if err != nil {
+			return nil, err
+		}
+		resp := []DutyData{}
+		for _, d := range duties {
+			resp = append(resp, toDutyData(d))
+		}
+		return &DutiesResponse{Body: resp}, nil
+	})
+
+	// Create Duty
+	huma.Register(api, huma.Operation{
+		OperationID: "create-duty",
+		Method:      http.MethodPost,
+		Path:        "/duties",
+		Summary:     "Create a duty held in shifts (e.g. kitchen duty, night watch)",
+	}, func(ctx context.Context, input *CreateDutyInput) (*DutyResponse, error) {
+		duty, err := a.ui.CreateDuty(input.Body.Name, input.Body.WorkersPerShift)
+		if err != nil {
+			return nil, huma.Error400BadRequest(err.Error())
+		}
+		return &DutyResponse{Body: toDutyData(duty)}, nil
+	})
+
+	// Schedule Shifts
+	huma.Register(api, huma.Operation{
+		OperationID: "schedule-shifts",
+		Method:      http.MethodPost,
+		Path:        "/duties/{id}/shifts",
+		Summary:     "Schedule shifts of a duty, the holders are picked by fair rotation",
+	}, func(ctx context.Context, input *ScheduleShiftsInput) (*ShiftsResponse, error) {
+		if _, err := a.storage.GetDuty(uint(input.ID)); err != nil {
+			return nil, huma.Error404NotFound("duty not found")
+		}
+		shifts, err := a.ui.ScheduleShifts(uint(input.ID), input.Body.Start, input.Body.End, input.Body.Days)
+		if err != nil {
+			return nil, huma.Error400BadRequest(err.Error())
+		}
+		resp := []ShiftData{}
+		for _, sh := range shifts {
+			resp = append(resp, toShiftData(sh))
+		}
+		return &ShiftsResponse{Body: resp}, nil
+	})
+
+	// Get Roster
+	huma.Register(api, huma.Operation{
+		OperationID: "get-roster",
+		Method:      http.MethodGet,
+		Path:        "/roster",
+		Summary:     "Get the upcoming and running shifts",
+	}, func(ctx context.Context, input *RosterInput) (*ShiftsResponse, error) {
+		shifts, err := a.storage.GetUpcomingShifts(input.DutyId)
+		if err != nil {
+			return nil, err
+		}
+		resp := []ShiftData{}
+		for _, sh := range shifts {
+			resp = append(resp, toShiftData(sh))
+		}
+		return &ShiftsResponse{Body: resp}, nil
+	})
+
+	// Update User availability
+	huma.Register(api, huma.Operation{
+		OperationID: "patch-user",
+		Method:      http.MethodPatch,
+		Path:        "/users/{id}",
+		Summary:     "Update a user's quiet hours, away blocks and chore preferences",
+	}, func(ctx context.Context, input *UserPatchInput) (*UserSettingsResponse, error) {
+		userId, err := actingUser(ctx, input.ID)
+		if err != nil {
+			return nil, err
+		}
+		if input.Body.QuietStart != nil || input.Body.QuietEnd != nil {
+			settings, err := a.storage.GetUserSettings(userId)
+			if err != nil {
+				return nil, err
+			}
+			start, end := settings.QuietStart, settings.QuietEnd
+			if input.Body.QuietStart != nil {
+				start = *input.Body.QuietStart
+			}
+			if input.Body.QuietEnd != nil {
+				end = *input.Body.QuietEnd
+			}
+			_, err = a.ui.SetQuietHours(userId, start, end)
+			if err != nil {
+				return nil, huma.Error400BadRequest(err.Error())
+			}
+		}
+		if input.Body.Unavailable != nil {
+			err := a.ui.ClearAway(userId)
+			if err != nil {
+				return nil, err
+			}
+			for _, b := range *input.Body.Unavailable {
+				_, err = a.ui.AddAway(userId, b.From, b.To, b.Reason)
+				if err != nil {
+					return nil, huma.Error400BadRequest(err.Error())
+				}
+			}
+		}
+		if input.Body.Likes != nil || input.Body.Dislikes != nil {
+			_, err := a.ui.SetPreferences(userId, input.Body.Likes, input.Body.Dislikes)
+			if err != nil {
+				return nil, huma.Error400BadRequest(err.Error())
+			}
+		}
+		return a.userSettingsResponse(userId)
+	})
+
+	// Get Task Stats
+	huma.Register(api, huma.Operation{
+		OperationID: "get-task-stats",
+		Method:      http.MethodGet,
+		Path:        "/tasks/{id}/stats",
+		Summary:     "Get stats for a specific task",
+	}, func(ctx context.Context, input *TaskActionInput) (*TaskStatsResponse, error) {
+		worklogs, err := a.storage.GetWorkLogsForChore(uint(input.ID))
+		if err != nil {
+			return nil, err
+		}
+
+		var totalTime uint
+		workerIdMap := make(map[string]struct{})
+		for _, log := range worklogs {
+			totalTime += log.TimeSpentMin
+			workerIdMap[log.UserId] = struct{}{}
+		}
+
+		return &TaskStatsResponse{
+			Body: TaskStatsData{
+				TotalTimeMin: totalTime,
+				WorkerCount:  uint(len(workerIdMap)),
+			},
+		}, nil
+	})
+
+	return router
+}
+
+func (a *Api) Run(ctx context.Context) error {
+	router := a.SetupRoutes()
+	addr := fmt.Sprintf("%s:%d", a.conf.Host, a.conf.Port)
+	if a.conf.Host == "" {
+		addr = fmt.Sprintf(":%d", a.conf.Port)
+	}
+	srv := &http.Server{
+		Addr:    addr,
+		Handler: router,
+	}
+
+	go func() {
+		<-ctx.Done()
+		srv.Shutdown(context.Background())
+	}()
+
+	a.logger.Info("Starting REST API", "addr", addr)
+	return srv.ListenAndServe()
+}
+
+// Schemas
 
 type HealthData struct {
 	Status string `json:"status" doc:"Status of the service"`
@@ -440,23 +1035,108 @@ type HealthResponse struct {
 }
 
 type TaskData struct {
-	ID                    uint       `json:"id"`
-	Name                  string     `json:"name"`
-	NecessaryWorkers      uint       `json:"necessary_workers"`
-	EstimatedTimeMin      uint       `json:"estimated_time_min"`
-	AssignmentTimeoutMin  uint       `json:"assignment_timeout_min"`
-	CreatorId             string     `json:"creator_id"`
-	Created               time.Time  `json:"created"`
-	Completed             *time.Time `json:"completed,omitempty"`
-	Cancelled             *time.Time `json:"cancelled,omitempty"`
-	Deadline              *time.Time `json:"deadline,omitempty"`
-	NecessaryCapabilities []string   `json:"necessary_capabilities"`
+	ID                    uint         `json:"id"`
+	Name                  string       `json:"name"`
+	NecessaryWorkers      uint         `json:"necessary_workers"`
+	EstimatedTimeMin      uint         `json:"estimated_time_min"`
+	AssignmentTimeoutMin  uint         `json:"assignment_timeout_min"`
+	CreatorId             string       `json:"creator_id"`
+	Created               time.Time    `json:"created"`
+	Completed             *time.Time   `json:"completed,omitempty"`
+	Cancelled             *time.Time   `json:"cancelled,omitempty"`
+	Deadline              *time.Time   `json:"deadline,omitempty"`
+	NecessaryCapabilities []string     `json:"necessary_capabilities"`
+	Categories            []string     `json:"categories"`
+	Resources             []string     `json:"resources"`
+	NotBefore             *time.Time   `json:"not_before,omitempty" doc:"The task is not published before this time"`
+	Status                string       `json:"status" enum:"draft,open,in_progress,done,cancelled,reopened"`
+	Draft                 bool         `json:"draft" doc:"The task is not published yet, it has no assignments"`
+	Bounty                float64      `json:"bounty" doc:"Multiplier of the credited minutes, grows with every refusal and timeout"`
+	AuctionMin            uint         `json:"auction_min" doc:"Length of the bidding window in minutes, 0 when the task is assigned directly"`
+	AuctionUntil          *time.Time   `json:"auction_until,omitempty" doc:"End of the bidding window"`
+	AuctionOpen           bool         `json:"auction_open"`
+	Staffing              StaffingData `json:"staffing"`
+	Assignees             []string     `json:"assignees" doc:"Manually assigned users"`
+	Waitlist              []string     `json:"waitlist" doc:"Users waiting for an acked worker to drop out, first in line first"`
+	TeamMode              bool         `json:"team_mode"`
+	TrainingMode          bool         `json:"training_mode"`
+	Version               uint         `json:"version" doc:"Raised by every change of the task, the ETag of the task"`
+}
+
+// TaskAckResponse is empty when the ack went through, an ack beyond the necessary workers gets the waitlist position.
+type TaskAckResponse struct {
+	Status int
+	Body   *WaitlistData
+}
+
+type WaitlistData struct {
+	TaskId   uint   `json:"task_id"`
+	UserId   string `json:"user_id"`
+	Position int    `json:"position" doc:"1 is the next user to get a freed slot"`
+}
+
+type StaffingData struct {
+	State    string `json:"state" enum:"staffed,understaffed,unstaffed" doc:"Whether the task has enough active assignees"`
+	Assigned uint   `json:"assigned" doc:"Number of assigned or acked users"`
+	Needed   uint   `json:"needed" doc:"Number of necessary workers"`
 }
 
 type TasksResponse struct {
 	Body []TaskData
 }
 
+type TasksInput struct {
+	Status       []string  `query:"status" enum:"draft,open,in_progress,done,cancelled,reopened" doc:"Only tasks in one of these states (comma separated)"`
+	Assignee     string    `query:"assignee" doc:"Only tasks assigned to or acked by this user"`
+	Creator      string    `query:"creator" doc:"Only tasks created by this user"`
+	Capability   string    `query:"capability" doc:"Only tasks needing this capability"`
+	DeadlineFrom time.Time `query:"deadline_from" doc:"Only tasks with a deadline at or after this time"`
+	DeadlineTo   time.Time `query:"deadline_to" doc:"Only tasks with a deadline before this time"`
+	CreatedFrom  time.Time `query:"created_from" doc:"Only tasks created at or after this time"`
+	CreatedTo    time.Time `query:"created_to" doc:"Only tasks created before this time"`
+	Sort         string    `query:"sort" enum:"created,deadline,name" default:"created" doc:"Tasks without a deadline are sorted after the others"`
+	Order        string    `query:"order" enum:"asc,desc" default:"asc"`
+	Cursor       string    `query:"cursor" doc:"Next-Cursor header of the previous page"`
+	Limit        int       `query:"limit" minimum:"1" maximum:"500" doc:"Size of the page, all the tasks without it (100 when following a cursor)"`
+}
+
+// defaultTasksPage is the page size when a cursor is followed without a limit.
+const defaultTasksPage = 100
+
+func (in *TasksInput) filter() (storage.ChoreFilter, error) {
+	f := storage.ChoreFilter{
+		Assignee:   in.Assignee,
+		Creator:    in.Creator,
+		Capability: in.Capability,
+		Sort:       in.Sort,
+		Desc:       in.Order == "desc",
+	}
+	for _, st := range in.Status {
+		f.Statuses = append(f.Statuses, storage.ChoreStatus(st))
+	}
+	for _, t := range []struct {
+		from time.Time
+		to   **time.Time
+	}{{in.DeadlineFrom, &f.DeadlineFrom}, {in.DeadlineTo, &f.DeadlineTo}, {in.CreatedFrom, &f.CreatedFrom}, {in.CreatedTo, &f.CreatedTo}} {
+		if !t.from.IsZero() {
+			*t.to = &t.from
+		}
+	}
+	if in.Cursor != "" {
+		id, err := decodeCursor(in.Cursor)
+		if err != nil {
+			return f, huma.Error400BadRequest("invalid cursor", err)
+		}
+		f.AfterId = id
+	}
+	return f, nil
+}
+
+type TasksPageResponse struct {
+	NextCursor string `header:"Next-Cursor" doc:"Cursor of the next page, empty on the last page"`
+	Body       []TaskData
+}
+
 type TaskCreateInputBody struct {
 	Name                  string     `json:"name" doc:"Name of the chore"`
 	NecessaryWorkers      uint       `json:"necessary_workers" default:"1"`
@@ -464,10 +1144,24 @@ type TaskCreateInputBody struct {
 	AssignmentTimeoutMin  uint       `json:"assignment_timeout_min" default:"15"`
 	Deadline              *time.Time `json:"deadline,omitempty"`
 	NecessaryCapabilities []string   `json:"necessary_capabilities,omitempty"`
+	Categories            []string   `json:"categories,omitempty" doc:"Categories (e.g. cooking, toilets) users can like or dislike"`
+	NotBefore             *time.Time `json:"not_before,omitempty" doc:"Keep the task as a draft without assignments until this time, then publish it"`
+	Resources             []string   `json:"resources,omitempty" doc:"Names of the catalogue resources the task needs, it waits while another task holds them"`
+	AuctionMin            uint       `json:"auction_min,omitempty" doc:"Let users bid their credit for this many minutes after publishing, the lowest bids win"`
+	Assignees             []string   `json:"assignees,omitempty" doc:"Discord IDs of users assigned directly, the rest of the necessary workers is picked automatically"`
+	TeamMode              bool       `json:"team_mode,omitempty" doc:"Pick the workers as a team covering the capabilities and rotating partners"`
+	TrainingMode          bool       `json:"training_mode,omitempty" doc:"Pair skilled mentors with trainees in the team (implies team_mode)"`
 }
 
 type CreateTaskInput struct {
-	Body TaskCreateInputBody
+	IdempotencyKey string `header:"Idempotency-Key" maxLength:"255" doc:"Retries with the same key get the task created by the first request instead of a new one"`
+	Body           TaskCreateInputBody
+}
+
+type TaskCreatedResponse struct {
+	ETag               string `header:"ETag" doc:"Version of the task"`
+	IdempotentReplayed string `header:"Idempotent-Replayed" doc:"true when the task was created by an earlier request with the same Idempotency-Key"`
+	Body               TaskData
 }
 
 type UpdateTaskInputBody struct {
@@ -477,14 +1171,70 @@ type UpdateTaskInputBody struct {
 	AssignmentTimeoutMin  uint       `json:"assignment_timeout_min,omitempty"`
 	Deadline              *time.Time `json:"deadline,omitempty"`
 	NecessaryCapabilities []string   `json:"necessary_capabilities,omitempty"`
+	Categories            []string   `json:"categories,omitempty" doc:"Replaces the categories"`
+	Resources             []string   `json:"resources,omitempty" doc:"Replaces the required resources"`
+	NotBefore             *time.Time `json:"not_before,omitempty" doc:"Moves the publishing time of a draft, a past time publishes it now"`
+	Assignees             []string   `json:"assignees,omitempty" doc:"Replaces the manually assigned users, an empty list removes them (acked users stay)"`
+	TeamMode              *bool      `json:"team_mode,omitempty"`
+	TrainingMode          *bool      `json:"training_mode,omitempty"`
 }
 
 type UpdateTaskInput struct {
-	ID   int                 `path:"id"`
-	Body UpdateTaskInputBody
+	ID      int    `path:"id"`
+	IfMatch string `header:"If-Match" doc:"ETag of the task the update is based on, a changed task fails with 412"`
+	Body    UpdateTaskInputBody
+}
+
+// patch keeps the fields which are not set.
+func (b UpdateTaskInputBody) patch() PatchTaskInputBody {
+	p := PatchTaskInputBody{
+		Deadline:              b.Deadline,
+		NecessaryCapabilities: b.NecessaryCapabilities,
+		Categories:            b.Categories,
+		Resources:             b.Resources,
+		NotBefore:             b.NotBefore,
+		Assignees:             b.Assignees,
+		TeamMode:              b.TeamMode,
+		TrainingMode:          b.TrainingMode,
+	}
+	if b.Name != "" {
+		p.Name = &b.Name
+	}
+	if b.NecessaryWorkers > 0 {
+		p.NecessaryWorkers = &b.NecessaryWorkers
+	}
+	if b.EstimatedTimeMin > 0 {
+		p.EstimatedTimeMin = &b.EstimatedTimeMin
+	}
+	if b.AssignmentTimeoutMin > 0 {
+		p.AssignmentTimeoutMin = &b.AssignmentTimeoutMin
+	}
+	return p
+}
+
+type PatchTaskInputBody struct {
+	Name                  *string    `json:"name,omitempty" minLength:"1"`
+	NecessaryWorkers      *uint      `json:"necessary_workers,omitempty" minimum:"1"`
+	EstimatedTimeMin      *uint      `json:"estimated_time_min,omitempty"`
+	AssignmentTimeoutMin  *uint      `json:"assignment_timeout_min,omitempty" doc:"0 turns the assignment timeout off"`
+	Deadline              *time.Time `json:"deadline,omitempty"`
+	NecessaryCapabilities []string   `json:"necessary_capabilities,omitempty" doc:"Replaces the capabilities, an empty list removes them"`
+	Categories            []string   `json:"categories,omitempty" doc:"Replaces the categories, an empty list removes them"`
+	Resources             []string   `json:"resources,omitempty" doc:"Replaces the required resources, an empty list removes them"`
+	NotBefore             *time.Time `json:"not_before,omitempty" doc:"Moves the publishing time of a draft, a past time publishes it now"`
+	Assignees             []string   `json:"assignees,omitempty" doc:"Replaces the manually assigned users, an empty list removes them (acked users stay)"`
+	TeamMode              *bool      `json:"team_mode,omitempty"`
+	TrainingMode          *bool      `json:"training_mode,omitempty"`
+}
+
+type PatchTaskInput struct {
+	ID      int    `path:"id"`
+	IfMatch string `header:"If-Match" doc:"ETag of the task the change is based on, a changed task fails with 412"`
+	Body    PatchTaskInputBody
 }
 
 type TaskCreateResponse struct {
+	ETag string `header:"ETag" doc:"Version of the task, send it as If-Match to update the task only if nobody changed it meanwhile"`
 	Body TaskData
 }
 
@@ -493,14 +1243,277 @@ type TaskActionInput struct {
 }
 
 type TaskUserActionBody struct {
-	UserId string `json:"user_id"`
+	UserId  string `json:"user_id,omitempty" doc:"The acting user, a login session or a key bound to a user always acts as its user"`
+	Confirm bool   `json:"confirm,omitempty" doc:"Ack even if the fairness policy asks for a confirmation"`
 }
 
 type TaskUserActionInput struct {
-	ID   int                `path:"id"`
+	ID   int `path:"id"`
 	Body TaskUserActionBody
 }
 
+type TaskAssignBody struct {
+	UserIds []string `json:"user_ids" minItems:"1" doc:"Discord IDs of the users to assign"`
+}
+
+type TaskAssignInput struct {
+	ID   int `path:"id"`
+	Body TaskAssignBody
+}
+
+type TaskHandoffBody struct {
+	UserId   string `json:"user_id" doc:"The assignee offering the task"`
+	ToUserId string `json:"to_user_id" doc:"The user who should take the task over"`
+}
+
+type TaskHandoffInput struct {
+	ID   int `path:"id"`
+	Body TaskHandoffBody
+}
+
+type TaskSwapBody struct {
+	UserId     string `json:"user_id" doc:"The assignee offering the task"`
+	ToUserId   string `json:"to_user_id" doc:"The user to swap with"`
+	SwapTaskId uint   `json:"swap_task_id" doc:"The task of to_user_id taken in exchange"`
+}
+
+type TaskSwapInput struct {
+	ID   int `path:"id"`
+	Body TaskSwapBody
+}
+
+type TaskBidBody struct {
+	UserId  string `json:"user_id"`
+	TimeMin uint   `json:"time_min" minimum:"1" doc:"Minutes of credit the user wants for the task"`
+}
+
+type TaskBidInput struct {
+	ID   int `path:"id"`
+	Body TaskBidBody
+}
+
+type BidData struct {
+	TaskId  uint      `json:"task_id"`
+	UserId  string    `json:"user_id"`
+	TimeMin uint      `json:"time_min"`
+	Created time.Time `json:"created"`
+}
+
+type BidResponse struct {
+	Body BidData
+}
+
+type BidsResponse struct {
+	Body []BidData
+}
+
+type TransferData struct {
+	ID         uint       `json:"id"`
+	Kind       string     `json:"kind" enum:"handoff,swap"`
+	TaskId     uint       `json:"task_id"`
+	FromUserId string     `json:"from_user_id"`
+	ToUserId   string     `json:"to_user_id"`
+	SwapTaskId uint       `json:"swap_task_id,omitempty"`
+	Created    time.Time  `json:"created"`
+	Accepted   *time.Time `json:"accepted,omitempty"`
+	Declined   *time.Time `json:"declined,omitempty"`
+}
+
+type TransferResponse struct {
+	Body TransferData
+}
+
+type ResourceData struct {
+	ID             uint      `json:"id"`
+	Name           string    `json:"name"`
+	Kind           string    `json:"kind,omitempty"`
+	Created        time.Time `json:"created"`
+	LockedByTaskId uint      `json:"locked_by_task_id,omitempty" doc:"Task holding the resource, 0 when it is free"`
+}
+
+type ResourceResponse struct {
+	Body ResourceData
+}
+
+type ResourcesResponse struct {
+	Body []ResourceData
+}
+
+type CreateResourceBody struct {
+	Name string `json:"name" doc:"Name of the resource, e.g. van"`
+	Kind string `json:"kind,omitempty" doc:"Kind of the resource, e.g. vehicle, tool, room"`
+}
+
+type CreateResourceInput struct {
+	Body CreateResourceBody
+}
+
+type DutyData struct {
+	ID              uint      `json:"id"`
+	Name            string    `json:"name"`
+	WorkersPerShift uint      `json:"workers_per_shift"`
+	Created         time.Time `json:"created"`
+}
+
+type DutyResponse struct {
+	Body DutyData
+}
+
+type DutiesResponse struct {
+	Body []DutyData
+}
+
+type CreateDutyBody struct {
+	Name            string `json:"name" doc:"Name of the duty, e.g. kitchen"`
+	WorkersPerShift uint   `json:"workers_per_shift" default:"1"`
+}
+
+type CreateDutyInput struct {
+	Body CreateDutyBody
+}
+
+type ScheduleShiftsBody struct {
+	Start time.Time `json:"start" doc:"Start of the first shift"`
+	End   time.Time `json:"end" doc:"End of the first shift"`
+	Days  uint      `json:"days,omitempty" default:"1" doc:"Number of daily shifts, the first one included"`
+}
+
+type ScheduleShiftsInput struct {
+	ID   int `path:"id"`
+	Body ScheduleShiftsBody
+}
+
+type RosterInput struct {
+	DutyId uint `query:"duty_id" doc:"Only shifts of this duty"`
+}
+
+type ShiftData struct {
+	ID          uint      `json:"id"`
+	DutyId      uint      `json:"duty_id"`
+	DutyName    string    `json:"duty_name"`
+	UserId      string    `json:"user_id"`
+	Start       time.Time `json:"start"`
+	End         time.Time `json:"end"`
+	DurationMin uint      `json:"duration_min" doc:"Minutes credited as work once the shift ends"`
+}
+
+type ShiftsResponse struct {
+	Body []ShiftData
+}
+
+type TransfersInput struct {
+	UserId string `query:"user_id" doc:"Only offers sent or received by this user"`
+}
+
+type ApiKeyData struct {
+	ID       uint       `json:"id"`
+	Name     string     `json:"name"`
+	Hint     string     `json:"hint" doc:"The beginning of the secret to tell the keys apart"`
+	Scopes   []string   `json:"scopes"`
+	UserId   string     `json:"user_id,omitempty" doc:"The user the key acts as"`
+	Created  time.Time  `json:"created"`
+	LastUsed *time.Time `json:"last_used,omitempty"`
+	Revoked  *time.Time `json:"revoked,omitempty"`
+}
+
+type ApiKeysResponse struct {
+	Body []ApiKeyData
+}
+
+type ApiKeyResponse struct {
+	Body ApiKeyData
+}
+
+type ApiKeyCreateBody struct {
+	Name   string   `json:"name" minLength:"1"`
+	Scopes []string `json:"scopes" minItems:"1" enum:"read,write,admin,ack-as-self" doc:"admin grants everything, write grants read and acking as any user"`
+	UserId string   `json:"user_id,omitempty" doc:"Bind the key to a user, it can act only as them (required for ack-as-self)"`
+}
+
+type ApiKeyCreateInput struct {
+	Body ApiKeyCreateBody
+}
+
+type ApiKeyCreatedData struct {
+	ApiKeyData
+	Secret string `json:"secret" doc:"The API key, it is not shown again"`
+}
+
+type ApiKeyCreateResponse struct {
+	Body ApiKeyCreatedData
+}
+
+type WebhookData struct {
+	ID         uint      `json:"id"`
+	Url        string    `json:"url"`
+	EventTypes []string  `json:"event_types" doc:"Subscribed event types, empty for all events"`
+	Secret     string    `json:"secret,omitempty" doc:"Key of the X-Chores-Signature HMAC, shown only on creation"`
+	Created    time.Time `json:"created"`
+}
+
+type WebhooksResponse struct {
+	Body []WebhookData
+}
+
+type WebhookResponse struct {
+	Body WebhookData
+}
+
+type WebhookCreateBody struct {
+	Url        string   `json:"url" format:"uri"`
+	EventTypes []string `json:"event_types,omitempty" doc:"Event types to deliver (e.g. task_created, task_acked, task_done), all events when empty"`
+	Secret     string   `json:"secret,omitempty" doc:"Signing secret, a random one is generated when empty"`
+}
+
+type WebhookCreateInput struct {
+	Body WebhookCreateBody
+}
+
+type DeliveriesInput struct {
+	ID     int    `path:"id"`
+	Status string `query:"status" enum:"pending,delivered,dead" doc:"Only deliveries in this state"`
+	Limit  int    `query:"limit" minimum:"1" maximum:"500" default:"50"`
+}
+
+type DeliveryActionInput struct {
+	ID         int `path:"id"`
+	DeliveryId int `path:"delivery_id"`
+}
+
+type DeliveryData struct {
+	ID           uint       `json:"id"`
+	WebhookId    uint       `json:"webhook_id"`
+	EventType    string     `json:"event_type"`
+	Status       string     `json:"status" enum:"pending,delivered,dead"`
+	Attempts     uint       `json:"attempts"`
+	NextAttempt  *time.Time `json:"next_attempt,omitempty" doc:"When a pending delivery is tried again"`
+	ResponseCode int        `json:"response_code,omitempty"`
+	LastError    string     `json:"last_error,omitempty"`
+	Created      time.Time  `json:"created"`
+	Delivered    *time.Time `json:"delivered,omitempty"`
+}
+
+type DeliveriesResponse struct {
+	Body []DeliveryData
+}
+
+type DeliveryResponse struct {
+	Body DeliveryData
+}
+
+type TransfersResponse struct {
+	Body []TransferData
+}
+
+type TransferActionBody struct {
+	UserId string `json:"user_id" doc:"The user the offer was made to"`
+}
+
+type TransferActionInput struct {
+	ID   int `path:"id"`
+	Body TransferActionBody
+}
+
 type UserStats struct {
 	WorkedCount     float64 `json:"worked_count"`
 	WorkedMin       float64 `json:"worked_min"`
@@ -526,6 +1539,38 @@ type UsersResponse struct {
 	Body []UserData
 }
 
+type AvailabilityBlock struct {
+	From   time.Time `json:"from"`
+	To     time.Time `json:"to"`
+	Reason string    `json:"reason,omitempty"`
+}
+
+type UserPatchBody struct {
+	QuietStart  *string              `json:"quiet_start,omitempty" doc:"Start of the daily quiet hours (HH:MM in the server timezone), empty clears them"`
+	QuietEnd    *string              `json:"quiet_end,omitempty" doc:"End of the daily quiet hours (HH:MM in the server timezone), empty clears them"`
+	Unavailable *[]AvailabilityBlock `json:"unavailable,omitempty" doc:"Replaces the upcoming away blocks"`
+	Likes       []string             `json:"likes,omitempty" doc:"Replaces the preferred chore categories or skills"`
+	Dislikes    []string             `json:"dislikes,omitempty" doc:"Replaces the avoided chore categories or skills"`
+}
+
+type UserPatchInput struct {
+	ID   string `path:"id" doc:"Discord ID of the user"`
+	Body UserPatchBody
+}
+
+type UserSettingsData struct {
+	UserId      string              `json:"user_id"`
+	QuietStart  string              `json:"quiet_start"`
+	QuietEnd    string              `json:"quiet_end"`
+	Unavailable []AvailabilityBlock `json:"unavailable"`
+	Likes       []string            `json:"likes"`
+	Dislikes    []string            `json:"dislikes"`
+}
+
+type UserSettingsResponse struct {
+	Body UserSettingsData
+}
+
 type TaskStatsData struct {
 	TotalTimeMin uint `json:"total_time_min"`
 	WorkerCount  uint `json:"worker_count"`
@@ -535,7 +1580,153 @@ type TaskStatsResponse struct {
 	Body TaskStatsData
 }
 
-func toTaskData(chore storage.Chore) TaskData {
+func (a *Api) taskResponse(chore storage.Chore) (*TaskCreateResponse, error) {
+	assignments, err := a.storage.GetChoreAssignments(chore.ID)
+	if err != nil {
+		return nil, err
+	}
+	waitlist, err := a.storage.GetWaitlist(chore.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &TaskCreateResponse{ETag: taskETag(chore.Version), Body: toTaskData(chore, assignments, waitlist)}, nil
+}
+
+// patchTask applies the set fields of the body to the task. With If-Match the task must not have changed since
+// the client read it, the check of the details is atomic with their update.
+func (a *Api) patchTask(id uint, ifMatch string, body PatchTaskInputBody) (*TaskCreateResponse, error) {
+	chore, err := a.storage.GetChore(id)
+	if err != nil {
+		return nil, err
+	}
+	version, err := ifMatchVersion(ifMatch, chore)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.ui.CheckResources(body.Resources); err != nil {
+		return nil, huma.Error400BadRequest(err.Error())
+	}
+
+	updated, err := a.ui.EditChoreDetails(id, version, ui.ChoreEdit{
+		Name:                 body.Name,
+		NecessaryWorkers:     body.NecessaryWorkers,
+		EstimatedTimeMin:     body.EstimatedTimeMin,
+		AssignmentTimeoutMin: body.AssignmentTimeoutMin,
+		Deadline:             body.Deadline,
+		Capabilities:         body.NecessaryCapabilities,
+		Categories:           body.Categories,
+		Resources:            body.Resources,
+		TeamMode:             body.TeamMode,
+		TrainingMode:         body.TrainingMode,
+		NotBefore:            body.NotBefore,
+	})
+	if errors.Is(err, storage.ErrVersionConflict) {
+		return nil, errPreconditionFailed(updated)
+	}
+	if errors.Is(err, chores.ErrChorePublished) {
+		return nil, huma.Error409Conflict(err.Error())
+	}
+	if err != nil {
+		return nil, err
+	}
+	if body.Assignees != nil {
+		updated, _, err = a.ui.SetAssignees(updated.ID, body.Assignees)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return a.taskResponse(updated)
+}
+
+// createTask saves the task and publishes it to Discord.
+func (a *Api) createTask(body TaskCreateInputBody) (*TaskCreateResponse, error) {
+	workers := body.NecessaryWorkers
+	if workers == 0 {
+		workers = 1
+	}
+	estTime := body.EstimatedTimeMin
+	if estTime == 0 {
+		estTime = 10
+	}
+	timeoutMin := body.AssignmentTimeoutMin
+	if timeoutMin == 0 {
+		timeoutMin = 15
+	}
+	deadline := body.Deadline
+	if deadline == nil {
+		d := time.Now().Add(24 * time.Hour)
+		deadline = &d
+	}
+
+	chore := storage.Chore{
+		Name:                 body.Name,
+		NecessaryWorkers:     workers,
+		EstimatedTimeMin:     estTime,
+		AssignmentTimeoutMin: timeoutMin,
+		Deadline:             deadline,
+		CreatorId:            "API",
+		Created:              time.Now(),
+		TeamMode:             body.TeamMode || body.TrainingMode,
+		TrainingMode:         body.TrainingMode,
+		AuctionMin:           body.AuctionMin,
+		NotBefore:            body.NotBefore,
+	}
+	if len(body.NecessaryCapabilities) > 0 {
+		chore.SetCapabilities(body.NecessaryCapabilities)
+	}
+	chore.SetCategories(body.Categories)
+	chore.SetResources(body.Resources)
+	if err := a.ui.CheckResources(chore.GetResources()); err != nil {
+		return nil, huma.Error400BadRequest(err.Error())
+	}
+
+	// The manual assignees are assigned once the chore is published, before the strategy fills the rest.
+	chore.SetHeldAssignees(body.Assignees)
+
+	saved, _, err := a.ui.PublishChore(chore)
+	if err != nil {
+		a.logger.Warn("Failed to publish chore to Discord", "error", err)
+		if saved.ID == 0 {
+			saved, err = a.storage.SaveChore(chore)
+		} else {
+			saved, err = a.storage.GetChore(saved.ID)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return a.taskResponse(saved)
+}
+
+// encodeCursor makes an opaque page cursor from the ID of the last task of the page.
+func encodeCursor(id uint) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatUint(uint64(id), 10)))
+}
+
+func decodeCursor(cursor string) (uint, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, err
+	}
+	id, err := strconv.ParseUint(string(b), 10, 64)
+	return uint(id), err
+}
+
+func toTaskData(chore storage.Chore, assignments []storage.ChoreAssignment, waitlist []storage.WaitlistEntry) TaskData {
+	staffing := chore.Staffing(assignments)
+	assignees := chore.GetHeldAssignees()
+	for _, a := range assignments {
+		if a.ChoreId == chore.ID && a.Manual && a.Active() {
+			assignees = append(assignees, a.UserId)
+		}
+	}
+	waiting := []string{}
+	for _, e := range waitlist {
+		if e.ChoreId == chore.ID {
+			waiting = append(waiting, e.UserId)
+		}
+	}
 	return TaskData{
 		ID:                    chore.ID,
 		Name:                  chore.Name,
@@ -548,5 +1739,145 @@ func toTaskData(chore storage.Chore) TaskData {
 		Cancelled:             chore.Cancelled,
 		Deadline:              chore.Deadline,
 		NecessaryCapabilities: chore.GetCapabilities(),
+		Categories:            chore.GetCategories(),
+		Resources:             chore.GetResources(),
+		NotBefore:             chore.NotBefore,
+		Status:                string(chore.Status),
+		Draft:                 chore.Status == storage.ChoreDraft,
+		Bounty:                chore.BountyMultiplier(),
+		AuctionMin:            chore.AuctionMin,
+		AuctionUntil:          chore.AuctionUntil,
+		AuctionOpen:           chore.AuctionOpen(),
+		Staffing: StaffingData{
+			State:    staffing.State,
+			Assigned: staffing.Assigned,
+			Needed:   staffing.Needed,
+		},
+		Assignees:    assignees,
+		Waitlist:     waiting,
+		TeamMode:     chore.TeamMode,
+		TrainingMode: chore.TrainingMode,
+		Version:      chore.Version,
+	}
+}
+
+func toResourceData(r storage.Resource, locks map[string]uint) ResourceData {
+	return ResourceData{
+		ID:             r.ID,
+		Name:           r.Name,
+		Kind:           r.Kind,
+		Created:        r.Created,
+		LockedByTaskId: locks[r.Name],
+	}
+}
+
+func toDutyData(d storage.Duty) DutyData {
+	return DutyData{
+		ID:              d.ID,
+		Name:            d.Name,
+		WorkersPerShift: d.WorkersPerShift,
+		Created:         d.Created,
+	}
+}
+
+func toShiftData(sh storage.Shift) ShiftData {
+	return ShiftData{
+		ID:          sh.ID,
+		DutyId:      sh.DutyId,
+		DutyName:    sh.Duty.Name,
+		UserId:      sh.UserId,
+		Start:       sh.Start,
+		End:         sh.End,
+		DurationMin: sh.DurationMin,
+	}
+}
+
+func toBidData(b storage.Bid) BidData {
+	return BidData{
+		TaskId:  b.ChoreId,
+		UserId:  b.UserId,
+		TimeMin: b.TimeMin,
+		Created: b.Created,
+	}
+}
+
+func toApiKeyData(k storage.ApiKey) ApiKeyData {
+	return ApiKeyData{
+		ID:       k.ID,
+		Name:     k.Name,
+		Hint:     k.Hint,
+		Scopes:   k.GetScopes(),
+		UserId:   k.UserId,
+		Created:  k.Created,
+		LastUsed: k.LastUsed,
+		Revoked:  k.Revoked,
+	}
+}
+
+func toWebhookData(w storage.Webhook, withSecret bool) WebhookData {
+	data := WebhookData{
+		ID:         w.ID,
+		Url:        w.Url,
+		EventTypes: w.GetEventTypes(),
+		Created:    w.Created,
+	}
+	if withSecret {
+		data.Secret = w.Secret
+	}
+	return data
+}
+
+func toDeliveryData(d storage.WebhookDelivery) DeliveryData {
+	data := DeliveryData{
+		ID:           d.ID,
+		WebhookId:    d.WebhookId,
+		EventType:    string(d.EventType),
+		Status:       string(d.Status),
+		Attempts:     d.Attempts,
+		ResponseCode: d.ResponseCode,
+		LastError:    d.LastError,
+		Created:      d.Created,
+		Delivered:    d.Delivered,
+	}
+	if d.Status == storage.DeliveryPending {
+		data.NextAttempt = &d.NextAttempt
+	}
+	return data
+}
+
+func toTransferData(t storage.AssignmentTransfer) TransferData {
+	return TransferData{
+		ID:         t.ID,
+		Kind:       t.Kind,
+		TaskId:     t.ChoreId,
+		FromUserId: t.FromUserId,
+		ToUserId:   t.ToUserId,
+		SwapTaskId: t.SwapChoreId,
+		Created:    t.Created,
+		Accepted:   t.Accepted,
+		Declined:   t.Declined,
+	}
+}
+
+func (a *Api) userSettingsResponse(userId string) (*UserSettingsResponse, error) {
+	settings, err := a.storage.GetUserSettings(userId)
+	if err != nil {
+		return nil, err
+	}
+	blocks, err := a.storage.GetUserAvailabilities(userId)
+	if err != nil {
+		return nil, err
+	}
+	resp := UserSettingsData{
+		UserId:      userId,
+		QuietStart:  settings.QuietStart,
+		QuietEnd:    settings.QuietEnd,
+		Unavailable: []AvailabilityBlock{},
+		Likes:       settings.GetLikes(),
+		Dislikes:    settings.GetDislikes(),
+	}
+	for _, b := range blocks {
+		resp.Unavailable = append(resp.Unavailable, AvailabilityBlock{From: b.From, To: b.To, Reason: b.Reason})
 	}
+	return &UserSettingsResponse{Body: resp}, nil
 }
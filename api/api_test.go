@@ -1,6 +1,7 @@
 package api
 
 import (
+	"bufio"
 	"bytes"
 	"encoding/json"
 	"fmt"
@@ -9,6 +10,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"slices"
 	"strings"
 	"testing"
 	"time"
@@ -42,7 +44,7 @@ func setupTestApi(t *testing.T) (*Api, *storage.Storage, *ui.Ui, func()) {
 		t.Fatalf("Failed to create storage: %v", err)
 	}
 
-	choresLogic := chores.NewChoresLogic(s, logger, chores.Config{OversampleRatio: 0})
+	choresLogic := chores.NewChoresLogic(s, logger, chores.Config{OversampleRatio: 0, BountyStep: 0.5, BountyMax: 3})
 	uiConf := ui.Config{DiscordChannelId: "123456"}
 	u := ui.NewUi(s, logger, &choresLogic, nil, uiConf)
 
@@ -87,7 +89,7 @@ func TestGetTasksEmpty(t *testing.T) {
 }
 
 func TestCreateAndGetTask(t *testing.T) {
-	api, _, _, cleanup := setupTestApi(t)
+	api, stor, _, cleanup := setupTestApi(t)
 	defer cleanup()
 
 	handler := api.SetupRoutes()
@@ -143,6 +145,843 @@ func TestCreateAndGetTask(t *testing.T) {
 	if fetched.ID != created.ID || fetched.Name != "Wash dishes" {
 		t.Fatalf("Fetched task mismatch: %+v", fetched)
 	}
+
+	// Nobody is present in headless mode.
+	if fetched.Staffing.State != storage.StaffingUnstaffed || fetched.Staffing.Needed != 1 {
+		t.Fatalf("Expected unstaffed task, got %+v", fetched.Staffing)
+	}
+	dbChore, _ := stor.GetChore(created.ID)
+	if !dbChore.Understaffed {
+		t.Fatalf("Expected task to be in the backlog")
+	}
+}
+
+func TestManualAssignmentViaAPI(t *testing.T) {
+	api, stor, _, cleanup := setupTestApi(t)
+	defer cleanup()
+
+	handler := api.SetupRoutes()
+
+	createReq := TaskCreateInputBody{
+		Name:             "Cook dinner",
+		NecessaryWorkers: 2,
+		Assignees:        []string{"alice"},
+	}
+	body, _ := json.Marshal(createReq)
+	req := httptest.NewRequest(http.MethodPost, "/tasks", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var created TaskData
+	json.Unmarshal(w.Body.Bytes(), &created)
+	if created.Staffing.State != storage.StaffingUnderstaffed || created.Staffing.Assigned != 1 {
+		t.Fatalf("Expected one of two slots taken, got %+v", created.Staffing)
+	}
+	if len(created.Assignees) != 1 || created.Assignees[0] != "alice" {
+		t.Fatalf("Expected alice as assignee, got %v", created.Assignees)
+	}
+
+	assignBody, _ := json.Marshal(TaskAssignBody{UserIds: []string{"bob"}})
+	reqAssign := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/tasks/%d/assign", created.ID), bytes.NewReader(assignBody))
+	reqAssign.Header.Set("Content-Type", "application/json")
+	wAssign := httptest.NewRecorder()
+	handler.ServeHTTP(wAssign, reqAssign)
+
+	if wAssign.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", wAssign.Code, wAssign.Body.String())
+	}
+	var assigned TaskData
+	json.Unmarshal(wAssign.Body.Bytes(), &assigned)
+	if assigned.Staffing.State != storage.StaffingStaffed {
+		t.Fatalf("Expected staffed task, got %+v", assigned.Staffing)
+	}
+	dbChore, _ := stor.GetChore(created.ID)
+	if dbChore.Understaffed {
+		t.Fatalf("Expected task to leave the backlog once staffed")
+	}
+
+	// Replacing the assignees through an update drops bob again.
+	updateBody, _ := json.Marshal(UpdateTaskInputBody{Assignees: []string{"alice"}})
+	reqUpdate := httptest.NewRequest(http.MethodPut, fmt.Sprintf("/tasks/%d", created.ID), bytes.NewReader(updateBody))
+	reqUpdate.Header.Set("Content-Type", "application/json")
+	wUpdate := httptest.NewRecorder()
+	handler.ServeHTTP(wUpdate, reqUpdate)
+
+	var updated TaskData
+	json.Unmarshal(wUpdate.Body.Bytes(), &updated)
+	if len(updated.Assignees) != 1 || updated.Staffing.Assigned != 1 {
+		t.Fatalf("Expected only alice to stay assigned, got %v %+v", updated.Assignees, updated.Staffing)
+	}
+}
+
+func postJSON(handler http.Handler, path string, payload any) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(payload)
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	return w
+}
+
+func TestHandoffAndSwapViaAPI(t *testing.T) {
+	api, stor, _, cleanup := setupTestApi(t)
+	defer cleanup()
+
+	handler := api.SetupRoutes()
+
+	var dishes, trash TaskData
+	json.Unmarshal(postJSON(handler, "/tasks", TaskCreateInputBody{Name: "Dishes", Assignees: []string{"alice"}}).Body.Bytes(), &dishes)
+	json.Unmarshal(postJSON(handler, "/tasks", TaskCreateInputBody{Name: "Trash", Assignees: []string{"bob"}}).Body.Bytes(), &trash)
+
+	// Bob already holds the trash, he cannot get it handed over.
+	w := postJSON(handler, fmt.Sprintf("/tasks/%d/handoff", trash.ID), TaskHandoffBody{UserId: "alice", ToUserId: "bob"})
+	if w.Code == http.StatusOK {
+		t.Fatalf("Expected handoff of a chore alice does not hold to fail")
+	}
+
+	w = postJSON(handler, fmt.Sprintf("/tasks/%d/handoff", dishes.ID), TaskHandoffBody{UserId: "alice", ToUserId: "carol"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var handoff TransferData
+	json.Unmarshal(w.Body.Bytes(), &handoff)
+
+	w = postJSON(handler, fmt.Sprintf("/transfers/%d/accept", handoff.ID), TransferActionBody{UserId: "bob"})
+	if w.Code == http.StatusOK {
+		t.Fatalf("Expected only carol to be able to accept the offer")
+	}
+	w = postJSON(handler, fmt.Sprintf("/transfers/%d/accept", handoff.ID), TransferActionBody{UserId: "carol"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	from, _ := stor.GetChoreAssignment(dishes.ID, "alice")
+	to, _ := stor.GetChoreAssignment(dishes.ID, "carol")
+	if from.Transferred == nil || from.Active() {
+		t.Fatalf("Expected alice's assignment to be transferred, got %+v", from)
+	}
+	if to.Acked == nil || to.PreviousAssignmentId == nil || *to.PreviousAssignmentId != from.ID {
+		t.Fatalf("Expected carol's acked assignment linked to alice's, got %+v", to)
+	}
+
+	// Carol swaps the dishes for bob's trash, bob declines first.
+	w = postJSON(handler, fmt.Sprintf("/tasks/%d/swap", dishes.ID), TaskSwapBody{UserId: "carol", ToUserId: "bob", SwapTaskId: trash.ID})
+	var swap TransferData
+	json.Unmarshal(w.Body.Bytes(), &swap)
+	postJSON(handler, fmt.Sprintf("/transfers/%d/decline", swap.ID), TransferActionBody{UserId: "bob"})
+	w = postJSON(handler, fmt.Sprintf("/transfers/%d/accept", swap.ID), TransferActionBody{UserId: "bob"})
+	if w.Code == http.StatusOK {
+		t.Fatalf("Expected a declined offer not to be accepted")
+	}
+
+	w = postJSON(handler, fmt.Sprintf("/tasks/%d/swap", dishes.ID), TaskSwapBody{UserId: "carol", ToUserId: "bob", SwapTaskId: trash.ID})
+	json.Unmarshal(w.Body.Bytes(), &swap)
+	w = postJSON(handler, fmt.Sprintf("/transfers/%d/accept", swap.ID), TransferActionBody{UserId: "bob"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	bobDishes, _ := stor.GetChoreAssignment(dishes.ID, "bob")
+	carolTrash, _ := stor.GetChoreAssignment(trash.ID, "carol")
+	if !bobDishes.Active() || !carolTrash.Active() {
+		t.Fatalf("Expected the chores to be swapped, got %+v and %+v", bobDishes, carolTrash)
+	}
+
+	// The stats only count the current holders.
+	stats, _ := stor.GetAssignedStats()
+	if stats["alice"].Count != 0 || stats["bob"].Count != 1 || stats["carol"].Count != 1 {
+		t.Fatalf("Unexpected assigned stats %+v", stats)
+	}
+}
+
+func TestPatchUserAvailabilityViaAPI(t *testing.T) {
+	api, stor, _, cleanup := setupTestApi(t)
+	defer cleanup()
+
+	handler := api.SetupRoutes()
+
+	from := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	payload := UserPatchBody{
+		QuietStart:  new(string),
+		QuietEnd:    new(string),
+		Unavailable: &[]AvailabilityBlock{{From: from, To: from.Add(2 * time.Hour), Reason: "hike"}},
+	}
+	*payload.QuietStart = "23:00"
+	*payload.QuietEnd = "07:00"
+
+	body, _ := json.Marshal(payload)
+	req := httptest.NewRequest(http.MethodPatch, "/users/alice", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var res UserSettingsData
+	json.Unmarshal(w.Body.Bytes(), &res)
+	if res.QuietStart != "23:00" || res.QuietEnd != "07:00" || len(res.Unavailable) != 1 {
+		t.Fatalf("Unexpected availability: %+v", res)
+	}
+
+	_, unavailable, err := stor.UnavailableUntil("alice", from.Add(time.Minute))
+	if err != nil || !unavailable {
+		t.Fatalf("Expected alice to be unavailable during the block (%v)", err)
+	}
+
+	// Invalid times are rejected.
+	req = httptest.NewRequest(http.MethodPatch, "/users/alice", strings.NewReader(`{"quiet_start": "25:00"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400 for an invalid time, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodPatch, "/users/alice", strings.NewReader(`{"unavailable": []}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	json.Unmarshal(w.Body.Bytes(), &res)
+	if w.Code != http.StatusOK || len(res.Unavailable) != 0 || res.QuietStart != "23:00" {
+		t.Fatalf("Expected the blocks to be cleared and quiet hours kept, got %d %+v", w.Code, res)
+	}
+
+	req = httptest.NewRequest(http.MethodPatch, "/users/alice", strings.NewReader(`{"likes": ["Cooking", " shopping"], "dislikes": ["toilets"]}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	json.Unmarshal(w.Body.Bytes(), &res)
+	if w.Code != http.StatusOK || !slices.Equal(res.Likes, []string{"cooking", "shopping"}) || !slices.Equal(res.Dislikes, []string{"toilets"}) {
+		t.Fatalf("Expected the preferences to be set, got %d %+v", w.Code, res)
+	}
+
+	req = httptest.NewRequest(http.MethodPatch, "/users/alice", strings.NewReader(`{"likes": ["toilets"]}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400 for a liked and disliked category, got %d", w.Code)
+	}
+}
+
+func TestAuctionViaAPI(t *testing.T) {
+	api, stor, _, cleanup := setupTestApi(t)
+	defer cleanup()
+
+	handler := api.SetupRoutes()
+
+	var created TaskData
+	json.Unmarshal(postJSON(handler, "/tasks", TaskCreateInputBody{Name: "Paint the shed", EstimatedTimeMin: 120, AuctionMin: 30}).Body.Bytes(), &created)
+	if !created.AuctionOpen || created.AuctionUntil == nil {
+		t.Fatalf("Expected an open auction, got %+v", created)
+	}
+
+	for _, b := range []TaskBidBody{{UserId: "alice", TimeMin: 90}, {UserId: "bob", TimeMin: 60}, {UserId: "alice", TimeMin: 50}} {
+		w := postJSON(handler, fmt.Sprintf("/tasks/%d/bids", created.ID), b)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Bid failed with %d: %s", w.Code, w.Body.String())
+		}
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/tasks/%d/bids", created.ID), nil))
+	var bids []BidData
+	json.Unmarshal(w.Body.Bytes(), &bids)
+	if len(bids) != 2 || bids[0].UserId != "alice" || bids[0].TimeMin != 50 {
+		t.Fatalf("Expected alice's rebid to lead, got %+v", bids)
+	}
+
+	// Acking would skip the bidding.
+	w = postJSON(handler, fmt.Sprintf("/tasks/%d/ack", created.ID), TaskUserActionBody{UserId: "carol"})
+	if w.Code != http.StatusConflict {
+		t.Fatalf("Expected status 409 for an ack during the bidding, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = postJSON(handler, fmt.Sprintf("/tasks/%d/close_auction", created.ID), nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Closing the auction failed with %d: %s", w.Code, w.Body.String())
+	}
+	ass, err := stor.GetChoreAssignment(created.ID, "alice")
+	if err != nil || ass.Acked == nil || ass.BidMin != 50 {
+		t.Fatalf("Expected alice to win with 50 min: %v, %+v", err, ass)
+	}
+
+	// The bidding is over.
+	w = postJSON(handler, fmt.Sprintf("/tasks/%d/bids", created.ID), TaskBidBody{UserId: "bob", TimeMin: 10})
+	if w.Code != http.StatusConflict {
+		t.Fatalf("Expected status 409 for a bid after closing, got %d", w.Code)
+	}
+
+	postJSON(handler, fmt.Sprintf("/tasks/%d/done", created.ID), nil)
+	logs, _ := stor.GetWorkLogsForChore(created.ID)
+	if len(logs) != 1 || logs[0].TimeSpentMin != 50 {
+		t.Fatalf("Expected the winning bid to be credited, got %+v", logs)
+	}
+}
+
+func TestDutiesViaAPI(t *testing.T) {
+	api, _, _, cleanup := setupTestApi(t)
+	defer cleanup()
+
+	handler := api.SetupRoutes()
+
+	var duty DutyData
+	w := postJSON(handler, "/duties", CreateDutyBody{Name: "Night watch", WorkersPerShift: 2})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	json.Unmarshal(w.Body.Bytes(), &duty)
+	if duty.ID == 0 || duty.WorkersPerShift != 2 {
+		t.Fatalf("Unexpected duty: %+v", duty)
+	}
+
+	start := time.Now().Add(time.Hour)
+	w = postJSON(handler, fmt.Sprintf("/duties/%d/shifts", duty.ID), ScheduleShiftsBody{Start: start, End: start.Add(-time.Hour)})
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400 for a shift ending before it starts, got %d", w.Code)
+	}
+	w = postJSON(handler, "/duties/999/shifts", ScheduleShiftsBody{Start: start, End: start.Add(time.Hour)})
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("Expected status 404 for an unknown duty, got %d", w.Code)
+	}
+
+	// Nobody is present in headless mode, so there is nobody to rotate.
+	w = postJSON(handler, fmt.Sprintf("/duties/%d/shifts", duty.ID), ScheduleShiftsBody{Start: start, End: start.Add(4 * time.Hour), Days: 2})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	wGet := httptest.NewRecorder()
+	handler.ServeHTTP(wGet, httptest.NewRequest(http.MethodGet, "/duties", nil))
+	var duties []DutyData
+	json.Unmarshal(wGet.Body.Bytes(), &duties)
+	if len(duties) != 1 || duties[0].Name != "Night watch" {
+		t.Fatalf("Expected the night watch duty, got %+v", duties)
+	}
+
+	wGet = httptest.NewRecorder()
+	handler.ServeHTTP(wGet, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/roster?duty_id=%d", duty.ID), nil))
+	if wGet.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", wGet.Code)
+	}
+}
+
+func TestResourcesViaAPI(t *testing.T) {
+	api, _, _, cleanup := setupTestApi(t)
+	defer cleanup()
+
+	handler := api.SetupRoutes()
+
+	w := postJSON(handler, "/resources", CreateResourceBody{Name: " Van ", Kind: "vehicle"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	w = postJSON(handler, "/tasks", TaskCreateInputBody{Name: "Fly", Resources: []string{"helicopter"}})
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400 for an unknown resource, got %d", w.Code)
+	}
+
+	var shopping, firewood TaskData
+	w = postJSON(handler, "/tasks", TaskCreateInputBody{Name: "Shopping", Resources: []string{"van"}})
+	json.Unmarshal(w.Body.Bytes(), &shopping)
+	w = postJSON(handler, "/tasks", TaskCreateInputBody{Name: "Firewood", Resources: []string{"VAN"}})
+	json.Unmarshal(w.Body.Bytes(), &firewood)
+	if len(firewood.Resources) != 1 || firewood.Resources[0] != "van" {
+		t.Fatalf("Expected the van to be required, got %+v", firewood.Resources)
+	}
+
+	w = postJSON(handler, fmt.Sprintf("/tasks/%d/ack", shopping.ID), TaskUserActionBody{UserId: "u1"})
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("Expected status 204, got %d: %s", w.Code, w.Body.String())
+	}
+	w = postJSON(handler, fmt.Sprintf("/tasks/%d/ack", firewood.ID), TaskUserActionBody{UserId: "u2"})
+	if w.Code != http.StatusConflict {
+		t.Fatalf("Expected status 409 while the van is in use, got %d", w.Code)
+	}
+
+	var resources []ResourceData
+	wGet := httptest.NewRecorder()
+	handler.ServeHTTP(wGet, httptest.NewRequest(http.MethodGet, "/resources", nil))
+	json.Unmarshal(wGet.Body.Bytes(), &resources)
+	if len(resources) != 1 || resources[0].Name != "van" || resources[0].LockedByTaskId != shopping.ID {
+		t.Fatalf("Expected the van locked by the shopping, got %+v", resources)
+	}
+
+	// Completing the holder releases the resource.
+	postJSON(handler, fmt.Sprintf("/tasks/%d/done", shopping.ID), struct{}{})
+	w = postJSON(handler, fmt.Sprintf("/tasks/%d/ack", firewood.ID), TaskUserActionBody{UserId: "u2"})
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("Expected status 204 once the van is free, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestDraftsViaAPI(t *testing.T) {
+	api, stor, u, cleanup := setupTestApi(t)
+	defer cleanup()
+
+	handler := api.SetupRoutes()
+
+	notBefore := time.Now().Add(10 * time.Hour)
+	var breakfast, lunch TaskData
+	w := postJSON(handler, "/tasks", TaskCreateInputBody{Name: "Prepare breakfast", NotBefore: &notBefore})
+	json.Unmarshal(w.Body.Bytes(), &breakfast)
+	w = postJSON(handler, "/tasks", TaskCreateInputBody{Name: "Prepare lunch", NotBefore: &notBefore, Assignees: []string{"u2"}})
+	json.Unmarshal(w.Body.Bytes(), &lunch)
+	if !breakfast.Draft || breakfast.NotBefore == nil {
+		t.Fatalf("Expected a draft, got %+v", breakfast)
+	}
+
+	// The manual assignees of a draft are held until it is published.
+	if !slices.Equal(lunch.Assignees, []string{"u2"}) {
+		t.Fatalf("Expected the held assignee to be listed, got %+v", lunch.Assignees)
+	}
+	if ass, _ := stor.GetChoreAssignments(lunch.ID); len(ass) != 0 {
+		t.Fatalf("Expected no assignments of a draft, got %+v", ass)
+	}
+	w = postJSON(handler, fmt.Sprintf("/tasks/%d/assign", lunch.ID), TaskAssignBody{UserIds: []string{"u3"}})
+	if w.Code != http.StatusConflict {
+		t.Fatalf("Expected status 409 for assigning a draft, got %d", w.Code)
+	}
+
+	w = postJSON(handler, fmt.Sprintf("/tasks/%d/ack", breakfast.ID), TaskUserActionBody{UserId: "u1"})
+	if w.Code != http.StatusConflict {
+		t.Fatalf("Expected status 409 for acking a draft, got %d", w.Code)
+	}
+
+	var drafts []TaskData
+	wGet := httptest.NewRecorder()
+	handler.ServeHTTP(wGet, httptest.NewRequest(http.MethodGet, "/drafts", nil))
+	json.Unmarshal(wGet.Body.Bytes(), &drafts)
+	if len(drafts) != 2 {
+		t.Fatalf("Expected 2 drafts, got %+v", drafts)
+	}
+
+	// Moving the time to the past publishes the draft right away.
+	past := time.Now().Add(-time.Minute)
+	body, _ := json.Marshal(UpdateTaskInputBody{NotBefore: &past})
+	req := httptest.NewRequest(http.MethodPut, fmt.Sprintf("/tasks/%d", breakfast.ID), bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	wPut := httptest.NewRecorder()
+	handler.ServeHTTP(wPut, req)
+	json.Unmarshal(wPut.Body.Bytes(), &breakfast)
+	if wPut.Code != http.StatusOK || breakfast.Draft {
+		t.Fatalf("Expected the draft to be published, got %d: %s", wPut.Code, wPut.Body.String())
+	}
+	wPut = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodPut, fmt.Sprintf("/tasks/%d", breakfast.ID), bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	handler.ServeHTTP(wPut, req)
+	if wPut.Code != http.StatusConflict {
+		t.Fatalf("Expected status 409 for moving a published task, got %d", wPut.Code)
+	}
+
+	// The reminders publish drafts whose time has come.
+	c, _ := stor.GetChore(lunch.ID)
+	c.NotBefore = &past
+	stor.SaveChore(c)
+	u.PublishDueDrafts()
+	c, _ = stor.GetChore(lunch.ID)
+	if c.Status != storage.ChoreOpen {
+		t.Fatalf("Expected the due draft to be published")
+	}
+	a, err := stor.GetChoreAssignment(lunch.ID, "u2")
+	if err != nil || !a.Manual || !a.Active() || c.HeldAssignees != "" {
+		t.Fatalf("Expected the held assignee to be assigned on publishing, got %+v, %v", a, err)
+	}
+}
+
+func TestTaskStatusViaAPI(t *testing.T) {
+	api, _, _, cleanup := setupTestApi(t)
+	defer cleanup()
+
+	handler := api.SetupRoutes()
+
+	var task TaskData
+	w := postJSON(handler, "/tasks", TaskCreateInputBody{Name: "Wash the dishes"})
+	json.Unmarshal(w.Body.Bytes(), &task)
+	if task.Status != "open" {
+		t.Fatalf("Expected an open task, got %s", task.Status)
+	}
+
+	postJSON(handler, fmt.Sprintf("/tasks/%d/ack", task.ID), TaskUserActionBody{UserId: "u1"})
+	w = postJSON(handler, fmt.Sprintf("/tasks/%d/done", task.ID), struct{}{})
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("Expected status 204, got %d: %s", w.Code, w.Body.String())
+	}
+	w = postJSON(handler, fmt.Sprintf("/tasks/%d/done", task.ID), struct{}{})
+	if w.Code != http.StatusConflict {
+		t.Fatalf("Expected status 409 for completing a done task, got %d", w.Code)
+	}
+	w = postJSON(handler, fmt.Sprintf("/tasks/%d/ack", task.ID), TaskUserActionBody{UserId: "u2"})
+	if w.Code != http.StatusConflict {
+		t.Fatalf("Expected status 409 for acking a done task, got %d", w.Code)
+	}
+
+	w = postJSON(handler, fmt.Sprintf("/tasks/%d/reopen", task.ID), struct{}{})
+	json.Unmarshal(w.Body.Bytes(), &task)
+	// u1 still holds the ack, so the reopened task is in progress again.
+	if w.Code != http.StatusOK || task.Status != "in_progress" || task.Completed != nil {
+		t.Fatalf("Expected a task in progress, got %d: %s", w.Code, w.Body.String())
+	}
+	w = postJSON(handler, fmt.Sprintf("/tasks/%d/reopen", task.ID), struct{}{})
+	if w.Code != http.StatusConflict {
+		t.Fatalf("Expected status 409 for reopening an unfinished task, got %d", w.Code)
+	}
+
+	// Nobody acked the cancelled task, reopened and assigned again it is open like any other.
+	cancelled := createTask(t, handler, TaskCreateInputBody{Name: "Dry the dishes"})
+	requestWithKey(handler, http.MethodDelete, fmt.Sprintf("/tasks/%d", cancelled.ID), "", nil)
+	w = postJSON(handler, fmt.Sprintf("/tasks/%d/reopen", cancelled.ID), struct{}{})
+	json.Unmarshal(w.Body.Bytes(), &cancelled)
+	if w.Code != http.StatusOK || cancelled.Status != "open" || cancelled.Cancelled != nil {
+		t.Fatalf("Expected an open task, got %d: %s", w.Code, w.Body.String())
+	}
+	if tasks, _ := getTasks(t, handler, "status=open"); !slices.ContainsFunc(tasks, func(d TaskData) bool { return d.ID == cancelled.ID }) {
+		t.Fatalf("Expected the reopened task among the open ones, got %s", taskNames(tasks))
+	}
+}
+
+func TestWaitlistViaAPI(t *testing.T) {
+	api, stor, _, cleanup := setupTestApi(t)
+	defer cleanup()
+
+	handler := api.SetupRoutes()
+	task := createTask(t, handler, TaskCreateInputBody{Name: "Paddle the canoe", NecessaryWorkers: 2})
+
+	for _, u := range []string{"u1", "u2"} {
+		w := postJSON(handler, fmt.Sprintf("/tasks/%d/ack", task.ID), TaskUserActionBody{UserId: u})
+		if w.Code != http.StatusNoContent {
+			t.Fatalf("Expected status 204, got %d: %s", w.Code, w.Body.String())
+		}
+	}
+	for i, u := range []string{"u3", "u4", "u3"} {
+		w := postJSON(handler, fmt.Sprintf("/tasks/%d/ack", task.ID), TaskUserActionBody{UserId: u})
+		var wd WaitlistData
+		json.Unmarshal(w.Body.Bytes(), &wd)
+		// Acking again keeps the place in line.
+		if w.Code != http.StatusAccepted || wd.Position != []int{1, 2, 1}[i] {
+			t.Fatalf("Expected %s waitlisted, got %d: %s", u, w.Code, w.Body.String())
+		}
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/tasks/%d", task.ID), nil))
+	json.Unmarshal(w.Body.Bytes(), &task)
+	if len(task.Waitlist) != 2 || task.Waitlist[0] != "u3" || task.Waitlist[1] != "u4" {
+		t.Fatalf("Expected u3 and u4 waiting, got %v", task.Waitlist)
+	}
+
+	// A worker dropping out makes room for the first in line.
+	postJSON(handler, fmt.Sprintf("/tasks/%d/reject", task.ID), TaskUserActionBody{UserId: "u1"})
+	ass, err := stor.GetChoreAssignment(task.ID, "u3")
+	if err != nil || ass.Acked == nil || !ass.Active() {
+		t.Fatalf("Expected u3 promoted, got %v, %+v", err, ass)
+	}
+	if acked := ackedCount(t, stor, task.ID); acked != 2 {
+		t.Fatalf("Expected 2 acked workers, got %d", acked)
+	}
+
+	// Rejecting from the waitlist only leaves it.
+	postJSON(handler, fmt.Sprintf("/tasks/%d/reject", task.ID), TaskUserActionBody{UserId: "u4"})
+	waitlist, err := stor.GetWaitlist(task.ID)
+	if err != nil || len(waitlist) != 0 {
+		t.Fatalf("Expected an empty waitlist, got %v, %+v", err, waitlist)
+	}
+	if _, err := stor.GetChoreAssignment(task.ID, "u4"); err == nil {
+		t.Fatalf("Expected no assignment for u4")
+	}
+}
+
+func getTasks(t *testing.T, handler http.Handler, query string) ([]TaskData, string) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/tasks?"+query, nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 for %s, got %d: %s", query, w.Code, w.Body.String())
+	}
+	var tasks []TaskData
+	json.Unmarshal(w.Body.Bytes(), &tasks)
+	return tasks, w.Header().Get("Next-Cursor")
+}
+
+func taskNames(tasks []TaskData) string {
+	names := []string{}
+	for _, t := range tasks {
+		names = append(names, t.Name)
+	}
+	return strings.Join(names, ",")
+}
+
+func TestTaskFiltersViaAPI(t *testing.T) {
+	api, stor, _, cleanup := setupTestApi(t)
+	defer cleanup()
+
+	handler := api.SetupRoutes()
+	now := time.Now()
+	soon, later := now.Add(time.Hour), now.Add(3*time.Hour)
+	a := createTask(t, handler, TaskCreateInputBody{Name: "a", Deadline: &later, NecessaryCapabilities: []string{"driver", "cook"}})
+	createTask(t, handler, TaskCreateInputBody{Name: "b"})
+	c := createTask(t, handler, TaskCreateInputBody{Name: "c", Deadline: &soon, NecessaryCapabilities: []string{"cook"}})
+	stor.SaveChore(storage.Chore{Name: "d", CreatorId: "boss", Status: storage.ChoreOpen, Created: time.Now()})
+
+	postJSON(handler, fmt.Sprintf("/tasks/%d/ack", a.ID), TaskUserActionBody{UserId: "u1"})
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/tasks/%d", c.ID), nil))
+
+	for query, want := range map[string]string{
+		"":                        "a,b,c,d",
+		"status=open":             "b,d",
+		"status=in_progress,open": "a,b,d",
+		"assignee=u1":             "a",
+		"creator=boss":            "d",
+		"capability=cook":         "a,c",
+		"capability=driver":       "a",
+		"sort=deadline":           "c,a,b,d",
+		"sort=name&order=desc":    "d,c,b,a",
+		"deadline_to=" + now.Add(2*time.Hour).Format(time.RFC3339): "c",
+	} {
+		tasks, _ := getTasks(t, handler, query)
+		if got := taskNames(tasks); got != want {
+			t.Errorf("Expected %s for %q, got %s", want, query, got)
+		}
+	}
+
+	// Paging by deadline walks through all tasks once.
+	names := []string{}
+	cursor := ""
+	for range 3 {
+		tasks, next := getTasks(t, handler, "sort=deadline&limit=3&cursor="+cursor)
+		names = append(names, taskNames(tasks))
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+	if got := strings.Join(names, "|"); got != "c,a,b|d" {
+		t.Fatalf("Expected pages c,a,b|d, got %s", got)
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/tasks?cursor=nope", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400 for a bad cursor, got %d", w.Code)
+	}
+
+	// Without a limit the list is not cut to a page.
+	for i := range 150 {
+		stor.SaveChore(storage.Chore{Name: fmt.Sprintf("bulk %d", i), Status: storage.ChoreOpen, Created: time.Now()})
+	}
+	tasks, next := getTasks(t, handler, "")
+	if len(tasks) != 154 || next != "" {
+		t.Fatalf("Expected all 154 tasks on one page, got %d with cursor %q", len(tasks), next)
+	}
+}
+
+func requestWithKey(handler http.Handler, method string, path string, key string, payload any) *httptest.ResponseRecorder {
+	var body io.Reader
+	if payload != nil {
+		b, _ := json.Marshal(payload)
+		body = bytes.NewReader(b)
+	}
+	req := httptest.NewRequest(method, path, body)
+	req.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		req.Header.Set("Authorization", "Bearer "+key)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	return w
+}
+
+func TestApiKeyScopes(t *testing.T) {
+	api, stor, _, cleanup := setupTestApi(t)
+	defer cleanup()
+
+	handler := api.SetupRoutes()
+	task := createTask(t, handler, TaskCreateInputBody{Name: "Sweep the porch"})
+
+	newKey := func(scopes string, userId string) string {
+		t.Helper()
+		k := storage.ApiKey{Name: scopes, UserId: userId}
+		k.SetScopes(strings.Split(scopes, ","))
+		_, secret, err := stor.CreateApiKey(k)
+		if err != nil {
+			t.Fatalf("Failed to create key: %v", err)
+		}
+		return secret
+	}
+	admin := newKey("admin", "")
+	reader := newKey("read", "")
+	self := newKey("ack-as-self,read", "u1")
+	writer := newKey("write", "")
+	boundAdmin := newKey("admin", "u3")
+	boundWriter := newKey("write", "u5") // The permissions of a Discord login.
+	noAway := UserPatchBody{Unavailable: &[]AvailabilityBlock{}}
+
+	for _, c := range []struct {
+		method, path, key string
+		body              any
+		code              int
+	}{
+		{http.MethodGet, "/tasks", "", nil, http.StatusUnauthorized},
+		{http.MethodGet, "/tasks", "gtc_nope", nil, http.StatusUnauthorized},
+		{http.MethodGet, "/health", "", nil, http.StatusOK},
+		{http.MethodGet, "/tasks", reader, nil, http.StatusOK},
+		{http.MethodPost, "/tasks", reader, TaskCreateInputBody{Name: "Nope"}, http.StatusForbidden},
+		{http.MethodGet, "/apikeys", reader, nil, http.StatusForbidden},
+		{http.MethodDelete, fmt.Sprintf("/tasks/%d", task.ID), self, nil, http.StatusForbidden},
+		{http.MethodPost, fmt.Sprintf("/tasks/%d/ack", task.ID), self, TaskUserActionBody{UserId: "u2"}, http.StatusForbidden},
+		// The bound user is filled in.
+		{http.MethodPost, fmt.Sprintf("/tasks/%d/ack", task.ID), self, TaskUserActionBody{}, http.StatusNoContent},
+		// A write key manages tasks but cannot act as the users.
+		{http.MethodPut, fmt.Sprintf("/tasks/%d", task.ID), writer, UpdateTaskInputBody{NecessaryWorkers: 3}, http.StatusOK},
+		{http.MethodPost, fmt.Sprintf("/tasks/%d/ack", task.ID), writer, TaskUserActionBody{UserId: "u2"}, http.StatusForbidden},
+		{http.MethodPost, fmt.Sprintf("/tasks/%d/reject", task.ID), writer, TaskUserActionBody{UserId: "u1"}, http.StatusForbidden},
+		{http.MethodPost, fmt.Sprintf("/tasks/%d/ack", task.ID), admin, TaskUserActionBody{UserId: "u2"}, http.StatusNoContent},
+		{http.MethodPost, fmt.Sprintf("/tasks/%d/ack", task.ID), boundAdmin, TaskUserActionBody{UserId: "u4"}, http.StatusNoContent},
+		{http.MethodGet, "/apikeys", admin, nil, http.StatusOK},
+		// Only admins change or look into the settings and offers of other users.
+		{http.MethodPatch, "/users/u2", writer, noAway, http.StatusForbidden},
+		{http.MethodPatch, "/users/u2", boundWriter, noAway, http.StatusForbidden},
+		{http.MethodPatch, "/users/u5", boundWriter, noAway, http.StatusOK},
+		{http.MethodPatch, "/users/u2", admin, noAway, http.StatusOK},
+		{http.MethodGet, "/transfers?user_id=u2", boundWriter, nil, http.StatusForbidden},
+		{http.MethodGet, "/transfers?user_id=u2", reader, nil, http.StatusForbidden},
+		{http.MethodGet, "/transfers", boundWriter, nil, http.StatusOK},
+		{http.MethodGet, "/transfers?user_id=u2", admin, nil, http.StatusOK},
+	} {
+		if w := requestWithKey(handler, c.method, c.path, c.key, c.body); w.Code != c.code {
+			t.Errorf("Expected %d for %s %s, got %d: %s", c.code, c.method, c.path, w.Code, w.Body.String())
+		}
+	}
+	if _, err := stor.GetChoreAssignment(task.ID, "u1"); err != nil {
+		t.Fatalf("Expected u1 to ack with their key: %v", err)
+	}
+
+	w := requestWithKey(handler, http.MethodPost, "/apikeys", admin, ApiKeyCreateBody{Name: "dashboard", Scopes: []string{"read"}})
+	var created ApiKeyCreatedData
+	json.Unmarshal(w.Body.Bytes(), &created)
+	if w.Code != http.StatusOK || !strings.HasPrefix(created.Secret, created.Hint) {
+		t.Fatalf("Expected a new key, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := requestWithKey(handler, http.MethodPost, "/apikeys", admin, ApiKeyCreateBody{Name: "bad", Scopes: []string{"ack-as-self"}}); w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected 400 for ack-as-self without a user, got %d", w.Code)
+	}
+	if w := requestWithKey(handler, http.MethodGet, "/tasks", created.Secret, nil); w.Code != http.StatusOK {
+		t.Fatalf("Expected the new key to work, got %d", w.Code)
+	}
+	requestWithKey(handler, http.MethodDelete, fmt.Sprintf("/apikeys/%d", created.ID), admin, nil)
+	if w := requestWithKey(handler, http.MethodGet, "/tasks", created.Secret, nil); w.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected a revoked key to be refused, got %d", w.Code)
+	}
+
+	keys, _ := stor.GetApiKeys()
+	for _, k := range keys {
+		if k.Name == "read" && k.LastUsed == nil {
+			t.Fatalf("Expected the last use of the key to be recorded")
+		}
+		if strings.Contains(k.SecretHash, "gtc_") {
+			t.Fatalf("Expected only the hash of the secret to be stored")
+		}
+	}
+}
+
+func TestWebhooksViaAPI(t *testing.T) {
+	api, stor, _, cleanup := setupTestApi(t)
+	defer cleanup()
+
+	handler := api.SetupRoutes()
+	if w := postJSON(handler, "/webhooks", WebhookCreateBody{Url: "not a url"}); w.Code != http.StatusBadRequest && w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("Expected an invalid URL to be refused, got %d", w.Code)
+	}
+	if w := postJSON(handler, "/webhooks", WebhookCreateBody{Url: "http://example.invalid/hook", EventTypes: []string{"task_finished"}}); w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected an unknown event type to be refused, got %d", w.Code)
+	}
+	var hook WebhookData
+	w := postJSON(handler, "/webhooks", WebhookCreateBody{Url: "http://example.invalid/hook", EventTypes: []string{"task_done"}})
+	json.Unmarshal(w.Body.Bytes(), &hook)
+	if w.Code != http.StatusOK || hook.Secret == "" || len(hook.EventTypes) != 1 {
+		t.Fatalf("Expected a webhook with a secret, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/webhooks", nil))
+	if strings.Contains(w.Body.String(), hook.Secret) {
+		t.Fatalf("Expected the secret to be shown only on creation")
+	}
+
+	var delivery DeliveryData
+	w = postJSON(handler, fmt.Sprintf("/webhooks/%d/test", hook.ID), struct{}{})
+	json.Unmarshal(w.Body.Bytes(), &delivery)
+	if w.Code != http.StatusOK || delivery.EventType != "webhook_test" || delivery.Status != "pending" {
+		t.Fatalf("Expected a queued test delivery, got %d: %s", w.Code, w.Body.String())
+	}
+	due, _ := stor.GetDueDeliveries(time.Now())
+	if len(due) != 1 || due[0].WebhookId != hook.ID {
+		t.Fatalf("Expected the test delivery to be due, got %+v", due)
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/webhooks/%d/deliveries?status=pending", hook.ID), nil))
+	var log []DeliveryData
+	json.Unmarshal(w.Body.Bytes(), &log)
+	if len(log) != 1 || log[0].ID != delivery.ID {
+		t.Fatalf("Expected the test delivery in the log, got %s", w.Body.String())
+	}
+}
+
+func postWithIdempotencyKey(handler http.Handler, key string, payload any) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(payload)
+	req := httptest.NewRequest(http.MethodPost, "/tasks", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Idempotency-Key", key)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	return w
+}
+
+func TestIdempotentTaskCreation(t *testing.T) {
+	api, stor, _, cleanup := setupTestApi(t)
+	defer cleanup()
+
+	handler := api.SetupRoutes()
+	body := TaskCreateInputBody{Name: "Buy bread", EstimatedTimeMin: 20}
+
+	var first, replay TaskData
+	w := postWithIdempotencyKey(handler, "bread-monday", body)
+	json.Unmarshal(w.Body.Bytes(), &first)
+	if w.Code != http.StatusOK || w.Header().Get("Idempotent-Replayed") != "" {
+		t.Fatalf("Expected the task to be created, got %d: %s", w.Code, w.Body.String())
+	}
+	w = postWithIdempotencyKey(handler, "bread-monday", body)
+	json.Unmarshal(w.Body.Bytes(), &replay)
+	if w.Code != http.StatusOK || w.Header().Get("Idempotent-Replayed") != "true" || replay.ID != first.ID {
+		t.Fatalf("Expected the replay to return task %d, got %d: %s", first.ID, w.Code, w.Body.String())
+	}
+
+	body.EstimatedTimeMin = 30
+	if w := postWithIdempotencyKey(handler, "bread-monday", body); w.Code != http.StatusConflict {
+		t.Fatalf("Expected a conflict for a different body, got %d", w.Code)
+	}
+	if w := postWithIdempotencyKey(handler, "bread-tuesday", body); w.Code != http.StatusOK {
+		t.Fatalf("Expected a new key to create a task, got %d", w.Code)
+	}
+	// A failed request does not burn its key.
+	if w := postWithIdempotencyKey(handler, "bread-wednesday", TaskCreateInputBody{Name: "Bread", Resources: []string{"missing"}}); w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected an unknown resource to be refused, got %d", w.Code)
+	}
+	if w := postWithIdempotencyKey(handler, "bread-wednesday", TaskCreateInputBody{Name: "Bread"}); w.Code != http.StatusOK {
+		t.Fatalf("Expected the key of a failed request to be free, got %d", w.Code)
+	}
+
+	all, _ := stor.GetChores()
+	if len(all) != 3 {
+		t.Fatalf("Expected 3 tasks, got %d", len(all))
+	}
 }
 
 func TestTaskLifecycleViaAPI(t *testing.T) {
@@ -308,6 +1147,15 @@ func TestRejectTaskViaAPI(t *testing.T) {
 	if err != nil || ass.Refused == nil {
 		t.Fatalf("Expected refused assignment in DB: %v, %+v", err, ass)
 	}
+
+	// The refusal raises the bounty.
+	wGet := httptest.NewRecorder()
+	handler.ServeHTTP(wGet, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/tasks/%d", created.ID), nil))
+	var fetched TaskData
+	json.Unmarshal(wGet.Body.Bytes(), &fetched)
+	if fetched.Bounty != 1.5 {
+		t.Fatalf("Expected bounty 1.5 after a refusal, got %v", fetched.Bounty)
+	}
 }
 
 func TestMetadataAndStatsEndpoints(t *testing.T) {
@@ -401,4 +1249,281 @@ func TestCORSHeaders(t *testing.T) {
 	if wOpt.Header().Get("Access-Control-Allow-Origin") != "*" {
 		t.Fatalf("Expected Allow-Origin *, got %s", wOpt.Header().Get("Access-Control-Allow-Origin"))
 	}
+	if exposed := wOpt.Header().Get("Access-Control-Expose-Headers"); !strings.Contains(exposed, "Next-Cursor") {
+		t.Fatalf("Expected Next-Cursor to be exposed, got %s", exposed)
+	}
+	if allowed := wOpt.Header().Get("Access-Control-Allow-Headers"); !strings.Contains(allowed, "Idempotency-Key") {
+		t.Fatalf("Expected Idempotency-Key to be allowed, got %s", allowed)
+	}
+	if exposed := wOpt.Header().Get("Access-Control-Expose-Headers"); !strings.Contains(exposed, "Idempotent-Replayed") {
+		t.Fatalf("Expected Idempotent-Replayed to be exposed, got %s", exposed)
+	}
+}
+
+func updateTask(handler http.Handler, method string, id uint, ifMatch string, payload any) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(payload)
+	req := httptest.NewRequest(method, fmt.Sprintf("/tasks/%d", id), bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if ifMatch != "" {
+		req.Header.Set("If-Match", ifMatch)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	return w
+}
+
+func TestTaskETagsViaAPI(t *testing.T) {
+	api, _, _, cleanup := setupTestApi(t)
+	defer cleanup()
+	handler := api.SetupRoutes()
+
+	task := createTask(t, handler, TaskCreateInputBody{Name: "Wash the van", EstimatedTimeMin: 40, AssignmentTimeoutMin: 30})
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/tasks/%d", task.ID), nil))
+	etag := w.Header().Get("ETag")
+	if etag == "" || etag != fmt.Sprintf(`"%d"`, task.Version) {
+		t.Fatalf("Expected the ETag of version %d, got %q", task.Version, etag)
+	}
+
+	// PUT keeps the omitted fields, the assignment timeout included.
+	w = updateTask(handler, http.MethodPut, task.ID, etag, UpdateTaskInputBody{Name: "Wash the big van"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var updated TaskData
+	json.Unmarshal(w.Body.Bytes(), &updated)
+	if updated.Name != "Wash the big van" || updated.AssignmentTimeoutMin != 30 || updated.EstimatedTimeMin != 40 {
+		t.Fatalf("Expected only the name changed, got %+v", updated)
+	}
+	if updated.Version <= task.Version || w.Header().Get("ETag") == etag {
+		t.Fatalf("Expected a new version, got %d and ETag %s", updated.Version, w.Header().Get("ETag"))
+	}
+
+	// The second writer still has the old ETag.
+	w = updateTask(handler, http.MethodPatch, task.ID, etag, map[string]any{"estimated_time_min": 60})
+	if w.Code != http.StatusPreconditionFailed {
+		t.Fatalf("Expected status 412 for a stale ETag, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// PATCH sets zero values and leaves the rest alone.
+	w = updateTask(handler, http.MethodPatch, task.ID, fmt.Sprintf(`"%d"`, updated.Version), map[string]any{"assignment_timeout_min": 0})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	json.Unmarshal(w.Body.Bytes(), &updated)
+	if updated.AssignmentTimeoutMin != 0 || updated.Name != "Wash the big van" || updated.EstimatedTimeMin != 40 {
+		t.Fatalf("Expected only the timeout cleared, got %+v", updated)
+	}
+
+	// All the fields of one PATCH are applied at once, as one new version.
+	before := updated.Version
+	w = updateTask(handler, http.MethodPatch, task.ID, fmt.Sprintf(`"%d"`, before), map[string]any{"categories": []string{"cars"}, "team_mode": true, "estimated_time_min": 50})
+	json.Unmarshal(w.Body.Bytes(), &updated)
+	if w.Code != http.StatusOK || updated.Version != before+1 || !updated.TeamMode || updated.EstimatedTimeMin != 50 || len(updated.Categories) != 1 {
+		t.Fatalf("Expected a single new version with all the fields, got %d: %s", w.Code, w.Body.String())
+	}
+	// A draft-only field fails the whole PATCH.
+	w = updateTask(handler, http.MethodPatch, task.ID, "", map[string]any{"name": "Nope", "not_before": time.Now().Add(time.Hour)})
+	if w.Code != http.StatusConflict {
+		t.Fatalf("Expected status 409 for moving a published task, got %d: %s", w.Code, w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/tasks/%d", task.ID), nil))
+	var got TaskData
+	json.Unmarshal(w.Body.Bytes(), &got)
+	if got.Name != "Wash the big van" || got.Version != updated.Version {
+		t.Fatalf("Expected the failed PATCH to change nothing, got %+v", got)
+	}
+
+	// Without If-Match the last writer wins, as before.
+	w = updateTask(handler, http.MethodPatch, task.ID, "", map[string]any{"necessary_workers": 2})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	w = updateTask(handler, http.MethodPatch, task.ID, "*", map[string]any{"necessary_workers": 0})
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("Expected status 422 for no workers, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+// readSSE parses the events of the stream until it ends, the ID of every event is checked against its seq.
+func readSSE(t *testing.T, body io.Reader) chan storage.Event {
+	events := make(chan storage.Event, 100)
+	go func() {
+		defer close(events)
+		scanner := bufio.NewScanner(body)
+		id := ""
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case strings.HasPrefix(line, "id: "):
+				id = strings.TrimPrefix(line, "id: ")
+			case strings.HasPrefix(line, "data: "):
+				var e storage.Event
+				if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &e); err != nil || fmt.Sprint(e.Seq) != id {
+					t.Errorf("Unexpected event %q with ID %s: %v", line, id, err)
+				}
+				events <- e
+			}
+		}
+	}()
+	return events
+}
+
+// nextCreated waits for the task_created event of the named task, the events before it are returned too.
+func nextCreated(t *testing.T, events chan storage.Event, name string) []storage.Event {
+	t.Helper()
+	seen := []storage.Event{}
+	for {
+		select {
+		case e, ok := <-events:
+			if !ok {
+				t.Fatalf("The stream ended before %s was created", name)
+			}
+			seen = append(seen, e)
+			if e.Type == storage.TaskCreated && e.Chore != nil && e.Chore.Name == name {
+				return seen
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("No task_created event for %s", name)
+		}
+	}
+}
+
+func TestEventStream(t *testing.T) {
+	api, stor, _, cleanup := setupTestApi(t)
+	defer cleanup()
+
+	handler := api.SetupRoutes()
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	createTask(t, handler, TaskCreateInputBody{Name: "Seen task"})
+	last := stor.Events.Seq()
+	createTask(t, handler, TaskCreateInputBody{Name: "Missed task"})
+
+	// The client was disconnected after the first task, it resumes with the missed events.
+	req, _ := http.NewRequest(http.MethodGet, ts.URL+"/events", nil)
+	req.Header.Set("Last-Event-ID", fmt.Sprint(last))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to open the stream: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("Expected an event stream, got %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	events := readSSE(t, resp.Body)
+
+	replayed := nextCreated(t, events, "Missed task")
+	if replayed[0].Seq != last+1 {
+		t.Fatalf("Expected the replay to start after %d, got %d", last, replayed[0].Seq)
+	}
+
+	// Live events follow the replay.
+	createTask(t, handler, TaskCreateInputBody{Name: "Live task"})
+	live := nextCreated(t, events, "Live task")
+	prev := replayed[len(replayed)-1].Seq
+	for _, e := range live {
+		if e.Seq != prev+1 {
+			t.Fatalf("Expected event %d after %d", e.Seq, prev)
+		}
+		prev = e.Seq
+	}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/events", nil)
+	r.Header.Set("Last-Event-ID", "latest")
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400 for a bad Last-Event-ID, got %d", w.Code)
+	}
+}
+
+func TestWebSocketReplay(t *testing.T) {
+	api, _, _, cleanup := setupTestApi(t)
+	defer cleanup()
+
+	handler := api.SetupRoutes()
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	task := createTask(t, handler, TaskCreateInputBody{Name: "Before connecting"})
+
+	wsURL := "ws" + strings.TrimPrefix(ts.URL, "http") + "/ws?last_event_id=0"
+	wsConn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	if err != nil {
+		t.Fatalf("WebSocket connection failed: %v", err)
+	}
+	defer wsConn.Close()
+
+	wsConn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var event storage.Event
+	if err := wsConn.ReadJSON(&event); err != nil {
+		t.Fatalf("Failed to read the replayed event: %v", err)
+	}
+	if event.Seq != 1 || event.Type != storage.TaskCreated || event.Chore == nil || event.Chore.ID != task.ID {
+		t.Fatalf("Expected the creation of task %d replayed first, got %+v", task.ID, event)
+	}
+
+	if _, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/ws?last_event_id=first", nil); err == nil {
+		t.Fatalf("Expected a bad last event ID to be refused")
+	}
+}
+
+func TestWebSocketAuth(t *testing.T) {
+	api, stor, _, cleanup := setupTestApi(t)
+	defer cleanup()
+
+	newKey := func(scopes string, userId string) string {
+		t.Helper()
+		k := storage.ApiKey{Name: scopes, UserId: userId}
+		k.SetScopes(strings.Split(scopes, ","))
+		_, secret, err := stor.CreateApiKey(k)
+		if err != nil {
+			t.Fatalf("Failed to create key: %v", err)
+		}
+		return secret
+	}
+	reader := newKey("read", "")
+	acker := newKey("ack-as-self", "u1")
+
+	handler := api.SetupRoutes()
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	dial := func(key string) *websocket.Conn {
+		t.Helper()
+		header := http.Header{"Authorization": []string{"Bearer " + reader}}
+		wsConn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/ws?last_event_id=0", header)
+		if err != nil {
+			t.Fatalf("WebSocket connection failed: %v", err)
+		}
+		if err := wsConn.WriteJSON(map[string]string{"api_key": key}); err != nil {
+			t.Fatalf("Failed to send the key: %v", err)
+		}
+		wsConn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		return wsConn
+	}
+
+	if w := requestWithKey(handler, http.MethodPost, "/tasks", newKey("write", ""), TaskCreateInputBody{Name: "Watched task"}); w.Code != http.StatusOK {
+		t.Fatalf("Failed to create task: %d %s", w.Code, w.Body.String())
+	}
+
+	// A database key with the read scope gets the events.
+	wsConn := dial(reader)
+	defer wsConn.Close()
+	var event storage.Event
+	if err := wsConn.ReadJSON(&event); err != nil || event.Type != storage.TaskCreated {
+		t.Fatalf("Expected the task creation event, got %+v, %v", event, err)
+	}
+
+	// Keys without the read scope are refused.
+	wsConn = dial(acker)
+	defer wsConn.Close()
+	_, _, err := wsConn.ReadMessage()
+	if !websocket.IsCloseError(err, websocket.ClosePolicyViolation) {
+		t.Fatalf("Expected the connection closed as unauthorized, got %v", err)
+	}
 }
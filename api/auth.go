@@ -0,0 +1,125 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/gdg-garage/garage-trip-chores/storage"
+	"gorm.io/gorm"
+)
+
+type apiKeyCtxKey struct{}
+
+// configKey stands for the keys from the configuration, they can do everything.
+var configKey = storage.ApiKey{Name: "config", Scopes: storage.ScopeAdmin}
+
+// publicPaths are served without a key.
+var publicPaths = []string{"/openapi.json", "/openapi.yaml", "/docs", "/ws/docs", "/ws/asyncapi.yaml", "/health", "/auth/login", "/auth/callback", "/auth/logout"}
+
+// authRequired tells whether any key exists or the login is enabled, otherwise the API is open.
+func (a *Api) authRequired() bool {
+	if len(a.authorizedKeys) > 0 || a.conf.OAuth.enabled() {
+		return true
+	}
+	has, err := a.storage.HasApiKeys()
+	if err != nil {
+		a.logger.Error("failed to check API keys", "error", err)
+		return true
+	}
+	return has
+}
+
+// requiredScope maps the request to the scope the key needs for it.
+func requiredScope(r *http.Request) string {
+	path := r.URL.Path
+	switch {
+	case strings.HasPrefix(path, "/apikeys"), strings.HasPrefix(path, "/webhooks"):
+		return storage.ScopeAdmin
+	case strings.HasPrefix(path, "/auth/"):
+		return storage.ScopeRead
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		return storage.ScopeRead
+	case r.Method == http.MethodPost && strings.HasPrefix(path, "/tasks/") && (strings.HasSuffix(path, "/ack") || strings.HasSuffix(path, "/reject")):
+		return storage.ScopeAckAsSelf
+	}
+	return storage.ScopeWrite
+}
+
+func (a *Api) authMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, p := range publicPaths {
+			if r.URL.Path == p {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+		if !a.authRequired() {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		authHeader := r.Header.Get("Authorization")
+		token := ""
+		if len(authHeader) > 7 && authHeader[:7] == "Bearer " {
+			token = authHeader[7:]
+		} else if c, err := r.Cookie(sessionCookie); err == nil {
+			token = c.Value
+		}
+		if token == "" {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		key, err := a.authenticate(token)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if err != nil {
+			a.logger.Error("failed to authenticate", "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		if scope := requiredScope(r); !key.Allows(scope) {
+			http.Error(w, "Forbidden, the key lacks the "+scope+" scope", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), apiKeyCtxKey{}, key)))
+	})
+}
+
+// authenticate finds the permissions of a configured key, an API key or a login session.
+func (a *Api) authenticate(token string) (storage.ApiKey, error) {
+	if _, ok := a.authorizedKeys[token]; ok {
+		return configKey, nil
+	}
+	if strings.HasPrefix(token, storage.SessionPrefix) {
+		session, err := a.storage.GetSession(token)
+		if err != nil {
+			return storage.ApiKey{}, err
+		}
+		return sessionKey(session), nil
+	}
+	return a.storage.AuthenticateApiKey(token)
+}
+
+// actingUser returns the user a request acts as. A key bound to a user acts as that user, which can then be left
+// out of the request. Acting as anybody else needs the admin scope.
+func actingUser(ctx context.Context, userId string) (string, error) {
+	key, ok := ctx.Value(apiKeyCtxKey{}).(storage.ApiKey)
+	if !ok {
+		return userId, nil
+	}
+	if key.UserId != "" && (userId == "" || userId == key.UserId) {
+		return key.UserId, nil
+	}
+	if !key.Allows(storage.ScopeAdmin) {
+		if key.UserId != "" {
+			return userId, huma.Error403Forbidden("the API key can act only as user " + key.UserId)
+		}
+		return userId, huma.Error403Forbidden("acting as another user needs the admin scope")
+	}
+	return userId, nil
+}
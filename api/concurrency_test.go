@@ -0,0 +1,280 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gdg-garage/garage-trip-chores/chores"
+	"github.com/gdg-garage/garage-trip-chores/reminders"
+	"github.com/gdg-garage/garage-trip-chores/storage"
+)
+
+// Every test here fires the same requests from many goroutines and checks the invariants afterwards.
+
+func createTask(t *testing.T, handler http.Handler, body TaskCreateInputBody) TaskData {
+	t.Helper()
+	var task TaskData
+	w := postJSON(handler, "/tasks", body)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	json.Unmarshal(w.Body.Bytes(), &task)
+	return task
+}
+
+// parallel runs fn n times at once and counts the response codes.
+func parallel(n int, fn func(i int) int) map[int]int {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	codes := map[int]int{}
+	start := make(chan struct{})
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			code := fn(i)
+			mu.Lock()
+			codes[code]++
+			mu.Unlock()
+		}()
+	}
+	close(start)
+	wg.Wait()
+	return codes
+}
+
+func ackedCount(t *testing.T, stor *storage.Storage, choreId uint) int {
+	t.Helper()
+	all, err := stor.GetChoreAssignments(choreId)
+	if err != nil {
+		t.Fatalf("Failed to get assignments: %v", err)
+	}
+	seen := map[string]bool{}
+	acked := 0
+	for _, a := range all {
+		if seen[a.UserId] {
+			t.Fatalf("User %s is assigned twice", a.UserId)
+		}
+		seen[a.UserId] = true
+		if a.Active() && a.Acked != nil {
+			acked++
+		}
+	}
+	return acked
+}
+
+func TestConcurrentAcks(t *testing.T) {
+	api, stor, _, cleanup := setupTestApi(t)
+	defer cleanup()
+	handler := api.SetupRoutes()
+
+	task := createTask(t, handler, TaskCreateInputBody{Name: "Carry the boat", NecessaryWorkers: 2})
+
+	codes := parallel(20, func(i int) int {
+		return postJSON(handler, fmt.Sprintf("/tasks/%d/ack", task.ID), TaskUserActionBody{UserId: fmt.Sprintf("u%d", i)}).Code
+	})
+	if codes[http.StatusNoContent] != 2 || codes[http.StatusAccepted] != 18 {
+		t.Fatalf("Expected 2 acks and 18 waitlisted, got %v", codes)
+	}
+	if acked := ackedCount(t, stor, task.ID); acked != 2 {
+		t.Fatalf("Expected 2 acked workers, got %d", acked)
+	}
+	waitlist, err := stor.GetWaitlist(task.ID)
+	if err != nil {
+		t.Fatalf("Failed to get waitlist: %v", err)
+	}
+	if len(waitlist) != 18 {
+		t.Fatalf("Expected 18 users on the waitlist, got %d", len(waitlist))
+	}
+
+	// The same user acking many times holds a single assignment.
+	other := createTask(t, handler, TaskCreateInputBody{Name: "Fetch water", NecessaryWorkers: 3})
+	codes = parallel(10, func(i int) int {
+		return postJSON(handler, fmt.Sprintf("/tasks/%d/ack", other.ID), TaskUserActionBody{UserId: "u1"}).Code
+	})
+	if codes[http.StatusNoContent] != 10 {
+		t.Fatalf("Expected all repeated acks to pass, got %v", codes)
+	}
+	if acked := ackedCount(t, stor, other.ID); acked != 1 {
+		t.Fatalf("Expected 1 acked worker, got %d", acked)
+	}
+}
+
+func TestConcurrentResourceAcks(t *testing.T) {
+	api, stor, _, cleanup := setupTestApi(t)
+	defer cleanup()
+	handler := api.SetupRoutes()
+
+	postJSON(handler, "/resources", CreateResourceBody{Name: "van"})
+	tasks := []TaskData{}
+	for i := range 10 {
+		tasks = append(tasks, createTask(t, handler, TaskCreateInputBody{Name: fmt.Sprintf("Drive %d", i), Resources: []string{"van"}}))
+	}
+
+	codes := parallel(10, func(i int) int {
+		return postJSON(handler, fmt.Sprintf("/tasks/%d/ack", tasks[i].ID), TaskUserActionBody{UserId: fmt.Sprintf("driver%d", i)}).Code
+	})
+	if codes[http.StatusNoContent] != 1 || codes[http.StatusConflict] != 9 {
+		t.Fatalf("Expected a single ack to lock the van, got %v", codes)
+	}
+	acked := 0
+	for _, task := range tasks {
+		acked += ackedCount(t, stor, task.ID)
+	}
+	if acked != 1 {
+		t.Fatalf("Expected 1 acked driver, got %d", acked)
+	}
+}
+
+func TestConcurrentCompletion(t *testing.T) {
+	api, stor, _, cleanup := setupTestApi(t)
+	defer cleanup()
+	handler := api.SetupRoutes()
+
+	task := createTask(t, handler, TaskCreateInputBody{Name: "Cook dinner", NecessaryWorkers: 1, EstimatedTimeMin: 30})
+	postJSON(handler, fmt.Sprintf("/tasks/%d/ack", task.ID), TaskUserActionBody{UserId: "cook"})
+
+	codes := parallel(10, func(i int) int {
+		if i%2 == 0 {
+			return postJSON(handler, fmt.Sprintf("/tasks/%d/done", task.ID), struct{}{}).Code
+		}
+		return postJSON(handler, fmt.Sprintf("/tasks/%d/ack", task.ID), TaskUserActionBody{UserId: fmt.Sprintf("late%d", i)}).Code
+	})
+	// The late acks are waitlisted before the completion and conflict after it, they never go through.
+	if codes[http.StatusNoContent] != 1 || codes[http.StatusAccepted]+codes[http.StatusConflict] != 9 {
+		t.Fatalf("Expected a single completion and no late acks, got %v", codes)
+	}
+
+	wls, err := stor.GetWorkLogsForChore(task.ID)
+	if err != nil {
+		t.Fatalf("Failed to get work logs: %v", err)
+	}
+	if len(wls) != 1 || wls[0].UserId != "cook" || wls[0].TimeSpentMin != 30 {
+		t.Fatalf("Expected the cook credited once, got %+v", wls)
+	}
+}
+
+func TestConcurrentAckRejectTimeout(t *testing.T) {
+	api, stor, u, cleanup := setupTestApi(t)
+	defer cleanup()
+	handler := api.SetupRoutes()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	cl := chores.NewChoresLogic(stor, logger, chores.Config{})
+	reminder := reminders.NewReminder(stor, u, &cl, logger, &reminders.Config{ReminderRatio: 0.5})
+
+	task := createTask(t, handler, TaskCreateInputBody{Name: "Pitch the tents", NecessaryWorkers: 3, AssignmentTimeoutMin: 1})
+	users := []string{}
+	for i := range 8 {
+		users = append(users, fmt.Sprintf("u%d", i))
+	}
+	postJSON(handler, fmt.Sprintf("/tasks/%d/assign", task.ID), TaskAssignBody{UserIds: users})
+
+	// Make all the assignments expired, the reminders race the users to time them out.
+	all, _ := stor.GetChoreAssignments(task.ID)
+	for _, a := range all {
+		stor.UpdateChoreAssignment(a.ID, func(a *storage.ChoreAssignment) error {
+			a.Created = time.Now().Add(-time.Hour)
+			return nil
+		})
+	}
+
+	// There is a single reminder loop, it keeps checking while the users ack and reject.
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				reminder.CheckChores()
+			}
+		}
+	}()
+	parallel(24, func(i int) int {
+		if i%2 == 0 {
+			return postJSON(handler, fmt.Sprintf("/tasks/%d/ack", task.ID), TaskUserActionBody{UserId: users[i%len(users)]}).Code
+		}
+		return postJSON(handler, fmt.Sprintf("/tasks/%d/reject", task.ID), TaskUserActionBody{UserId: users[(i+3)%len(users)]}).Code
+	})
+	close(stop)
+	<-done
+
+	all, _ = stor.GetChoreAssignments(task.ID)
+	for _, a := range all {
+		if a.Acked != nil && a.Timeouted != nil {
+			t.Fatalf("The timeout overwrote the ack of %s", a.UserId)
+		}
+	}
+	acked := ackedCount(t, stor, task.ID)
+	if acked > 3 {
+		t.Fatalf("Expected at most 3 acked workers, got %d", acked)
+	}
+
+	codes := parallel(5, func(i int) int {
+		return postJSON(handler, fmt.Sprintf("/tasks/%d/done", task.ID), struct{}{}).Code
+	})
+	if codes[http.StatusNoContent] != 1 {
+		t.Fatalf("Expected a single completion, got %v", codes)
+	}
+	wls, _ := stor.GetWorkLogsForChore(task.ID)
+	if len(wls) != acked {
+		t.Fatalf("Expected %d work logs, got %d", acked, len(wls))
+	}
+}
+
+func TestConcurrentIdempotentCreation(t *testing.T) {
+	api, stor, _, cleanup := setupTestApi(t)
+	defer cleanup()
+	handler := api.SetupRoutes()
+
+	ids := make(chan uint, 10)
+	codes := parallel(10, func(i int) int {
+		w := postWithIdempotencyKey(handler, "cron-42", TaskCreateInputBody{Name: "Empty the bins"})
+		var task TaskData
+		json.Unmarshal(w.Body.Bytes(), &task)
+		if w.Code == http.StatusOK {
+			ids <- task.ID
+		}
+		return w.Code
+	})
+	close(ids)
+	// Retries racing the first request are told to wait, the others replay it.
+	if codes[http.StatusOK]+codes[http.StatusConflict] != 10 {
+		t.Fatalf("Expected replays and conflicts only, got %v", codes)
+	}
+	for id := range ids {
+		if id != 1 {
+			t.Fatalf("Expected every replay to return task 1, got %d", id)
+		}
+	}
+	all, _ := stor.GetChores()
+	if len(all) != 1 {
+		t.Fatalf("Expected a single task, got %d", len(all))
+	}
+}
+
+func TestConcurrentConditionalEdits(t *testing.T) {
+	api, _, _, cleanup := setupTestApi(t)
+	defer cleanup()
+	handler := api.SetupRoutes()
+
+	task := createTask(t, handler, TaskCreateInputBody{Name: "Sweep the porch"})
+	etag := fmt.Sprintf(`"%d"`, task.Version)
+
+	codes := parallel(10, func(i int) int {
+		return updateTask(handler, http.MethodPatch, task.ID, etag, map[string]any{"estimated_time_min": 10 + i}).Code
+	})
+	if codes[http.StatusOK] != 1 || codes[http.StatusPreconditionFailed] != 9 {
+		t.Fatalf("Expected a single edit based on the ETag to win, got %v", codes)
+	}
+}
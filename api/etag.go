@@ -0,0 +1,33 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/gdg-garage/garage-trip-chores/storage"
+)
+
+// taskETag is the entity tag of a task, it changes with every change of the task.
+func taskETag(version uint) string {
+	return fmt.Sprintf(`"%d"`, version)
+}
+
+// ifMatchVersion checks the If-Match header against the current version of the chore. It returns the version
+// the update has to be applied to, 0 when the header is not set and any version goes.
+func ifMatchVersion(ifMatch string, chore storage.Chore) (uint, error) {
+	if ifMatch == "" {
+		return 0, nil
+	}
+	for _, tag := range strings.Split(ifMatch, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == taskETag(chore.Version) {
+			return chore.Version, nil
+		}
+	}
+	return 0, errPreconditionFailed(chore)
+}
+
+func errPreconditionFailed(chore storage.Chore) error {
+	return huma.Error412PreconditionFailed(fmt.Sprintf("the task was changed meanwhile, its current ETag is %s", taskETag(chore.Version)))
+}
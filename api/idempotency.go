@@ -0,0 +1,80 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/gdg-garage/garage-trip-chores/storage"
+)
+
+const defaultIdempotencyHours = 24
+
+// idempotencyOwner keeps the keys of different clients apart.
+func idempotencyOwner(ctx context.Context) string {
+	key, ok := ctx.Value(apiKeyCtxKey{}).(storage.ApiKey)
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf("%d/%s/%s", key.ID, key.Name, key.UserId)
+}
+
+// createTaskIdempotent creates the task once per Idempotency-Key, replays get the task of the first request.
+// A replay with a different body is a conflict, as is a replay while the first request is still running.
+func (a *Api) createTaskIdempotent(ctx context.Context, input *CreateTaskInput) (*TaskCreatedResponse, error) {
+	body, err := json.Marshal(input.Body)
+	if err != nil {
+		return nil, err
+	}
+	hash := sha256.Sum256(body)
+	hours := a.conf.IdempotencyHours
+	if hours <= 0 {
+		hours = defaultIdempotencyHours
+	}
+
+	k, reserved, err := a.storage.ReserveIdempotencyKey(storage.IdempotencyKey{
+		Owner:       idempotencyOwner(ctx),
+		Key:         input.IdempotencyKey,
+		RequestHash: hex.EncodeToString(hash[:]),
+	}, time.Duration(hours)*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	if !reserved {
+		if k.RequestHash != hex.EncodeToString(hash[:]) {
+			return nil, huma.Error409Conflict("the Idempotency-Key was already used with a different request body")
+		}
+		if k.Response == "" {
+			return nil, huma.Error409Conflict("a request with the same Idempotency-Key is still running")
+		}
+		resp := &TaskCreatedResponse{IdempotentReplayed: "true"}
+		if err := json.Unmarshal([]byte(k.Response), &resp.Body); err != nil {
+			return nil, err
+		}
+		resp.ETag = taskETag(resp.Body.Version)
+		return resp, nil
+	}
+
+	created, err := a.createTask(input.Body)
+	if err != nil {
+		if err := a.storage.ReleaseIdempotencyKey(k.ID); err != nil {
+			a.logger.Error("failed to release idempotency key", "error", err, "key", k.Key)
+		}
+		return nil, err
+	}
+	stored, err := json.Marshal(created.Body)
+	if err == nil {
+		err = a.storage.CompleteIdempotencyKey(k.ID, string(stored))
+	}
+	if err != nil {
+		a.logger.Error("failed to store idempotent response", "error", err, "key", k.Key)
+		if err := a.storage.ReleaseIdempotencyKey(k.ID); err != nil {
+			a.logger.Error("failed to release idempotency key", "error", err, "key", k.Key)
+		}
+	}
+	return &TaskCreatedResponse{ETag: created.ETag, Body: created.Body}, nil
+}
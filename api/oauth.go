@@ -0,0 +1,230 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/gdg-garage/garage-trip-chores/storage"
+)
+
+// OAuthConfig enables "Login with Discord", the URLs can point to a fake provider in tests.
+type OAuthConfig struct {
+	ClientId     string   `json:"clientid"`
+	ClientSecret string   `json:"clientsecret"`
+	RedirectUrl  string   `json:"redirecturl"` // The /auth/callback URL of this API as registered in the Discord application.
+	AuthUrl      string   `json:"authurl"`
+	TokenUrl     string   `json:"tokenurl"`
+	ApiUrl       string   `json:"apiurl"`
+	AdminRoles   []string `json:"adminroles"` // IDs of the guild roles which get the admin scope.
+	SessionHours int      `json:"sessionhours"`
+	SuccessUrl   string   `json:"successurl"` // Where the browser is sent after the login.
+}
+
+func (c OAuthConfig) enabled() bool {
+	return c.ClientId != ""
+}
+
+const (
+	sessionCookie = "chores_session"
+	stateCookie   = "chores_oauth_state"
+)
+
+type discordUser struct {
+	Id         string `json:"id"`
+	Username   string `json:"username"`
+	GlobalName string `json:"global_name"`
+}
+
+type discordMember struct {
+	Roles []string `json:"roles"`
+}
+
+// sessionKey turns a session into the permissions of a key bound to the logged in user.
+func sessionKey(s storage.Session) storage.ApiKey {
+	key := storage.ApiKey{Name: "session " + s.Username, UserId: s.UserId}
+	if s.Admin {
+		key.SetScopes([]string{storage.ScopeAdmin})
+	} else {
+		key.SetScopes([]string{storage.ScopeWrite})
+	}
+	return key
+}
+
+func (a *Api) secureCookies() bool {
+	return strings.HasPrefix(a.conf.OAuth.RedirectUrl, "https://")
+}
+
+// oauthLogin sends the browser to Discord, the state cookie ties the callback to this browser.
+func (a *Api) oauthLogin(w http.ResponseWriter, r *http.Request) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	state := base64.RawURLEncoding.EncodeToString(b)
+	http.SetCookie(w, &http.Cookie{
+		Name:     stateCookie,
+		Value:    state,
+		Path:     "/auth",
+		MaxAge:   600,
+		HttpOnly: true,
+		Secure:   a.secureCookies(),
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	q := url.Values{}
+	q.Set("client_id", a.conf.OAuth.ClientId)
+	q.Set("redirect_uri", a.conf.OAuth.RedirectUrl)
+	q.Set("response_type", "code")
+	q.Set("scope", "identify guilds.members.read")
+	q.Set("state", state)
+	http.Redirect(w, r, a.conf.OAuth.AuthUrl+"?"+q.Encode(), http.StatusFound)
+}
+
+// oauthCallback exchanges the code for the user's identity and guild roles and starts a session.
+func (a *Api) oauthCallback(w http.ResponseWriter, r *http.Request) {
+	state, err := r.Cookie(stateCookie)
+	if err != nil || state.Value == "" || state.Value != r.URL.Query().Get("state") {
+		http.Error(w, "Invalid login state, try logging in again", http.StatusBadRequest)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{Name: stateCookie, Path: "/auth", MaxAge: -1})
+
+	code := r.URL.Query().Get("code")
+	if code == "" {
+		http.Error(w, "The login was not approved", http.StatusBadRequest)
+		return
+	}
+	token, err := a.exchangeOAuthCode(r.Context(), code)
+	if err != nil {
+		a.logger.Error("failed to exchange OAuth code", "error", err)
+		http.Error(w, "Login failed", http.StatusBadGateway)
+		return
+	}
+	var user discordUser
+	if err := a.discordGet(r.Context(), token, "/users/@me", &user); err != nil {
+		a.logger.Error("failed to get Discord user", "error", err)
+		http.Error(w, "Login failed", http.StatusBadGateway)
+		return
+	}
+	var member discordMember
+	if err := a.discordGet(r.Context(), token, "/users/@me/guilds/"+a.storage.GetDiscordGuildId()+"/member", &member); err != nil {
+		a.logger.Info("Login refused, not a guild member", "user_id", user.Id, "error", err)
+		http.Error(w, "Only members of the Discord server can log in", http.StatusForbidden)
+		return
+	}
+
+	name := user.GlobalName
+	if name == "" {
+		name = user.Username
+	}
+	session := storage.Session{
+		UserId:   user.Id,
+		Username: name,
+		Admin:    slices.ContainsFunc(member.Roles, func(r string) bool { return slices.Contains(a.conf.OAuth.AdminRoles, r) }),
+	}
+	ttl := time.Duration(a.conf.OAuth.SessionHours) * time.Hour
+	session, secret, err := a.storage.CreateSession(session, ttl)
+	if err != nil {
+		a.logger.Error("failed to create session", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	a.logger.Info("User logged in", "user_id", session.UserId, "admin", session.Admin)
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    secret,
+		Path:     "/",
+		Expires:  session.Expires,
+		HttpOnly: true,
+		Secure:   a.secureCookies(),
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, a.conf.OAuth.SuccessUrl, http.StatusFound)
+}
+
+func (a *Api) oauthLogout(w http.ResponseWriter, r *http.Request) {
+	if c, err := r.Cookie(sessionCookie); err == nil {
+		_ = a.storage.DeleteSession(c.Value)
+	}
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		_ = a.storage.DeleteSession(token)
+	}
+	http.SetCookie(w, &http.Cookie{Name: sessionCookie, Path: "/", MaxAge: -1})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *Api) exchangeOAuthCode(ctx context.Context, code string) (string, error) {
+	form := url.Values{}
+	form.Set("client_id", a.conf.OAuth.ClientId)
+	form.Set("client_secret", a.conf.OAuth.ClientSecret)
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", a.conf.OAuth.RedirectUrl)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.conf.OAuth.TokenUrl, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	var token struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := doJSON(req, &token); err != nil {
+		return "", err
+	}
+	if token.AccessToken == "" {
+		return "", errors.New("no access token in the response")
+	}
+	return token.AccessToken, nil
+}
+
+func (a *Api) discordGet(ctx context.Context, token string, path string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, a.conf.OAuth.ApiUrl+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return doJSON(req, out)
+}
+
+func doJSON(req *http.Request, out any) error {
+	client := http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s %s: %s", req.Method, req.URL.Path, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+type MeData struct {
+	UserId string   `json:"user_id,omitempty" doc:"The user the requests act as, empty for keys acting as anybody"`
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
+
+type MeResponse struct {
+	Body MeData
+}
+
+func (a *Api) me(ctx context.Context, input *struct{}) (*MeResponse, error) {
+	key, ok := ctx.Value(apiKeyCtxKey{}).(storage.ApiKey)
+	if !ok {
+		return nil, huma.Error401Unauthorized("not logged in")
+	}
+	return &MeResponse{Body: MeData{UserId: key.UserId, Name: key.Name, Scopes: key.GetScopes()}}, nil
+}
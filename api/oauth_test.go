@@ -0,0 +1,148 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// fakeDiscord is a local OAuth provider, the code is the ID of the user who logs in.
+func fakeDiscord() *httptest.Server {
+	members := map[string][]string{"admin1": {"role-admin"}, "u1": {"role-guest"}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.Form.Get("client_secret") != "secret" || r.Form.Get("grant_type") != "authorization_code" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"access_token": "at-" + r.Form.Get("code"), "token_type": "Bearer"})
+	})
+	user := func(r *http.Request) string {
+		return strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer at-")
+	}
+	mux.HandleFunc("GET /api/users/@me", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(discordUser{Id: user(r), Username: "name-" + user(r)})
+	})
+	mux.HandleFunc("GET /api/users/@me/guilds/test-guild/member", func(w http.ResponseWriter, r *http.Request) {
+		roles, ok := members[user(r)]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(discordMember{Roles: roles})
+	})
+	return httptest.NewServer(mux)
+}
+
+func login(t *testing.T, handler http.Handler, code string) (*http.Cookie, int) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/auth/login", nil))
+	if w.Code != http.StatusFound {
+		t.Fatalf("Expected a redirect to the provider, got %d", w.Code)
+	}
+	loc, _ := url.Parse(w.Header().Get("Location"))
+	state := loc.Query().Get("state")
+
+	req := httptest.NewRequest(http.MethodGet, "/auth/callback?code="+code+"&state="+state, nil)
+	for _, c := range w.Result().Cookies() {
+		req.AddCookie(c)
+	}
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	for _, c := range w.Result().Cookies() {
+		if c.Name == sessionCookie && c.Value != "" {
+			return c, w.Code
+		}
+	}
+	return nil, w.Code
+}
+
+func requestWithCookie(handler http.Handler, method string, path string, cookie *http.Cookie, payload any) *httptest.ResponseRecorder {
+	var body io.Reader
+	if payload != nil {
+		b, _ := json.Marshal(payload)
+		body = bytes.NewReader(b)
+	}
+	req := httptest.NewRequest(method, path, body)
+	req.Header.Set("Content-Type", "application/json")
+	req.AddCookie(cookie)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	return w
+}
+
+func TestDiscordLogin(t *testing.T) {
+	api, stor, _, cleanup := setupTestApi(t)
+	defer cleanup()
+	provider := fakeDiscord()
+	defer provider.Close()
+
+	api.conf.OAuth = OAuthConfig{
+		ClientId:     "client",
+		ClientSecret: "secret",
+		RedirectUrl:  "http://localhost/auth/callback",
+		AuthUrl:      provider.URL + "/authorize",
+		TokenUrl:     provider.URL + "/token",
+		ApiUrl:       provider.URL + "/api",
+		AdminRoles:   []string{"role-admin"},
+		SessionHours: 1,
+		SuccessUrl:   "/",
+	}
+	handler := api.SetupRoutes()
+
+	if w := requestWithKey(handler, http.MethodGet, "/tasks", "", nil); w.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected the login to close the API, got %d", w.Code)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/auth/callback?code=u1&state=forged", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected a forged state to be refused, got %d", w.Code)
+	}
+	if _, code := login(t, handler, "outsider"); code != http.StatusForbidden {
+		t.Fatalf("Expected users outside the guild to be refused, got %d", code)
+	}
+
+	guest, code := login(t, handler, "u1")
+	if guest == nil || code != http.StatusFound {
+		t.Fatalf("Expected a session for u1, got %d", code)
+	}
+	var me MeData
+	json.Unmarshal(requestWithCookie(handler, http.MethodGet, "/auth/me", guest, nil).Body.Bytes(), &me)
+	if me.UserId != "u1" || strings.Join(me.Scopes, ",") != "write" {
+		t.Fatalf("Expected u1 with the write scope, got %+v", me)
+	}
+
+	var task TaskData
+	w = requestWithCookie(handler, http.MethodPost, "/tasks", guest, TaskCreateInputBody{Name: "Light the fire"})
+	json.Unmarshal(w.Body.Bytes(), &task)
+	if w := requestWithCookie(handler, http.MethodPost, fmt.Sprintf("/tasks/%d/ack", task.ID), guest, TaskUserActionBody{UserId: "u2"}); w.Code != http.StatusForbidden {
+		t.Fatalf("Expected acking as somebody else to be refused, got %d", w.Code)
+	}
+	if w := requestWithCookie(handler, http.MethodPost, fmt.Sprintf("/tasks/%d/ack", task.ID), guest, TaskUserActionBody{}); w.Code != http.StatusNoContent {
+		t.Fatalf("Expected the ack as the logged in user, got %d: %s", w.Code, w.Body.String())
+	}
+	if ass, err := stor.GetChoreAssignment(task.ID, "u1"); err != nil || ass.Acked == nil {
+		t.Fatalf("Expected u1 to hold the acked task, got %v, %+v", err, ass)
+	}
+	if w := requestWithCookie(handler, http.MethodGet, "/apikeys", guest, nil); w.Code != http.StatusForbidden {
+		t.Fatalf("Expected guests not to manage keys, got %d", w.Code)
+	}
+
+	admin, _ := login(t, handler, "admin1")
+	if w := requestWithCookie(handler, http.MethodGet, "/apikeys", admin, nil); w.Code != http.StatusOK {
+		t.Fatalf("Expected the admin role to manage keys, got %d", w.Code)
+	}
+
+	requestWithCookie(handler, http.MethodPost, "/auth/logout", guest, nil)
+	if w := requestWithCookie(handler, http.MethodGet, "/tasks", guest, nil); w.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected the session to end with the logout, got %d", w.Code)
+	}
+}
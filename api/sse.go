@@ -0,0 +1,101 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gdg-garage/garage-trip-chores/storage"
+)
+
+// sseKeepAlive is how often an idle stream gets a comment, so proxies do not close it.
+const sseKeepAlive = 30 * time.Second
+
+// ServeEvents streams the events as Server-Sent Events, the alternative to the WebSocket for simple clients.
+// Every event carries its sequence number as the ID, a client reconnecting with Last-Event-ID (or the last_event_id
+// query parameter) first gets the events it missed.
+func (a *Api) ServeEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	sub, err := a.subscribe(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer a.storage.Events.Unsubscribe(sub)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	ticker := time.NewTicker(sseKeepAlive)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case e, ok := <-sub:
+			if !ok {
+				return
+			}
+			if err := writeSSE(w, e); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// lastEventId returns the event a resuming client saw last, from the Last-Event-ID header or the last_event_id
+// query parameter. It is nil for a new client.
+func lastEventId(r *http.Request) (*uint64, error) {
+	lastId := r.Header.Get("Last-Event-ID")
+	if lastId == "" {
+		lastId = r.URL.Query().Get("last_event_id")
+	}
+	if lastId == "" {
+		return nil, nil
+	}
+	last, err := strconv.ParseUint(lastId, 10, 64)
+	if err != nil {
+		return nil, errors.New("the last event ID is not an event ID")
+	}
+	return &last, nil
+}
+
+// subscribe starts the subscription of a client after the event it saw last, a new client gets the events from now on.
+func (a *Api) subscribe(r *http.Request) (chan storage.Event, error) {
+	last, err := lastEventId(r)
+	if err != nil {
+		return nil, err
+	}
+	if last == nil {
+		return a.storage.Events.Subscribe(), nil
+	}
+	return a.storage.Events.SubscribeFrom(*last), nil
+}
+
+func writeSSE(w http.ResponseWriter, e storage.Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	// No event field, EventSource clients get every event in onmessage and tell them apart by their type.
+	_, err = fmt.Fprintf(w, "id: %d\ndata: %s\n\n", e.Seq, data)
+	return err
+}
@@ -1,13 +1,11 @@
 package api
 
 import (
-	"log/slog"
 	"net/http"
-	"sync"
 	"time"
 
-	"github.com/gorilla/websocket"
 	"github.com/gdg-garage/garage-trip-chores/storage"
+	"github.com/gorilla/websocket"
 )
 
 var upgrader = websocket.Upgrader{
@@ -18,95 +16,71 @@ var upgrader = websocket.Upgrader{
 	},
 }
 
-type WsHub struct {
-	logger     *slog.Logger
-	clients    map[*websocket.Conn]bool
-	broadcast  chan storage.Event
-	register   chan *websocket.Conn
-	unregister chan *websocket.Conn
-	mu         sync.Mutex
-}
-
-func NewWsHub(logger *slog.Logger) *WsHub {
-	return &WsHub{
-		logger:     logger,
-		clients:    make(map[*websocket.Conn]bool),
-		broadcast:  make(chan storage.Event),
-		register:   make(chan *websocket.Conn),
-		unregister: make(chan *websocket.Conn),
-	}
-}
-
-func (h *WsHub) Run() {
-	for {
-		select {
-		case client := <-h.register:
-			h.mu.Lock()
-			h.clients[client] = true
-			h.mu.Unlock()
-		case client := <-h.unregister:
-			h.mu.Lock()
-			if _, ok := h.clients[client]; ok {
-				delete(h.clients, client)
-				client.Close()
-			}
-			h.mu.Unlock()
-		case event := <-h.broadcast:
-			h.mu.Lock()
-			for client := range h.clients {
-				err := client.WriteJSON(event)
-				if err != nil {
-					h.logger.Error("websocket write error", "error", err)
-					client.Close()
-					delete(h.clients, client)
-				}
-			}
-			h.mu.Unlock()
-		}
-	}
-}
-
+// ServeWs streams the events to a WebSocket client. Every client has its own subscription, a client
+// reconnecting with the last_event_id query parameter first gets the events it missed.
 func (api *Api) ServeWs(w http.ResponseWriter, r *http.Request) {
+	// Subscribed before the upgrade, so a bad last event ID is a plain HTTP error and no event is missed.
+	sub, err := api.subscribe(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
+		api.storage.Events.Unsubscribe(sub)
 		api.logger.Error("failed to upgrade websocket", "error", err)
 		return
 	}
 
 	go func() {
-		defer func() {
-			api.hub.unregister <- conn
-		}()
+		defer conn.Close()
+		defer api.storage.Events.Unsubscribe(sub)
 
-		if len(api.authorizedKeys) > 0 {
+		if api.authRequired() {
 			conn.SetReadDeadline(time.Now().Add(10 * time.Second))
 			var authMsg struct {
 				ApiKey string `json:"api_key"`
 			}
 			err = conn.ReadJSON(&authMsg)
-			if _, ok := api.authorizedKeys[authMsg.ApiKey]; err != nil || !ok {
+			var key storage.ApiKey
+			if err == nil {
+				key, err = api.authenticate(authMsg.ApiKey)
+			}
+			if err != nil || !key.Allows(storage.ScopeRead) {
 				api.logger.Warn("websocket auth failed", "error", err)
 				conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "Unauthorized"))
-				conn.Close()
 				return
 			}
 			conn.SetReadDeadline(time.Time{})
 		}
 
-		api.hub.register <- conn
+		closed := make(chan struct{})
+		go func() {
+			defer close(closed)
+			for {
+				_, _, err := conn.ReadMessage()
+				if err != nil {
+					if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
+						api.logger.Error("websocket read error", "error", err)
+					}
+					return
+				}
+			}
+		}()
 
 		for {
-			_, _, err := conn.ReadMessage()
-			if err != nil {
-				if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-					api.logger.Error("websocket read error", "error", err)
+			select {
+			case <-closed:
+				return
+			case event, ok := <-sub:
+				if !ok {
+					return
+				}
+				if err := conn.WriteJSON(event); err != nil {
+					api.logger.Error("websocket write error", "error", err)
+					return
 				}
-				break
 			}
 		}
 	}()
 }
-
-func (h *WsHub) BroadcastEvent(event storage.Event) {
-	h.broadcast <- event
-}
@@ -0,0 +1,110 @@
+package backlog
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/gdg-garage/garage-trip-chores/chores"
+	"github.com/gdg-garage/garage-trip-chores/storage"
+	"github.com/gdg-garage/garage-trip-chores/ui"
+)
+
+// Backlog fills understaffed chores whenever someone may have become available.
+type Backlog struct {
+	storage *storage.Storage
+	ui      *ui.Ui
+	chores  *chores.ChoresLogic
+	logger  *slog.Logger
+}
+
+func NewBacklog(storage *storage.Storage, ui *ui.Ui, chores *chores.ChoresLogic, logger *slog.Logger) *Backlog {
+	return &Backlog{
+		storage: storage,
+		ui:      ui,
+		chores:  chores,
+		logger:  logger,
+	}
+}
+
+// Events after which a present user may be able to take another chore. A released or transferred assignment
+// frees its holder and the resources of the chore.
+func drainsBacklog(e storage.Event) bool {
+	switch e.Type {
+	case storage.UserCheckedIn, storage.TaskDone, storage.TaskRefused, storage.TaskTimeout, storage.TaskCancelled,
+		storage.TaskReleased, storage.TaskTransferred:
+		return true
+	}
+	return false
+}
+
+func (b *Backlog) Drain() {
+	understaffed, err := b.storage.GetUnderstaffedChores()
+	if err != nil {
+		b.logger.Error("Error getting understaffed chores", "error", err)
+		return
+	}
+	if len(understaffed) == 0 {
+		return
+	}
+
+	users, err := b.storage.GetPresentUsers()
+	if err != nil {
+		b.logger.Error("Error getting present users", "error", err)
+		return
+	}
+	for _, chore := range understaffed {
+		ass, err := b.chores.AssignChoresToUsers(users, chore)
+		if err != nil {
+			b.logger.Error("Error assigning chores to users", "error", err, "chore_id", chore.ID)
+			continue
+		}
+		if len(ass) > 0 {
+			b.logger.Info("Backlog chore got new assignees", "chore_id", chore.ID, "count", len(ass))
+			b.ui.UpdateChoreMessage(chore)
+			b.ui.EmitChoreEvent("chore_reassigned", chore)
+		}
+	}
+}
+
+// untilSomeoneAvailable returns how long until the first unavailable user (quiet hours, away) is available again.
+func (b *Backlog) untilSomeoneAvailable() time.Duration {
+	wait := time.Hour
+	unavailable, err := b.storage.GetUnavailableUsers(time.Now())
+	if err != nil {
+		b.logger.Error("Error getting unavailable users", "error", err)
+		return wait
+	}
+	for _, until := range unavailable {
+		wait = min(wait, time.Until(until))
+	}
+	return max(wait, time.Second)
+}
+
+func (b *Backlog) RunBacklog(ctx context.Context, wg *sync.WaitGroup) {
+	wg.Add(1)
+	defer wg.Done()
+
+	sub := b.storage.Events.Subscribe()
+	defer b.storage.Events.Unsubscribe(sub)
+
+	b.Drain()
+	// Users waking up from their quiet hours or coming back drain the backlog too.
+	wake := time.NewTimer(b.untilSomeoneAvailable())
+	defer wake.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			b.logger.Debug("Backlog stopped: context cancelled", "reason", ctx.Err())
+			return
+		case event := <-sub:
+			if drainsBacklog(event) {
+				b.Drain()
+			}
+		case <-wake.C:
+			b.Drain()
+			wake.Reset(b.untilSomeoneAvailable())
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package backlog
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gdg-garage/garage-trip-chores/chores"
+	"github.com/gdg-garage/garage-trip-chores/storage"
+	"github.com/gdg-garage/garage-trip-chores/ui"
+)
+
+func setupBacklog(t *testing.T) (*Backlog, *storage.Storage) {
+	t.Helper()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	s, err := storage.New(storage.Config{DbPath: t.TempDir() + "/test.sqlite", Timezone: "UTC"}, logger)
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	cl := chores.NewChoresLogic(s, logger, chores.Config{})
+	u := ui.NewUi(s, logger, &cl, nil, ui.Config{})
+	return NewBacklog(s, u, &cl, logger), s
+}
+
+func TestDrainsBacklog(t *testing.T) {
+	for eventType, want := range map[storage.EventType]bool{
+		storage.UserCheckedIn:   true,
+		storage.TaskDone:        true,
+		storage.TaskRefused:     true,
+		storage.TaskTimeout:     true,
+		storage.TaskCancelled:   true,
+		storage.TaskReleased:    true,
+		storage.TaskTransferred: true,
+		storage.TaskCreated:     false,
+		storage.TaskAcked:       false,
+		storage.UserLeft:        false,
+	} {
+		if got := drainsBacklog(storage.Event{Type: eventType}); got != want {
+			t.Errorf("Expected %s to drain the backlog: %v, got %v", eventType, want, got)
+		}
+	}
+}
+
+func TestRunBacklogDrainsOnFreedCapacity(t *testing.T) {
+	b, s := setupBacklog(t)
+
+	// Somebody volunteered for the chore, it stays in the backlog until the next drain notices it is staffed.
+	c, err := s.SaveChore(storage.Chore{Name: "Wash the van", NecessaryWorkers: 1, Status: storage.ChoreOpen, Created: time.Now()})
+	if err != nil {
+		t.Fatalf("Failed to save chore: %v", err)
+	}
+	if _, _, err := s.AckChoreAssignment(c.ID, "u1", func(c *storage.Chore) error { return nil }); err != nil {
+		t.Fatalf("Failed to ack chore: %v", err)
+	}
+	s.SetChoreUnderstaffed(c.ID, true)
+
+	understaffed := func() bool {
+		t.Helper()
+		c, err := s.GetChore(c.ID)
+		if err != nil {
+			t.Fatalf("Failed to get chore: %v", err)
+		}
+		return c.Understaffed
+	}
+	waitDrained := func(reason string) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for understaffed() {
+			if time.Now().After(deadline) {
+				t.Fatalf("Expected the backlog to be drained %s", reason)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		b.RunBacklog(ctx, &wg)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+	waitDrained("on start")
+
+	for _, eventType := range []storage.EventType{storage.TaskReleased, storage.TaskTransferred} {
+		s.SetChoreUnderstaffed(c.ID, true)
+		s.Events.Publish(storage.Event{Type: eventType})
+		waitDrained("after " + string(eventType))
+	}
+}
@@ -0,0 +1,28 @@
+package chores
+
+import (
+	"errors"
+	"slices"
+
+	"github.com/gdg-garage/garage-trip-chores/storage"
+)
+
+var (
+	ErrAuctionNotOpen = errors.New("the chore is not open for bidding")
+	ErrAuctionOpen    = errors.New("the chore is auctioned, bid for it until the bidding closes")
+)
+
+// AuctionWinners picks up to `n` of the lowest bids (ordered lowest first) skipping the excluded users.
+func AuctionWinners(bids []storage.Bid, n int, exclude []string) []storage.Bid {
+	winners := []storage.Bid{}
+	for _, b := range bids {
+		if len(winners) >= n {
+			break
+		}
+		if slices.Contains(exclude, b.UserId) {
+			continue
+		}
+		winners = append(winners, b)
+	}
+	return winners
+}
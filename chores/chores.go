@@ -59,7 +59,14 @@ func OversampleCnt(needed uint, ratio float64) uint {
 type StorageAccess interface {
 	GetTotalNormalizedChoreStats() (storage.UserChoreStats, error)
 	GetChoreAssignments(choreId uint) ([]storage.ChoreAssignment, error)
-	SaveChoreAssignments(assignments []storage.ChoreAssignment) ([]storage.ChoreAssignment, error)
+	AddChoreAssignments(choreId uint, assignments []storage.ChoreAssignment, limit uint) ([]storage.ChoreAssignment, error)
+	GetOpenAssignmentCounts() (map[string]int, error)
+	SetChoreUnderstaffed(choreId uint, understaffed bool) error
+	GetCoworkCounts(since time.Time) (map[string]map[string]int, error)
+	GetUnavailableUsers(at time.Time) (map[string]time.Time, error)
+	GetAllUserSettings() (map[string]storage.UserSettings, error)
+	SetChoreBounty(choreId uint, bounty float64) error
+	GetResourceLocks() (map[string]uint, error)
 }
 
 type ChoresLogic struct {
@@ -76,9 +83,16 @@ func NewChoresLogic(storage StorageAccess, logger *slog.Logger, config Config) C
 	}
 }
 
+// MaxOpenAssignments returns the number of open chores the user can hold at once (0 means unlimited).
+func (cl ChoresLogic) MaxOpenAssignments(userId string) uint {
+	if max, ok := cl.config.MaxOpenAssignmentsPerUser[userId]; ok {
+		return max
+	}
+	return cl.config.MaxOpenAssignments
+}
+
 func (cl ChoresLogic) AssignChoresToUsers(users []storage.User, chore storage.Chore) ([]storage.ChoreAssignment, error) {
-	needed := chore.NecessaryWorkers + OversampleCnt(chore.NecessaryWorkers, cl.config.OversampleRatio)
-	assignments := make([]storage.ChoreAssignment, 0, needed)
+	assignments := []storage.ChoreAssignment{}
 
 	userTotalStats, err := cl.storage.GetTotalNormalizedChoreStats()
 	if err != nil {
@@ -104,6 +118,8 @@ func (cl ChoresLogic) AssignChoresToUsers(users []storage.User, chore storage.Ch
 	}
 
 	alreadyAssignedCnt := uint(0)
+	manuallyAssignedCnt := uint(0)
+	members := []string{}
 	ass, err := cl.storage.GetChoreAssignments(chore.ID)
 	if err != nil {
 		cl.logger.Error("failed to get chore assignments", "error", err, "chore_id", chore.ID)
@@ -111,18 +127,108 @@ func (cl ChoresLogic) AssignChoresToUsers(users []storage.User, chore storage.Ch
 	}
 	for _, a := range ass {
 		delete(userStatsWithCap, a.UserId)
-		if a.Refused == nil && a.Timeouted == nil {
-			alreadyAssignedCnt++
+		if a.Active() {
+			members = append(members, a.UserId)
+			if a.Manual {
+				manuallyAssignedCnt++
+			} else {
+				alreadyAssignedCnt++
+			}
 		}
 	}
 
+	// Manually assigned users take their slots, the strategy (with oversampling) fills only the remainder.
+	remainder := chore.NecessaryWorkers - min(manuallyAssignedCnt, chore.NecessaryWorkers)
+	teamMode := chore.TeamMode && chore.NecessaryWorkers > 1
+	needed := remainder
+	if !teamMode {
+		needed += OversampleCnt(remainder, cl.config.OversampleRatio)
+	}
+	if alreadyAssignedCnt >= needed {
+		return assignments, cl.setUnderstaffed(chore, alreadyAssignedCnt < remainder)
+	}
+	slots := needed
 	needed -= alreadyAssignedCnt
-	if needed <= 0 {
-		return assignments, nil
+
+	// A chore needing a resource somebody else holds waits in the backlog until it is released.
+	conflicts, err := cl.ResourceConflicts(chore)
+	if err != nil {
+		return nil, err
+	}
+	if len(conflicts) > 0 {
+		cl.logger.Info("chore waits for resources", "chore_id", chore.ID, "conflicts", conflicts)
+		return assignments, cl.setUnderstaffed(chore, true)
+	}
+
+	// Skip users who already hold as many open chores as they are allowed to.
+	openCounts, err := cl.storage.GetOpenAssignmentCounts()
+	if err != nil {
+		cl.logger.Error("failed to get open assignment counts", "error", err)
+		return nil, err
+	}
+	cappedCnt := 0
+	for user := range userStatsWithCap {
+		if max := cl.MaxOpenAssignments(user); max > 0 && uint(openCounts[user]) >= max {
+			delete(userStatsWithCap, user)
+			cappedCnt++
+		}
 	}
 
-	sortedUsers := SortUsersBasedOnChoreStats(userStatsWithCap)
-	selectedUsers := sortedUsers[:int(math.Min(float64(len(sortedUsers)), float64(needed)))]
+	// Skip users in their quiet hours or away, the backlog picks the chore up again later.
+	unavailable, err := cl.storage.GetUnavailableUsers(time.Now())
+	if err != nil {
+		cl.logger.Error("failed to get unavailable users", "error", err)
+		return nil, err
+	}
+	for user := range unavailable {
+		delete(userStatsWithCap, user)
+	}
+
+	// Liked and disliked chores shift the user's load by the preference weight, a larger load gap still wins.
+	if cl.config.PreferenceWeightMin > 0 {
+		settings, err := cl.storage.GetAllUserSettings()
+		if err != nil {
+			cl.logger.Error("failed to get user settings", "error", err)
+			return nil, err
+		}
+		for user, st := range userStatsWithCap {
+			if pref := settings[user].Preference(chore.Tags()); pref != 0 {
+				st.TotalMin -= float64(pref) * cl.config.PreferenceWeightMin
+				userStatsWithCap[user] = st
+			}
+		}
+	}
+
+	var selectedUsers []string
+	if teamMode {
+		// Skills only have to be covered by the team as a whole, candidates are ordered by load alone.
+		loadOnly := map[string]storage.ChoreStatsWithCapabilities{}
+		for user, st := range userStatsWithCap {
+			loadOnly[user] = storage.ChoreStatsWithCapabilities{ChoreStats: st.ChoreStats}
+		}
+		capabilities := map[string][]string{}
+		for _, user := range users {
+			capabilities[user.DiscordId] = user.Capabilities
+		}
+		selectedUsers, err = cl.selectTeam(chore, SortUsersBasedOnChoreStats(loadOnly), members, capabilities, int(needed))
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		sortedUsers := SortUsersBasedOnChoreStats(userStatsWithCap)
+		selectedUsers = sortedUsers[:int(math.Min(float64(len(sortedUsers)), float64(needed)))]
+	}
+
+	// Keep the chore in the backlog until it has enough assignees.
+	if alreadyAssignedCnt+uint(len(selectedUsers)) < remainder {
+		cl.logger.Info("chore is understaffed", "chore_id", chore.ID, "capped_users", cappedCnt, "available_users", len(userStatsWithCap))
+		err = cl.setUnderstaffed(chore, true)
+	} else {
+		err = cl.setUnderstaffed(chore, false)
+	}
+	if err != nil {
+		return nil, err
+	}
 
 	// Create assignments for the selected users
 	for _, user := range selectedUsers {
@@ -134,5 +240,38 @@ func (cl ChoresLogic) AssignChoresToUsers(users []storage.User, chore storage.Ch
 		}
 		assignments = append(assignments, assignment)
 	}
-	return cl.storage.SaveChoreAssignments(assignments)
+	// The slots are checked again when saving, a concurrent assignment might have filled them meanwhile.
+	return cl.storage.AddChoreAssignments(chore.ID, assignments, slots)
+}
+
+func (cl ChoresLogic) setUnderstaffed(chore storage.Chore, understaffed bool) error {
+	if chore.Understaffed == understaffed {
+		return nil
+	}
+	err := cl.storage.SetChoreUnderstaffed(chore.ID, understaffed)
+	if err != nil {
+		cl.logger.Error("failed to update chore staffing", "error", err, "chore_id", chore.ID)
+	}
+	return err
+}
+
+// BumpBounty raises the bounty of a chore somebody refused or let time out.
+func (cl ChoresLogic) BumpBounty(chore storage.Chore) (storage.Chore, error) {
+	if cl.config.BountyStep <= 0 || chore.Completed != nil || chore.Cancelled != nil {
+		return chore, nil
+	}
+	bounty := chore.BountyMultiplier() + cl.config.BountyStep
+	if cl.config.BountyMax > 0 {
+		bounty = min(bounty, max(cl.config.BountyMax, 1))
+	}
+	if bounty == chore.Bounty {
+		return chore, nil
+	}
+	err := cl.storage.SetChoreBounty(chore.ID, bounty)
+	if err != nil {
+		cl.logger.Error("failed to update chore bounty", "error", err, "chore_id", chore.ID)
+		return chore, err
+	}
+	chore.Bounty = bounty
+	return chore, nil
 }
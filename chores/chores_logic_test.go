@@ -1,8 +1,10 @@
 package chores
 
 import (
+	"fmt"
 	"log/slog"
 	"os"
+	"slices"
 	"testing"
 	"time"
 
@@ -10,8 +12,14 @@ import (
 )
 
 type MockStorage struct {
-	Stats       storage.UserChoreStats
-	Assignments []storage.ChoreAssignment
+	Stats        storage.UserChoreStats
+	Assignments  []storage.ChoreAssignment
+	Understaffed map[uint]bool
+	Cowork       map[string]map[string]int
+	Unavailable  map[string]time.Time
+	Settings     map[string]storage.UserSettings
+	Bounty       map[uint]float64
+	Locks        map[string]uint
 }
 
 func (m *MockStorage) GetTotalNormalizedChoreStats() (storage.UserChoreStats, error) {
@@ -28,11 +36,53 @@ func (m *MockStorage) GetChoreAssignments(choreId uint) ([]storage.ChoreAssignme
 	return ass, nil
 }
 
-func (m *MockStorage) SaveChoreAssignments(assignments []storage.ChoreAssignment) ([]storage.ChoreAssignment, error) {
+func (m *MockStorage) AddChoreAssignments(choreId uint, assignments []storage.ChoreAssignment, limit uint) ([]storage.ChoreAssignment, error) {
 	m.Assignments = append(m.Assignments, assignments...)
 	return assignments, nil
 }
 
+func (m *MockStorage) GetOpenAssignmentCounts() (map[string]int, error) {
+	counts := map[string]int{}
+	for _, a := range m.Assignments {
+		if a.Active() {
+			counts[a.UserId]++
+		}
+	}
+	return counts, nil
+}
+
+func (m *MockStorage) SetChoreUnderstaffed(choreId uint, understaffed bool) error {
+	if m.Understaffed == nil {
+		m.Understaffed = map[uint]bool{}
+	}
+	m.Understaffed[choreId] = understaffed
+	return nil
+}
+
+func (m *MockStorage) GetCoworkCounts(since time.Time) (map[string]map[string]int, error) {
+	return m.Cowork, nil
+}
+
+func (m *MockStorage) GetUnavailableUsers(at time.Time) (map[string]time.Time, error) {
+	return m.Unavailable, nil
+}
+
+func (m *MockStorage) GetAllUserSettings() (map[string]storage.UserSettings, error) {
+	return m.Settings, nil
+}
+
+func (m *MockStorage) SetChoreBounty(choreId uint, bounty float64) error {
+	if m.Bounty == nil {
+		m.Bounty = map[uint]float64{}
+	}
+	m.Bounty[choreId] = bounty
+	return nil
+}
+
+func (m *MockStorage) GetResourceLocks() (map[string]uint, error) {
+	return m.Locks, nil
+}
+
 func TestAssignChoresToUsers(t *testing.T) {
 	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
 	mockStorage := &MockStorage{
@@ -127,3 +177,409 @@ func TestAssignChoresToUsers(t *testing.T) {
 		t.Fatalf("expected 0 assignment, got %d", len(assignments4))
 	}
 }
+
+func TestAssignChoresRespectsOpenAssignmentCap(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	mockStorage := &MockStorage{
+		Stats: storage.UserChoreStats{
+			"u1": {Count: 0, TotalMin: 0},
+			"u2": {Count: 1, TotalMin: 10},
+		},
+		Assignments: []storage.ChoreAssignment{
+			{ChoreId: 1, UserId: "u1"},
+			{ChoreId: 2, UserId: "u1"},
+			{ChoreId: 2, UserId: "u2"},
+		},
+	}
+
+	cl := NewChoresLogic(mockStorage, logger, Config{
+		MaxOpenAssignments:        2,
+		MaxOpenAssignmentsPerUser: map[string]uint{"u2": 1},
+	})
+	users := []storage.User{{DiscordId: "u1"}, {DiscordId: "u2"}}
+
+	// Both users are at their cap, the chore gets queued.
+	assignments, err := cl.AssignChoresToUsers(users, storage.Chore{ID: 3, NecessaryWorkers: 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(assignments) != 0 {
+		t.Fatalf("expected no assignments, got %v", assignments)
+	}
+	if !mockStorage.Understaffed[3] {
+		t.Fatal("expected the chore to be queued")
+	}
+
+	// u1 finishes a chore and drops below the cap.
+	now := time.Now()
+	mockStorage.Assignments[0].Timeouted = &now
+	assignments, err = cl.AssignChoresToUsers(users, storage.Chore{ID: 3, NecessaryWorkers: 1, Understaffed: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(assignments) != 1 || assignments[0].UserId != "u1" {
+		t.Fatalf("expected u1 to be assigned, got %v", assignments)
+	}
+	if mockStorage.Understaffed[3] {
+		t.Fatal("expected the chore to leave the queue")
+	}
+}
+
+func TestAssignChoresFillsOnlyRemainderOfManualSlots(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	mockStorage := &MockStorage{
+		Stats: storage.UserChoreStats{
+			"u1": {Count: 0, TotalMin: 0},
+			"u2": {Count: 1, TotalMin: 10},
+			"u3": {Count: 2, TotalMin: 20},
+		},
+		Assignments: []storage.ChoreAssignment{
+			{ChoreId: 1, UserId: "u3", Manual: true},
+		},
+	}
+
+	cl := NewChoresLogic(mockStorage, logger, Config{})
+	users := []storage.User{{DiscordId: "u1"}, {DiscordId: "u2"}, {DiscordId: "u3"}}
+
+	assignments, err := cl.AssignChoresToUsers(users, storage.Chore{ID: 1, NecessaryWorkers: 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(assignments) != 1 || assignments[0].UserId != "u1" {
+		t.Fatalf("expected only u1 to fill the remaining slot, got %v", assignments)
+	}
+
+	// Manual assignees filling all slots leave nothing for the strategy.
+	mockStorage.Assignments = []storage.ChoreAssignment{
+		{ChoreId: 2, UserId: "u2", Manual: true},
+		{ChoreId: 2, UserId: "u3", Manual: true},
+	}
+	assignments, err = cl.AssignChoresToUsers(users, storage.Chore{ID: 2, NecessaryWorkers: 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(assignments) != 0 {
+		t.Fatalf("expected no automatic assignments, got %v", assignments)
+	}
+}
+
+func TestAssignTeam(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	mockStorage := &MockStorage{
+		Stats: storage.UserChoreStats{
+			"u1": {Count: 0, TotalMin: 0},
+			"u2": {Count: 1, TotalMin: 10},
+			"u3": {Count: 2, TotalMin: 20},
+			"u4": {Count: 3, TotalMin: 30},
+		},
+		Cowork: map[string]map[string]int{
+			"u1": {"u2": 3},
+			"u2": {"u1": 3},
+		},
+	}
+	cl := NewChoresLogic(mockStorage, logger, Config{OversampleRatio: 1})
+	users := []storage.User{
+		{DiscordId: "u1"},
+		{DiscordId: "u2"},
+		{DiscordId: "u3", Capabilities: []string{"cook"}},
+		{DiscordId: "u4", Capabilities: []string{"cook", "drv"}},
+	}
+	teamOf := func(ass []storage.ChoreAssignment) []string {
+		team := []string{}
+		for _, a := range ass {
+			team = append(team, a.UserId)
+		}
+		slices.Sort(team)
+		return team
+	}
+
+	// u1 and u2 worked together recently, the team rotates partners (and does not oversample).
+	ass, err := cl.AssignChoresToUsers(users, storage.Chore{ID: 1, NecessaryWorkers: 2, TeamMode: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if team := teamOf(ass); !slices.Equal(team, []string{"u1", "u3"}) {
+		t.Fatalf("expected team u1 u3, got %v", team)
+	}
+
+	// The team as a whole has to cover the skills.
+	c := storage.Chore{ID: 2, NecessaryWorkers: 2, TeamMode: true}
+	c.SetCapabilities([]string{"drv"})
+	ass, err = cl.AssignChoresToUsers(users, c)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if team := teamOf(ass); !slices.Equal(team, []string{"u1", "u4"}) {
+		t.Fatalf("expected team u1 u4, got %v", team)
+	}
+
+	// Training pairs a mentor with somebody lacking the skill.
+	c = storage.Chore{ID: 3, NecessaryWorkers: 2, TeamMode: true, TrainingMode: true}
+	c.SetCapabilities([]string{"cook"})
+	users = []storage.User{
+		{DiscordId: "u1", Capabilities: []string{"cook"}},
+		{DiscordId: "u2", Capabilities: []string{"cook"}},
+		{DiscordId: "u4"},
+	}
+	ass, err = cl.AssignChoresToUsers(users, c)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if team := teamOf(ass); !slices.Equal(team, []string{"u1", "u4"}) {
+		t.Fatalf("expected mentor u1 with trainee u4, got %v", team)
+	}
+}
+
+func TestSelectLargeTeam(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	cl := NewChoresLogic(&MockStorage{}, logger, Config{})
+
+	// Every skill has a different holder at the end of the load ordering.
+	candidates := []string{}
+	capabilities := map[string][]string{}
+	skills := []string{}
+	for i := range 60 {
+		candidates = append(candidates, fmt.Sprintf("u%d", i))
+	}
+	for i := range 15 {
+		skill := fmt.Sprintf("skill%d", i)
+		skills = append(skills, skill)
+		capabilities[candidates[59-i]] = []string{skill}
+	}
+	c := storage.Chore{ID: 1, NecessaryWorkers: 20, TeamMode: true}
+	c.SetCapabilities(skills)
+
+	done := make(chan []string)
+	go func() {
+		team, _ := cl.selectTeam(c, candidates, nil, capabilities, 20)
+		done <- team
+	}()
+	select {
+	case team := <-done:
+		if len(team) != 20 {
+			t.Fatalf("expected a team of 20, got %v", team)
+		}
+		if score := scoreTeam(c, team, capabilities, nil); score.missingSkills != 0 {
+			t.Fatalf("expected the team to cover every skill, got %v", team)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the team to be selected quickly")
+	}
+}
+
+func TestAssignChoresSkipsUnavailableUsers(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	mockStorage := &MockStorage{
+		Stats: storage.UserChoreStats{
+			"u1": {Count: 0, TotalMin: 0},
+			"u2": {Count: 1, TotalMin: 10},
+		},
+		Unavailable: map[string]time.Time{"u1": time.Now().Add(time.Hour)},
+	}
+
+	cl := NewChoresLogic(mockStorage, logger, Config{})
+	users := []storage.User{{DiscordId: "u1"}, {DiscordId: "u2"}}
+
+	assignments, err := cl.AssignChoresToUsers(users, storage.Chore{ID: 1, NecessaryWorkers: 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(assignments) != 1 || assignments[0].UserId != "u2" {
+		t.Fatalf("expected the sleeping u1 to be skipped, got %v", assignments)
+	}
+}
+
+func TestAssignChoresRespectsPreferences(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	mockStorage := &MockStorage{
+		Stats: storage.UserChoreStats{
+			"u1": {Count: 1, TotalMin: 10},
+			"u2": {Count: 2, TotalMin: 30},
+			"u3": {Count: 8, TotalMin: 200},
+		},
+		Settings: map[string]storage.UserSettings{
+			"u1": {UserId: "u1", Dislikes: "toilets"},
+			"u2": {UserId: "u2", Likes: "cooking"},
+			"u3": {UserId: "u3", Likes: "toilets"},
+		},
+	}
+	users := []storage.User{{DiscordId: "u1"}, {DiscordId: "u2"}, {DiscordId: "u3"}}
+	cl := NewChoresLogic(mockStorage, logger, Config{PreferenceWeightMin: 30})
+
+	// u2 likes cooking which outweighs the small load gap to u1.
+	cooking := storage.Chore{ID: 1, NecessaryWorkers: 1, Categories: "cooking"}
+	ass, err := cl.AssignChoresToUsers(users, cooking)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ass) != 1 || ass[0].UserId != "u2" {
+		t.Fatalf("expected u2 who likes cooking, got %v", ass)
+	}
+
+	// u1 dislikes toilets, but u3 who likes them is too far ahead in load, so u2 gets them.
+	toilets := storage.Chore{ID: 2, NecessaryWorkers: 1, Categories: "Toilets"}
+	ass, err = cl.AssignChoresToUsers(users, toilets)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ass) != 1 || ass[0].UserId != "u2" {
+		t.Fatalf("expected u2 as fairness beats u3's preference, got %v", ass)
+	}
+}
+
+func TestCheckFairness(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	mockStorage := &MockStorage{
+		Stats: storage.UserChoreStats{
+			"u1": {Count: 1, TotalMin: 10},
+			"u2": {Count: 1, TotalMin: 20},
+			"u3": {Count: 4, TotalMin: 60},
+		},
+	}
+	users := []storage.User{{DiscordId: "u1"}, {DiscordId: "u2"}, {DiscordId: "u3"}, {DiscordId: "u4"}}
+
+	cl := NewChoresLogic(mockStorage, logger, Config{FairnessPolicy: FairnessOff, FairnessThresholdPct: 50})
+	verdict, err := cl.CheckFairness(users, "u3")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if verdict.Exceeded {
+		t.Fatalf("expected the check to be disabled, got %+v", verdict)
+	}
+
+	cl = NewChoresLogic(mockStorage, logger, Config{FairnessPolicy: FairnessBlock, FairnessThresholdPct: 50})
+
+	// Median of 0, 10, 20, 60 is 15, 60 is more than 50 % above it.
+	verdict, err = cl.CheckFairness(users, "u3")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !verdict.Exceeded || verdict.Median != 15 {
+		t.Fatalf("expected u3 to exceed the median 15, got %+v", verdict)
+	}
+	if len(verdict.UnderLoaded) != 2 || verdict.UnderLoaded[0] != "u4" || verdict.UnderLoaded[1] != "u1" {
+		t.Fatalf("expected u4 and u1 to be under-loaded, got %v", verdict.UnderLoaded)
+	}
+
+	// 20 is within 50 % of the median.
+	verdict, err = cl.CheckFairness(users, "u2")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if verdict.Exceeded {
+		t.Fatalf("expected u2 not to exceed the median, got %+v", verdict)
+	}
+
+	// Most users have no work yet, the median of 0, 0, 60 says nothing.
+	verdict, err = cl.CheckFairness([]storage.User{{DiscordId: "u3"}, {DiscordId: "u4"}, {DiscordId: "u5"}}, "u3")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if verdict.Exceeded {
+		t.Fatalf("expected no verdict for a median of 0, got %+v", verdict)
+	}
+
+	// Nobody to compare with.
+	verdict, err = cl.CheckFairness(users[2:3], "u3")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if verdict.Exceeded {
+		t.Fatalf("expected no verdict for a single present user, got %+v", verdict)
+	}
+}
+
+func TestBumpBounty(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	mockStorage := &MockStorage{}
+	cl := NewChoresLogic(mockStorage, logger, Config{BountyStep: 0.5, BountyMax: 2})
+
+	chore := storage.Chore{ID: 1, EstimatedTimeMin: 10}
+	for _, expected := range []float64{1.5, 2, 2} {
+		var err error
+		chore, err = cl.BumpBounty(chore)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if chore.Bounty != expected || mockStorage.Bounty[1] != expected {
+			t.Fatalf("expected bounty %v, got %v (stored %v)", expected, chore.Bounty, mockStorage.Bounty[1])
+		}
+	}
+	if chore.Credit(10) != 20 {
+		t.Fatalf("expected 20 credited minutes, got %d", chore.Credit(10))
+	}
+
+	disabled := NewChoresLogic(mockStorage, logger, Config{})
+	if c, _ := disabled.BumpBounty(storage.Chore{ID: 2}); c.Bounty != 0 {
+		t.Fatalf("expected no bounty when disabled, got %v", c.Bounty)
+	}
+}
+
+func TestAuctionWinners(t *testing.T) {
+	bids := []storage.Bid{
+		{UserId: "u1", TimeMin: 20},
+		{UserId: "u2", TimeMin: 30},
+		{UserId: "u3", TimeMin: 40},
+	}
+
+	winners := AuctionWinners(bids, 2, []string{"u1"})
+	if len(winners) != 2 || winners[0].UserId != "u2" || winners[1].UserId != "u3" {
+		t.Fatalf("expected u2 and u3 to win, got %v", winners)
+	}
+	if winners := AuctionWinners(bids, 5, nil); len(winners) != 3 {
+		t.Fatalf("expected all bidders to win, got %v", winners)
+	}
+	if winners := AuctionWinners(nil, 1, nil); len(winners) != 0 {
+		t.Fatalf("expected no winners without bids, got %v", winners)
+	}
+}
+
+func TestSelectShiftWorkers(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	mockStorage := &MockStorage{
+		Stats: storage.UserChoreStats{
+			"u1": {Count: 1, TotalMin: 10},
+			"u2": {Count: 2, TotalMin: 30},
+			"u3": {Count: 3, TotalMin: 60},
+		},
+	}
+	cl := NewChoresLogic(mockStorage, logger, Config{})
+	users := []storage.User{{DiscordId: "u1"}, {DiscordId: "u2"}, {DiscordId: "u3"}, {DiscordId: "u4"}}
+
+	workers, err := cl.SelectShiftWorkers(users, 2, []string{"u4"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !slices.Equal(workers, []string{"u1", "u2"}) {
+		t.Fatalf("expected the least loaded u1 and u2, got %v", workers)
+	}
+
+	workers, _ = cl.SelectShiftWorkers(users, 5, []string{"u1", "u2"})
+	if !slices.Equal(workers, []string{"u4", "u3"}) {
+		t.Fatalf("expected u4 and u3, got %v", workers)
+	}
+}
+
+func TestAssignChoresWaitsForLockedResource(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	mockStorage := &MockStorage{
+		Stats: storage.UserChoreStats{"u1": {Count: 0, TotalMin: 0}},
+		Locks: map[string]uint{"van": 1},
+	}
+	cl := NewChoresLogic(mockStorage, logger, Config{})
+	users := []storage.User{{DiscordId: "u1"}}
+
+	// The holder itself is not blocked by its own lock.
+	ass, err := cl.AssignChoresToUsers(users, storage.Chore{ID: 1, NecessaryWorkers: 1, Resources: "van"})
+	if err != nil || len(ass) != 1 {
+		t.Fatalf("expected the holder to be assigned, got %v (%v)", ass, err)
+	}
+
+	ass, err = cl.AssignChoresToUsers(users, storage.Chore{ID: 2, NecessaryWorkers: 1, Resources: "grill,van"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ass) != 0 || !mockStorage.Understaffed[2] {
+		t.Fatalf("expected the chore to wait in the backlog, got %v", ass)
+	}
+}
@@ -1,6 +1,7 @@
 package chores
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/gdg-garage/garage-trip-chores/storage"
@@ -155,3 +156,60 @@ func TestSliceIntersect(t *testing.T) {
 		}
 	}
 }
+
+func TestMedian(t *testing.T) {
+	tests := []struct {
+		values   []float64
+		expected float64
+	}{
+		{[]float64{}, 0},
+		{[]float64{3}, 3},
+		{[]float64{3, 1, 2}, 2},
+		{[]float64{4, 1, 3, 2}, 2.5},
+	}
+
+	for _, tt := range tests {
+		got := Median(tt.values)
+		if got != tt.expected {
+			t.Errorf("Median(%v) = %f; want %f", tt.values, got, tt.expected)
+		}
+	}
+}
+
+func TestTransition(t *testing.T) {
+	c := storage.Chore{ID: 1}
+	steps := []struct {
+		to storage.ChoreStatus
+		ok bool
+	}{
+		{storage.ChoreInProgress, false}, // Drafts cannot be acked.
+		{storage.ChoreOpen, true},
+		{storage.ChoreInProgress, true},
+		{storage.ChoreInProgress, true}, // Another ack.
+		{storage.ChoreDone, true},
+		{storage.ChoreDone, false},
+		{storage.ChoreCancelled, false},
+		{storage.ChoreReopened, true},
+		{storage.ChoreCancelled, true},
+		{storage.ChoreOpen, false},
+	}
+	for i, s := range steps {
+		from := c.Status
+		err := Transition(&c, s.to)
+		if s.ok && err != nil {
+			t.Fatalf("step %d: unexpected error: %v", i, err)
+		}
+		if !s.ok {
+			var te *TransitionError
+			if !errors.As(err, &te) || !errors.Is(err, ErrInvalidTransition) {
+				t.Fatalf("step %d: expected a transition error, got %v", i, err)
+			}
+			if c.Status != from {
+				t.Fatalf("step %d: rejected transition changed the status to %s", i, c.Status)
+			}
+		}
+	}
+	if c.Cancelled == nil || c.Completed != nil {
+		t.Fatalf("expected only the cancelled timestamp, got completed %v cancelled %v", c.Completed, c.Cancelled)
+	}
+}
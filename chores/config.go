@@ -1,5 +1,18 @@
 package chores
 
 type Config struct {
-	OversampleRatio float64 `mapstructure:"oversampleratio"`
+	OversampleRatio      float64 `mapstructure:"oversampleratio"`
+	FairnessPolicy       string  `mapstructure:"fairnesspolicy"`
+	FairnessThresholdPct float64 `mapstructure:"fairnessthresholdpct"`
+	// Maximum number of open (assigned or acked) chores per user, 0 means unlimited.
+	MaxOpenAssignments        uint            `mapstructure:"maxopenassignments"`
+	MaxOpenAssignmentsPerUser map[string]uint `mapstructure:"maxopenassignmentsperuser"`
+	// How far back shared work counts against pairing the same people again in team mode.
+	TeamRotationHours uint `mapstructure:"teamrotationhours"`
+	// How many minutes of normalized load a liked (or disliked) category or skill is worth in the ranking,
+	// users further apart in load are ranked by load alone.
+	PreferenceWeightMin float64 `mapstructure:"preferenceweightmin"`
+	// How much every refusal or timeout raises the bounty multiplier of a chore (0 disables bounties) and its maximum.
+	BountyStep float64 `mapstructure:"bountystep"`
+	BountyMax  float64 `mapstructure:"bountymax"`
 }
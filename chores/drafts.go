@@ -0,0 +1,8 @@
+package chores
+
+import "errors"
+
+var (
+	ErrChorePublished = errors.New("the chore is already published")
+	ErrChoreDraft     = errors.New("the chore is not published yet")
+)
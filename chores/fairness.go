@@ -0,0 +1,83 @@
+package chores
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/gdg-garage/garage-trip-chores/storage"
+)
+
+const (
+	FairnessOff     = "off"
+	FairnessBlock   = "block"
+	FairnessConfirm = "confirm"
+	FairnessNudge   = "nudge"
+)
+
+var (
+	ErrAckRefused           = errors.New("ack refused, you already worked much more than the others")
+	ErrAckNeedsConfirmation = errors.New("ack needs confirmation, you already worked much more than the others")
+)
+
+type FairnessVerdict struct {
+	Policy      string
+	Exceeded    bool
+	Load        float64
+	Median      float64
+	UnderLoaded []string // Present users below the median, least loaded first.
+}
+
+func Median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64{}, values...)
+	sort.Float64s(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
+
+// CheckFairness compares the normalised load of the user with the median of the present users.
+func (cl ChoresLogic) CheckFairness(users []storage.User, userId string) (FairnessVerdict, error) {
+	verdict := FairnessVerdict{Policy: cl.config.FairnessPolicy}
+	if verdict.Policy == "" || verdict.Policy == FairnessOff {
+		return verdict, nil
+	}
+
+	stats, err := cl.storage.GetTotalNormalizedChoreStats()
+	if err != nil {
+		return verdict, err
+	}
+
+	loads := map[string]float64{}
+	values := []float64{}
+	for _, u := range users {
+		loads[u.DiscordId] = stats[u.DiscordId].TotalMin
+		values = append(values, loads[u.DiscordId])
+	}
+	// There is nobody to compare with.
+	if len(values) < 2 {
+		return verdict, nil
+	}
+
+	verdict.Load = stats[userId].TotalMin
+	verdict.Median = Median(values)
+	// At the start of the trip most users have no work yet, any work would be over the median.
+	if verdict.Median == 0 {
+		return verdict, nil
+	}
+	verdict.Exceeded = verdict.Load > verdict.Median && verdict.Load > verdict.Median*(1+cl.config.FairnessThresholdPct/100)
+
+	for user, load := range loads {
+		if user != userId && load < verdict.Median {
+			verdict.UnderLoaded = append(verdict.UnderLoaded, user)
+		}
+	}
+	sort.Slice(verdict.UnderLoaded, func(i, j int) bool {
+		return loads[verdict.UnderLoaded[i]] < loads[verdict.UnderLoaded[j]]
+	})
+	return verdict, nil
+}
@@ -0,0 +1,64 @@
+package chores
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/gdg-garage/garage-trip-chores/storage"
+)
+
+var ErrInvalidTransition = errors.New("invalid chore status transition")
+
+// TransitionError is returned for a status change the chore lifecycle does not allow.
+type TransitionError struct {
+	ChoreId uint
+	From    storage.ChoreStatus
+	To      storage.ChoreStatus
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("chore `id: %d` cannot go from %s to %s", e.ChoreId, e.From, e.To)
+}
+
+func (e *TransitionError) Unwrap() error {
+	return ErrInvalidTransition
+}
+
+// transitions lists the allowed moves.
+var transitions = map[storage.ChoreStatus][]storage.ChoreStatus{
+	storage.ChoreDraft:      {storage.ChoreOpen, storage.ChoreCancelled},
+	storage.ChoreOpen:       {storage.ChoreInProgress, storage.ChoreDone, storage.ChoreCancelled},
+	storage.ChoreInProgress: {storage.ChoreOpen, storage.ChoreDone, storage.ChoreCancelled},
+	storage.ChoreDone:       {storage.ChoreReopened},
+	storage.ChoreCancelled:  {storage.ChoreReopened},
+	storage.ChoreReopened:   {storage.ChoreOpen, storage.ChoreInProgress, storage.ChoreDone, storage.ChoreCancelled},
+}
+
+// repeatable statuses can be entered again, e.g. by the second ack of a chore in progress.
+var repeatable = []storage.ChoreStatus{storage.ChoreDraft, storage.ChoreOpen, storage.ChoreInProgress}
+
+// Transition moves the chore to the given status and keeps its timestamps in sync,
+// an illegal move returns a *TransitionError and leaves the chore untouched.
+func Transition(c *storage.Chore, to storage.ChoreStatus) error {
+	from := c.Status
+	if from == "" {
+		from = storage.ChoreDraft
+	}
+	repeated := from == to && slices.Contains(repeatable, to)
+	if !repeated && !slices.Contains(transitions[from], to) {
+		return &TransitionError{ChoreId: c.ID, From: from, To: to}
+	}
+
+	c.Status = to
+	switch to {
+	case storage.ChoreDone:
+		c.Complete()
+	case storage.ChoreCancelled:
+		c.Cancel()
+	case storage.ChoreReopened:
+		c.Completed = nil
+		c.Cancelled = nil
+	}
+	return nil
+}
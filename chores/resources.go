@@ -0,0 +1,26 @@
+package chores
+
+import (
+	"github.com/gdg-garage/garage-trip-chores/storage"
+)
+
+var ErrResourceLocked = storage.ErrResourceLocked
+
+// ResourceConflicts returns the chore's required resources which are locked by other chores, with the holding chore ID.
+func (cl ChoresLogic) ResourceConflicts(chore storage.Chore) (map[string]uint, error) {
+	conflicts := map[string]uint{}
+	if len(chore.GetResources()) == 0 {
+		return conflicts, nil
+	}
+	locks, err := cl.storage.GetResourceLocks()
+	if err != nil {
+		cl.logger.Error("failed to get resource locks", "error", err)
+		return nil, err
+	}
+	for _, res := range chore.GetResources() {
+		if holder, ok := locks[res]; ok && holder != chore.ID {
+			conflicts[res] = holder
+		}
+	}
+	return conflicts, nil
+}
@@ -0,0 +1,27 @@
+package chores
+
+import (
+	"slices"
+
+	"github.com/gdg-garage/garage-trip-chores/storage"
+)
+
+// SelectShiftWorkers picks the `n` least loaded users for a shift, the excluded users (busy or away) are skipped.
+func (cl ChoresLogic) SelectShiftWorkers(users []storage.User, n int, exclude []string) ([]string, error) {
+	userTotalStats, err := cl.storage.GetTotalNormalizedChoreStats()
+	if err != nil {
+		cl.logger.Error("failed to get chore stats", "error", err)
+		return nil, err
+	}
+
+	candidates := map[string]storage.ChoreStatsWithCapabilities{}
+	for _, user := range users {
+		if slices.Contains(exclude, user.DiscordId) {
+			continue
+		}
+		candidates[user.DiscordId] = storage.ChoreStatsWithCapabilities{ChoreStats: userTotalStats[user.DiscordId]}
+	}
+
+	sortedUsers := SortUsersBasedOnChoreStats(candidates)
+	return sortedUsers[:min(n, len(sortedUsers))], nil
+}
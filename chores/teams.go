@@ -0,0 +1,105 @@
+package chores
+
+import (
+	"slices"
+	"time"
+
+	"github.com/gdg-garage/garage-trip-chores/storage"
+)
+
+type teamScore struct {
+	missingSkills  int // Required skills nobody in the team has.
+	missingTrainee int // 1 when a training chore has no trainee.
+	cowork         int // How many times the members worked together recently.
+	load           int // Sum of the members' positions in the load ordering.
+}
+
+func (s teamScore) less(o teamScore) bool {
+	if s.missingSkills != o.missingSkills {
+		return s.missingSkills < o.missingSkills
+	}
+	if s.missingTrainee != o.missingTrainee {
+		return s.missingTrainee < o.missingTrainee
+	}
+	if s.cowork != o.cowork {
+		return s.cowork < o.cowork
+	}
+	return s.load < o.load
+}
+
+// IsMentor reports whether the user has all the skills the chore requires.
+func IsMentor(capabilities []string, chore storage.Chore) bool {
+	required := chore.GetCapabilities()
+	return len(required) > 0 && len(sliceIntersect(capabilities, required)) == len(required)
+}
+
+// selectTeam picks `size` users from the candidates (ordered least loaded first) which together with the members
+// already on the team cover the required skills, contain a trainee in training mode and worked together the least recently.
+// The team is built greedily, one best scoring member at a time, so the cost grows linearly with the team size.
+func (cl ChoresLogic) selectTeam(chore storage.Chore, candidates []string, members []string, capabilities map[string][]string, size int) ([]string, error) {
+	if size <= 0 || len(candidates) == 0 {
+		return []string{}, nil
+	}
+	size = min(size, len(candidates))
+
+	since := time.Now().Add(-time.Duration(cl.config.TeamRotationHours) * time.Hour)
+	cowork, err := cl.storage.GetCoworkCounts(since)
+	if err != nil {
+		cl.logger.Error("failed to get cowork counts", "error", err)
+		return nil, err
+	}
+
+	team := make([]string, 0, size)
+	load := 0
+	for len(team) < size {
+		best := -1
+		var bestScore teamScore
+		for i, c := range candidates {
+			if slices.Contains(team, c) {
+				continue
+			}
+			score := scoreTeam(chore, append(append(slices.Clone(members), team...), c), capabilities, cowork)
+			score.load = load + i
+			if best == -1 || score.less(bestScore) {
+				best = i
+				bestScore = score
+			}
+		}
+		team = append(team, candidates[best])
+		load += best
+	}
+	return team, nil
+}
+
+func scoreTeam(chore storage.Chore, team []string, capabilities map[string][]string, cowork map[string]map[string]int) teamScore {
+	score := teamScore{}
+	for _, skill := range chore.GetCapabilities() {
+		covered := false
+		for _, u := range team {
+			if slices.Contains(capabilities[u], skill) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			score.missingSkills++
+		}
+	}
+
+	if chore.TrainingMode && len(chore.GetCapabilities()) > 0 {
+		score.missingTrainee = 1
+		for _, u := range team {
+			if !IsMentor(capabilities[u], chore) {
+				score.missingTrainee = 0
+				break
+			}
+		}
+	}
+
+	for i := range team {
+		for j := i + 1; j < len(team); j++ {
+			score.cowork += cowork[team[i]][team[j]]
+		}
+	}
+	return score
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/gdg-garage/garage-trip-chores/config"
+	"github.com/gdg-garage/garage-trip-chores/storage"
+)
+
+const apiKeyUsage = `Usage:
+  garage-trip-chores apikey create -name NAME -scopes read,write [-user DISCORD_ID]
+  garage-trip-chores apikey list
+  garage-trip-chores apikey revoke ID
+`
+
+// runApiKeyCommand manages the API keys from the command line, e.g. to create the first admin key.
+func runApiKeyCommand(conf *config.Config, logger *slog.Logger, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, apiKeyUsage)
+		return 2
+	}
+
+	// The keys live in the database only, there is no need to connect to Discord.
+	dbConf := conf.Db
+	dbConf.DiscordToken = ""
+	s, err := storage.New(dbConf, logger)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error initializing storage:", err)
+		return 1
+	}
+
+	switch args[0] {
+	case "create":
+		fs := flag.NewFlagSet("apikey create", flag.ContinueOnError)
+		name := fs.String("name", "", "name of the key")
+		scopes := fs.String("scopes", storage.ScopeRead, "comma separated scopes: "+strings.Join(storage.ApiKeyScopes, ", "))
+		user := fs.String("user", "", "Discord ID of the user the key acts as")
+		if err := fs.Parse(args[1:]); err != nil {
+			return 2
+		}
+		if *name == "" {
+			fmt.Fprintln(os.Stderr, "The key needs a -name.")
+			return 2
+		}
+		key := storage.ApiKey{Name: *name, UserId: *user}
+		key.SetScopes(strings.Split(*scopes, ","))
+		key, secret, err := s.CreateApiKey(key)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error creating API key:", err)
+			return 1
+		}
+		fmt.Printf("Created API key %d %q with scopes %s.\n", key.ID, key.Name, key.Scopes)
+		fmt.Println("Store the key now, it is not shown again:")
+		fmt.Println(secret)
+
+	case "list":
+		keys, err := s.GetApiKeys()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error listing API keys:", err)
+			return 1
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tNAME\tHINT\tSCOPES\tUSER\tLAST USED\tREVOKED")
+		for _, k := range keys {
+			fmt.Fprintf(w, "%d\t%s\t%s…\t%s\t%s\t%s\t%s\n", k.ID, k.Name, k.Hint, k.Scopes, k.UserId, formatCliTime(k.LastUsed), formatCliTime(k.Revoked))
+		}
+		w.Flush()
+
+	case "revoke":
+		if len(args) != 2 {
+			fmt.Fprint(os.Stderr, apiKeyUsage)
+			return 2
+		}
+		id, err := strconv.ParseUint(args[1], 10, 64)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid key ID %q.\n", args[1])
+			return 2
+		}
+		key, err := s.RevokeApiKey(uint(id))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error revoking API key:", err)
+			return 1
+		}
+		fmt.Printf("Revoked API key %d %q.\n", key.ID, key.Name)
+
+	default:
+		fmt.Fprint(os.Stderr, apiKeyUsage)
+		return 2
+	}
+	return 0
+}
+
+func formatCliTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Format(time.RFC3339)
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/gdg-garage/garage-trip-chores/reminders"
 	"github.com/gdg-garage/garage-trip-chores/storage"
 	"github.com/gdg-garage/garage-trip-chores/ui"
+	"github.com/gdg-garage/garage-trip-chores/webhooks"
 	"github.com/spf13/viper"
 )
 
@@ -24,6 +25,7 @@ type Config struct {
 	Reminder reminders.Config
 	Api      api.Config
 	LLM      llm.Config
+	Webhooks webhooks.Config
 }
 
 func New() (*Config, error) {
@@ -35,8 +37,17 @@ func New() (*Config, error) {
 	viper.SetDefault("db.discordguildid", "???")
 	viper.SetDefault("db.presentrole", "chores::present")
 	viper.SetDefault("db.skillprefix", "skill::")
+	viper.SetDefault("db.timezone", "Local")
+	viper.SetDefault("db.eventretentionhours", 7*24)
 
 	viper.SetDefault("chores.oversampleratio", 0.5)
+	viper.SetDefault("chores.fairnesspolicy", "off")
+	viper.SetDefault("chores.fairnessthresholdpct", 50)
+	viper.SetDefault("chores.maxopenassignments", 0)
+	viper.SetDefault("chores.teamrotationhours", 48)
+	viper.SetDefault("chores.preferenceweightmin", 30)
+	viper.SetDefault("chores.bountystep", 0.25)
+	viper.SetDefault("chores.bountymax", 3)
 
 	viper.SetDefault("ui.discordchannelid", "???")
 
@@ -44,11 +55,28 @@ func New() (*Config, error) {
 
 	viper.SetDefault("reminder.checkperiodseconds", 2)
 	viper.SetDefault("reminder.reminderatio", 0.1)
+	viper.SetDefault("reminder.departuregracemin", 30)
+	viper.SetDefault("reminder.shiftremindermin", 30)
 
 	viper.SetDefault("api.port", 8080)
 	viper.SetDefault("api.host", "0.0.0.0")
 	viper.SetDefault("api.cors", true)
 	viper.SetDefault("api.apikeys", []string{})
+	viper.SetDefault("api.idempotencyhours", 24)
+	viper.SetDefault("api.oauth.clientid", "")
+	viper.SetDefault("api.oauth.clientsecret", "")
+	viper.SetDefault("api.oauth.redirecturl", "")
+	viper.SetDefault("api.oauth.adminroles", []string{})
+	viper.SetDefault("api.oauth.authurl", "https://discord.com/oauth2/authorize")
+	viper.SetDefault("api.oauth.tokenurl", "https://discord.com/api/oauth2/token")
+	viper.SetDefault("api.oauth.apiurl", "https://discord.com/api")
+	viper.SetDefault("api.oauth.sessionhours", 72)
+	viper.SetDefault("api.oauth.successurl", "/")
+
+	viper.SetDefault("webhooks.checkperiodseconds", 2)
+	viper.SetDefault("webhooks.timeoutseconds", 10)
+	viper.SetDefault("webhooks.maxattempts", 8)
+	viper.SetDefault("webhooks.backoffseconds", 10)
 
 	viper.SetDefault("llm.apikey", "")
 	viper.SetDefault("llm.model", "gemini-3.7-flash")
@@ -136,6 +136,10 @@ func BuildPrompt(data PromptData) (string, error) {
 				status = "REFUSED"
 			} else if a.Timeouted != nil {
 				status = "TIMED OUT (ignored the assignment!)"
+			} else if a.Transferred != nil {
+				status = "Handed over to someone else"
+			} else if a.Released != nil {
+				status = "Handed back (e.g. after leaving)"
 			}
 			choreName := a.Chore.Name
 			if choreName == "" {
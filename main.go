@@ -9,6 +9,7 @@ import (
 	"syscall"
 
 	"github.com/gdg-garage/garage-trip-chores/api"
+	"github.com/gdg-garage/garage-trip-chores/backlog"
 	"github.com/gdg-garage/garage-trip-chores/chores"
 	"github.com/gdg-garage/garage-trip-chores/config"
 	"github.com/gdg-garage/garage-trip-chores/llm"
@@ -17,6 +18,7 @@ import (
 	"github.com/gdg-garage/garage-trip-chores/reminders"
 	"github.com/gdg-garage/garage-trip-chores/storage"
 	"github.com/gdg-garage/garage-trip-chores/ui"
+	"github.com/gdg-garage/garage-trip-chores/webhooks"
 	_ "time/tzdata"
 )
 
@@ -29,6 +31,10 @@ func main() {
 	logger := logger.New(conf.Logger)
 	logger.Debug("Config loaded", "conf", conf)
 
+	if len(os.Args) > 1 && os.Args[1] == "apikey" {
+		os.Exit(runApiKeyCommand(conf, logger, os.Args[2:]))
+	}
+
 	logger.Debug("Initializing storage")
 
 	s, err := storage.New(conf.Db, logger)
@@ -45,6 +51,8 @@ func main() {
 	sc := make(chan os.Signal, 1)
 	signal.Notify(sc, syscall.SIGINT, syscall.SIGTERM, syscall.SIGALRM, os.Interrupt)
 
+	go s.RunEventRetention(ctx, &wg)
+
 	uiServer := ui.NewUi(s, logger, &cl, s.GetDiscord(), conf.Ui)
 	go uiServer.Commands(ctx, &wg)
 
@@ -54,10 +62,16 @@ func main() {
 	reminder := reminders.NewReminder(s, uiServer, &cl, logger, &conf.Reminder)
 	go reminder.RunReminder(ctx, &wg)
 
+	choresBacklog := backlog.NewBacklog(s, uiServer, &cl, logger)
+	go choresBacklog.RunBacklog(ctx, &wg)
+
 	llmSummarizer := llm.NewSummarizer(s, s.GetDiscord(), logger, conf.LLM, conf.Ui.DiscordChannelId)
 	llmScheduler := llm.NewScheduler(llmSummarizer, logger, conf.LLM)
 	go llmScheduler.Run(ctx, &wg)
 
+	dispatcher := webhooks.NewDispatcher(s, logger, conf.Webhooks)
+	go dispatcher.RunDispatcher(ctx, &wg)
+
 	apiServer := api.NewApi(s, logger, &cl, uiServer, conf.Api)
 	go apiServer.Run(ctx)
 
@@ -13,6 +13,7 @@ type Tracker struct {
 	storage *storage.Storage
 	logger  *slog.Logger
 	conf    Config
+	present map[string]struct{} // Users present in the previous sample, nil before the first one.
 }
 
 func NewTracker(storage *storage.Storage, logger *slog.Logger, conf Config) *Tracker {
@@ -27,13 +28,38 @@ func (t *Tracker) track() {
 	u, err := t.storage.GetPresentUsers()
 	if err != nil {
 		t.logger.Error("Failed to get present users", "error", err)
+		return
 	}
+	present := map[string]struct{}{}
 	for _, user := range u {
+		present[user.DiscordId] = struct{}{}
 		_, err = t.storage.LogUserPresence(user.DiscordId)
 		if err != nil {
 			t.logger.Error("Failed to log user presence", "user", user.DiscordId, "error", err)
 		}
 	}
+
+	if t.present != nil {
+		for user := range present {
+			if _, ok := t.present[user]; !ok {
+				t.logger.Info("User checked in", "user", user)
+				t.storage.Events.Publish(storage.Event{
+					Type:   storage.UserCheckedIn,
+					UserId: user,
+				})
+			}
+		}
+		for user := range t.present {
+			if _, ok := present[user]; !ok {
+				t.logger.Info("User left", "user", user)
+				t.storage.Events.Publish(storage.Event{
+					Type:   storage.UserLeft,
+					UserId: user,
+				})
+			}
+		}
+	}
+	t.present = present
 }
 
 func (t *Tracker) RunTracker(ctx context.Context, wg *sync.WaitGroup) {
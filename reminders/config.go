@@ -3,4 +3,7 @@ package reminders
 type Config struct {
 	CheckPeriodSeconds int     `mapstructure:"checkperiodseconds"`
 	ReminderRatio      float64 `mapstructure:"reminderatio"`
+	DepartureGraceMin  int     `mapstructure:"departuregracemin"`
+	// How many minutes before a shift starts its holders are reminded.
+	ShiftReminderMin int `mapstructure:"shiftremindermin"`
 }
@@ -2,6 +2,7 @@ package reminders
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
@@ -20,6 +21,14 @@ type Reminder struct {
 	chores  *chores.ChoresLogic
 	logger  *slog.Logger
 	conf    *Config
+	// DMs waiting for the end of the recipient's quiet hours or away block, only touched by the reminder loop.
+	deferred []deferredDM
+}
+
+type deferredDM struct {
+	userId  string
+	until   time.Time
+	message *discordgo.MessageSend
 }
 
 func NewReminder(storage *storage.Storage, ui *ui.Ui, chores *chores.ChoresLogic, logger *slog.Logger, conf *Config) *Reminder {
@@ -32,7 +41,40 @@ func NewReminder(storage *storage.Storage, ui *ui.Ui, chores *chores.ChoresLogic
 	}
 }
 
+// sendDM sends the DM right away or defers it until the user is available.
+func (r *Reminder) sendDM(userId string, message *discordgo.MessageSend) {
+	until, unavailable, err := r.storage.UnavailableUntil(userId, time.Now())
+	if err != nil {
+		r.logger.Error("Error getting user availability", "error", err, "user_id", userId)
+	}
+	if unavailable {
+		r.logger.Debug("Deferring DM", "user_id", userId, "until", until)
+		r.deferred = append(r.deferred, deferredDM{userId: userId, until: until, message: message})
+		return
+	}
+	r.ui.SendDM(userId, message)
+}
+
+func (r *Reminder) sendDeferredDMs() {
+	due := []deferredDM{}
+	waiting := []deferredDM{}
+	for _, dm := range r.deferred {
+		if time.Now().Before(dm.until) {
+			waiting = append(waiting, dm)
+		} else {
+			due = append(due, dm)
+		}
+	}
+	r.deferred = waiting
+	for _, dm := range due {
+		// The user might have become unavailable again in the meantime.
+		r.sendDM(dm.userId, dm.message)
+	}
+}
+
 func (r *Reminder) CheckChores() {
+	r.sendDeferredDMs()
+
 	chores, err := r.storage.GetUnfinishedChores()
 	if err != nil {
 		r.logger.Error("Error getting unfinished chores", "error", err)
@@ -40,8 +82,23 @@ func (r *Reminder) CheckChores() {
 	}
 
 	for _, chore := range chores {
+		if chore.Status == storage.ChoreDraft {
+			// Drafts have no assignments and nobody to remind yet.
+			continue
+		}
+		if chore.AuctionOpen() {
+			if time.Now().After(*chore.AuctionUntil) {
+				_, _, err = r.ui.CloseAuction(chore.ID)
+				if err != nil {
+					r.logger.Error("Error closing auction", "error", err, "chore_id", chore.ID)
+				}
+			}
+			// Nobody is assigned while the bidding is open.
+			continue
+		}
+
 		if chore.Deadline != nil && chore.Deadline.Before(time.Now()) && !chore.AfterDeadlineReminded {
-			r.ui.SendDM(chore.CreatorId, &discordgo.MessageSend{
+			r.sendDM(chore.CreatorId, &discordgo.MessageSend{
 				Content: fmt.Sprintf("Your chore `id: %d` is after its deadline %s.", chore.ID, r.ui.GetChoreMessageUrl(chore)),
 				Components: []discordgo.MessageComponent{
 					discordgo.ActionsRow{
@@ -60,8 +117,10 @@ func (r *Reminder) CheckChores() {
 					},
 				},
 			})
-			chore.AfterDeadlineReminded = true
-			_, err = r.storage.SaveChore(chore)
+			_, err = r.storage.UpdateChore(chore.ID, func(c *storage.Chore) error {
+				c.AfterDeadlineReminded = true
+				return nil
+			})
 			if err != nil {
 				r.logger.Error("Error saving chore", "error", err)
 			}
@@ -76,7 +135,7 @@ func (r *Reminder) CheckChores() {
 		for _, a := range ass {
 			// Send DM that chore is after deadline.
 			if chore.Deadline != nil && chore.Deadline.Before(time.Now()) && !a.AfterDeadlineReminded && a.Acked != nil {
-				r.ui.SendDM(a.UserId, &discordgo.MessageSend{
+				r.sendDM(a.UserId, &discordgo.MessageSend{
 					Content: fmt.Sprintf("Your assigned chore `id: %d` is after its deadline %s.", chore.ID, r.ui.GetChoreMessageUrl(chore)),
 					Components: []discordgo.MessageComponent{
 						discordgo.ActionsRow{
@@ -90,10 +149,12 @@ func (r *Reminder) CheckChores() {
 						},
 					},
 				})
-				a.AfterDeadlineReminded = true
-				// No need to remind twice.
-				a.DeadlineReminded = true
-				_, err = r.storage.SaveChoreAssignment(a)
+				_, err = r.storage.UpdateChoreAssignment(a.ID, func(a *storage.ChoreAssignment) error {
+					a.AfterDeadlineReminded = true
+					// No need to remind twice.
+					a.DeadlineReminded = true
+					return nil
+				})
 				if err != nil {
 					r.logger.Error("Error saving chore assignment", "error", err)
 				}
@@ -102,7 +163,7 @@ func (r *Reminder) CheckChores() {
 			// Send DM that chore is near deadline.
 			if chore.Deadline != nil && !a.DeadlineReminded && a.Acked != nil {
 				if time.Until(*chore.Deadline) < time.Duration(float64(chore.Deadline.Sub(chore.Created))*r.conf.ReminderRatio) {
-					r.ui.SendDM(a.UserId, &discordgo.MessageSend{
+					r.sendDM(a.UserId, &discordgo.MessageSend{
 						Content: fmt.Sprintf("Your assigned chore `id: %d` is nearing its deadline %s.", chore.ID, r.ui.GetChoreMessageUrl(chore)),
 						Components: []discordgo.MessageComponent{
 							discordgo.ActionsRow{
@@ -116,33 +177,53 @@ func (r *Reminder) CheckChores() {
 							},
 						},
 					})
-					a.DeadlineReminded = true
-					_, err = r.storage.SaveChoreAssignment(a)
+					_, err = r.storage.UpdateChoreAssignment(a.ID, func(a *storage.ChoreAssignment) error {
+						a.DeadlineReminded = true
+						return nil
+					})
 					if err != nil {
 						r.logger.Error("Error saving chore assignment", "error", err)
 					}
 				}
 			}
 
+			// Release the acked chore of a departed user who did not answer in time.
+			if a.Acked != nil && a.ReleaseRequested != nil && time.Since(*a.ReleaseRequested) > time.Duration(r.conf.DepartureGraceMin)*time.Minute {
+				_, err = r.ui.ReleaseChore(chore.ID, a.UserId)
+				if err != nil {
+					r.logger.Error("Error releasing chore", "error", err, "chore_id", chore.ID, "user_id", a.UserId)
+					continue
+				}
+				r.sendDM(a.UserId, &discordgo.MessageSend{
+					Content: fmt.Sprintf("Your chore `id: %d` was handed back to others since you left %s.", chore.ID, r.ui.GetChoreMessageUrl(chore)),
+				})
+				continue
+			}
+
 			// Re-assignments disabled.
 			if chore.AssignmentTimeoutMin == 0 {
 				continue
 			}
 
-			if a.Acked != nil || a.Refused != nil || a.Timeouted != nil {
+			if a.Acked != nil || !a.Active() {
 				continue
 			}
 
 			// reschedule expired assignment
 			if time.Until(a.Created.Add(time.Duration(chore.AssignmentTimeoutMin)*time.Minute)) < 0 {
-				r.ui.SendDM(a.UserId, &discordgo.MessageSend{
-					Content: fmt.Sprintf("Your assignment for chore `id: %d` expired %s.", chore.ID, r.ui.GetChoreMessageUrl(chore)),
-				})
-				a.Timeout()
-				_, err = r.storage.SaveChoreAssignment(a)
+				// The user might have acked meanwhile, the timeout only applies to a pending assignment.
+				_, err = r.storage.UpdateChoreAssignment(a.ID, storage.TimeoutPending)
+				if errors.Is(err, storage.ErrAssignmentInactive) {
+					continue
+				}
 				if err != nil {
 					r.logger.Error("Error saving chore assignment", "error", err)
+					continue
 				}
+				r.sendDM(a.UserId, &discordgo.MessageSend{
+					Content: fmt.Sprintf("Your assignment for chore `id: %d` expired %s.", chore.ID, r.ui.GetChoreMessageUrl(chore)),
+				})
+				chore, _ = r.chores.BumpBounty(chore)
 				users, err := r.storage.GetPresentUsers()
 				if err != nil {
 					r.logger.Error("Error getting present users", "error", err)
@@ -161,11 +242,13 @@ func (r *Reminder) CheckChores() {
 			// send DM that assignment is going to expire
 			if !a.Reminded {
 				if time.Until(a.Created.Add(time.Duration(chore.AssignmentTimeoutMin)*time.Minute)) < time.Duration(float64(time.Duration(chore.AssignmentTimeoutMin)*time.Minute)*r.conf.ReminderRatio) {
-					r.ui.SendDM(a.UserId, &discordgo.MessageSend{
+					r.sendDM(a.UserId, &discordgo.MessageSend{
 						Content: fmt.Sprintf("Your assignment for chore `id: %d` is about to expire. Please ack it %s.", chore.ID, r.ui.GetChoreMessageUrl(chore)),
 					})
-					a.Reminded = true
-					_, err = r.storage.SaveChoreAssignment(a)
+					_, err = r.storage.UpdateChoreAssignment(a.ID, func(a *storage.ChoreAssignment) error {
+						a.Reminded = true
+						return nil
+					})
 					if err != nil {
 						r.logger.Error("Error saving chore assignment", "error", err)
 					}
@@ -176,17 +259,119 @@ func (r *Reminder) CheckChores() {
 	}
 }
 
+// HandleDeparture times out pending assignments of a user who left and asks them about the acked ones.
+func (r *Reminder) HandleDeparture(userId string) {
+	ass, err := r.storage.GetOpenAssignmentsForUser(userId)
+	if err != nil {
+		r.logger.Error("Error getting open assignments", "error", err, "user_id", userId)
+		return
+	}
+
+	var users []storage.User
+	for _, a := range ass {
+		chore := a.Chore
+		if a.Acked == nil {
+			_, err = r.storage.UpdateChoreAssignment(a.ID, storage.ReleasePending)
+			if errors.Is(err, storage.ErrAssignmentInactive) {
+				continue
+			}
+			if err != nil {
+				r.logger.Error("Error saving chore assignment", "error", err)
+				continue
+			}
+			r.sendDM(a.UserId, &discordgo.MessageSend{
+				Content: fmt.Sprintf("You left, so your assignment for chore `id: %d` was given to someone else %s.", chore.ID, r.ui.GetChoreMessageUrl(chore)),
+			})
+			if users == nil {
+				users, err = r.storage.GetPresentUsers()
+				if err != nil {
+					r.logger.Error("Error getting present users", "error", err)
+					return
+				}
+			}
+			_, err = r.chores.AssignChoresToUsers(users, chore)
+			if err != nil {
+				r.logger.Error("Error assigning chores to users", "error", err)
+			}
+			r.ui.UpdateChoreMessage(chore)
+			r.ui.EmitChoreEvent("chore_reassigned", chore)
+			continue
+		}
+
+		if a.ReleaseRequested != nil {
+			continue
+		}
+		_, err = r.storage.UpdateChoreAssignment(a.ID, func(a *storage.ChoreAssignment) error {
+			now := time.Now()
+			a.ReleaseRequested = &now
+			return nil
+		})
+		if err != nil {
+			r.logger.Error("Error saving chore assignment", "error", err)
+			continue
+		}
+		r.sendDM(a.UserId, &discordgo.MessageSend{
+			Content: fmt.Sprintf("You left while holding chore `id: %d` `%s` %s. Do you want to hand it back? It will be handed back automatically in %d minutes.", chore.ID, chore.Name, r.ui.GetChoreMessageUrl(chore), r.conf.DepartureGraceMin),
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{
+					Components: []discordgo.MessageComponent{
+						&discordgo.Button{
+							Style:    discordgo.DangerButton,
+							Label:    "Hand it back",
+							CustomID: ui.ReleaseButtonClick + fmt.Sprint(chore.ID),
+						},
+						&discordgo.Button{
+							Style:    discordgo.SuccessButton,
+							Label:    "I'll still do it",
+							CustomID: ui.KeepButtonClick + fmt.Sprint(chore.ID),
+						},
+					},
+				},
+			},
+		})
+	}
+}
+
+// CheckShifts reminds the holders of shifts starting soon.
+func (r *Reminder) CheckShifts() {
+	shifts, err := r.storage.GetShiftsToRemind(time.Now().Add(time.Duration(r.conf.ShiftReminderMin) * time.Minute))
+	if err != nil {
+		r.logger.Error("Error getting shifts to remind", "error", err)
+		return
+	}
+	for _, shift := range shifts {
+		r.sendDM(shift.UserId, &discordgo.MessageSend{
+			Content: fmt.Sprintf("Your `%s` duty starts soon (%s).", shift.Duty.Name, shift.Start.In(r.storage.Location()).Format(time.RFC822)),
+		})
+		shift.Reminded = true
+		_, err = r.storage.SaveShift(shift)
+		if err != nil {
+			r.logger.Error("Error saving shift", "error", err, "shift_id", shift.ID)
+		}
+	}
+}
+
 func (r *Reminder) RunReminder(ctx context.Context, wg *sync.WaitGroup) {
 	wg.Add(1)
 	defer wg.Done()
+	sub := r.storage.Events.Subscribe()
+	defer r.storage.Events.Unsubscribe(sub)
+	// A ticker keeps the checks periodic even when events keep coming.
+	ticker := time.NewTicker(time.Duration(r.conf.CheckPeriodSeconds) * time.Second)
+	defer ticker.Stop()
 	for {
-		timer := time.NewTimer(time.Duration(r.conf.CheckPeriodSeconds) * time.Second)
 		select {
 		case <-ctx.Done():
 			r.logger.Debug("Reminder stopped: context cancelled", "reason", ctx.Err())
 			return
-		case <-timer.C:
+		case <-ticker.C:
+			r.ui.PublishDueDrafts()
 			r.CheckChores()
+			r.CheckShifts()
+		case event := <-sub:
+			if event.Type == storage.UserLeft {
+				r.HandleDeparture(event.UserId)
+			}
 		}
 	}
 }
@@ -0,0 +1,77 @@
+package reminders
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/gdg-garage/garage-trip-chores/chores"
+	"github.com/gdg-garage/garage-trip-chores/storage"
+	"github.com/gdg-garage/garage-trip-chores/ui"
+)
+
+func setupReminder(t *testing.T) (*Reminder, *ui.Ui, *storage.Storage) {
+	t.Helper()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	s, err := storage.New(storage.Config{DbPath: t.TempDir() + "/test.sqlite", Timezone: "UTC"}, logger)
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	cl := chores.NewChoresLogic(s, logger, chores.Config{})
+	u := ui.NewUi(s, logger, &cl, nil, ui.Config{})
+	return NewReminder(s, u, &cl, logger, &Config{ReminderRatio: 0.5, DepartureGraceMin: 30}), u, s
+}
+
+func TestHandleDeparture(t *testing.T) {
+	r, u, s := setupReminder(t)
+
+	pending, _ := s.SaveChore(storage.Chore{Name: "Sweep the floor", NecessaryWorkers: 1, AssignmentTimeoutMin: 15, Status: storage.ChoreOpen, Created: time.Now()})
+	acked, _ := s.SaveChore(storage.Chore{Name: "Cook dinner", NecessaryWorkers: 1, AssignmentTimeoutMin: 15, Status: storage.ChoreOpen, Created: time.Now()})
+	if _, _, err := u.AssignUsers(pending.ID, []string{"u1"}); err != nil {
+		t.Fatalf("Failed to assign chore: %v", err)
+	}
+	if _, _, err := u.AckChore(acked.ID, "u1"); err != nil {
+		t.Fatalf("Failed to ack chore: %v", err)
+	}
+
+	r.HandleDeparture("u1")
+
+	// The pending assignment is released right away and the chore waits for somebody else in the backlog.
+	a, err := s.GetChoreAssignment(pending.ID, "u1")
+	if err != nil || a.Released == nil {
+		t.Fatalf("Expected the pending assignment to be released, got %+v, %v", a, err)
+	}
+	c, _ := s.GetChore(pending.ID)
+	if !c.Understaffed {
+		t.Fatalf("Expected the released chore in the backlog")
+	}
+
+	// The acked chore is kept until the grace period runs out.
+	a, err = s.GetChoreAssignment(acked.ID, "u1")
+	if err != nil || !a.Active() || a.ReleaseRequested == nil {
+		t.Fatalf("Expected the acked assignment to be kept and asked about, got %+v, %v", a, err)
+	}
+	requested := *a.ReleaseRequested
+	r.HandleDeparture("u1")
+	a, _ = s.GetChoreAssignment(acked.ID, "u1")
+	if !a.ReleaseRequested.Equal(requested) {
+		t.Fatalf("Expected a second departure to keep the grace period, got %v", a.ReleaseRequested)
+	}
+	r.CheckChores()
+	if a, _ = s.GetChoreAssignment(acked.ID, "u1"); !a.Active() {
+		t.Fatalf("Expected the acked assignment to be kept during the grace period")
+	}
+
+	s.UpdateChoreAssignment(a.ID, func(a *storage.ChoreAssignment) error {
+		past := time.Now().Add(-time.Hour)
+		a.ReleaseRequested = &past
+		return nil
+	})
+	r.CheckChores()
+	a, _ = s.GetChoreAssignment(acked.ID, "u1")
+	c, _ = s.GetChore(acked.ID)
+	if a.Active() || c.Status != storage.ChoreOpen {
+		t.Fatalf("Expected the acked chore handed back after the grace period, got %+v in %s", a, c.Status)
+	}
+}
@@ -0,0 +1,107 @@
+package storage
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+var ErrInvalidScope = errors.New("invalid API key scope")
+
+const apiKeyPrefix = "gtc_"
+
+// lastUsedPrecision limits the writes of the last used time to one per key and minute.
+const lastUsedPrecision = time.Minute
+
+// hashSecret is enough for the random secrets of keys and sessions, they cannot be guessed from a dictionary.
+func hashSecret(secret string) string {
+	h := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(h[:])
+}
+
+// CreateApiKey stores a new key with a random secret, the secret is returned only here.
+func (s *Storage) CreateApiKey(key ApiKey) (ApiKey, string, error) {
+	scopes := key.GetScopes()
+	if len(scopes) == 0 {
+		return key, "", fmt.Errorf("%w: the key needs at least one scope", ErrInvalidScope)
+	}
+	for _, sc := range scopes {
+		if !slices.Contains(ApiKeyScopes, sc) {
+			return key, "", fmt.Errorf("%w: %s (valid: %v)", ErrInvalidScope, sc, ApiKeyScopes)
+		}
+	}
+	if slices.Contains(scopes, ScopeAckAsSelf) && key.UserId == "" {
+		return key, "", fmt.Errorf("%w: %s needs a user the key is bound to", ErrInvalidScope, ScopeAckAsSelf)
+	}
+
+	secret, err := newSecret(apiKeyPrefix)
+	if err != nil {
+		return key, "", err
+	}
+	key.ID = 0
+	key.SecretHash = hashSecret(secret)
+	key.Hint = secret[:len(apiKeyPrefix)+6]
+	key.Created = time.Now()
+	key.LastUsed = nil
+	key.Revoked = nil
+	r := s.db.Create(&key)
+	return key, secret, r.Error
+}
+
+func newSecret(prefix string) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return prefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func (s *Storage) GetApiKeys() ([]ApiKey, error) {
+	var keys []ApiKey
+	r := s.db.Order("id ASC").Find(&keys)
+	return keys, r.Error
+}
+
+// RevokeApiKey disables the key for good, it is kept to show when it was used last.
+func (s *Storage) RevokeApiKey(id uint) (ApiKey, error) {
+	var key ApiKey
+	if err := s.db.First(&key, id).Error; err != nil {
+		return key, err
+	}
+	if key.Revoked == nil {
+		now := time.Now()
+		key.Revoked = &now
+		if err := s.db.Model(&key).UpdateColumn("revoked", now).Error; err != nil {
+			return key, err
+		}
+	}
+	return key, nil
+}
+
+// HasApiKeys tells whether any key which was not revoked exists.
+func (s *Storage) HasApiKeys() (bool, error) {
+	var count int64
+	r := s.db.Model(&ApiKey{}).Where("revoked IS NULL").Count(&count)
+	return count > 0, r.Error
+}
+
+// AuthenticateApiKey finds the key with the secret and records its use.
+func (s *Storage) AuthenticateApiKey(secret string) (ApiKey, error) {
+	var key ApiKey
+	if err := s.db.Where("secret_hash = ? AND revoked IS NULL", hashSecret(secret)).First(&key).Error; err != nil {
+		return key, err
+	}
+	now := time.Now()
+	if key.LastUsed == nil || now.Sub(*key.LastUsed) >= lastUsedPrecision {
+		key.LastUsed = &now
+		if err := s.db.Model(&key).UpdateColumn("last_used", now).Error; err != nil {
+			return key, err
+		}
+	}
+	return key, nil
+}
@@ -0,0 +1,221 @@
+package storage
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var ErrAssignmentInactive = errors.New("the assignment is not active anymore")
+var ErrVersionConflict = errors.New("the chore was changed meanwhile")
+
+// saveChoreStatus stores the status of the chore and raises its version.
+func saveChoreStatus(tx *gorm.DB, chore *Chore) error {
+	chore.Version++
+	return tx.Model(&Chore{}).Where("id = ?", chore.ID).UpdateColumns(map[string]any{
+		"status":  chore.Status,
+		"version": chore.Version,
+	}).Error
+}
+
+// activeAssignments limits the query to assignments which were not refused, timed out, transferred or released.
+func activeAssignments(tx *gorm.DB, choreId uint) *gorm.DB {
+	return tx.Model(&ChoreAssignment{}).
+		Where("chore_id = ? AND refused IS NULL AND timeouted IS NULL AND transferred IS NULL AND released IS NULL", choreId)
+}
+
+// TimeoutPending times out an assignment still waiting for an ack, to be used with UpdateChoreAssignment.
+func TimeoutPending(ca *ChoreAssignment) error {
+	if ca.Acked != nil || !ca.Active() {
+		return ErrAssignmentInactive
+	}
+	ca.Timeout()
+	return nil
+}
+
+// ReleasePending hands back an assignment still waiting for an ack, to be used with UpdateChoreAssignment.
+func ReleasePending(ca *ChoreAssignment) error {
+	if ca.Acked != nil || !ca.Active() {
+		return ErrAssignmentInactive
+	}
+	ca.Release()
+	return nil
+}
+
+// UpdateChore applies the change to the current state of the chore in a transaction,
+// so a stale copy never overwrites a concurrent change. An error from apply aborts the update.
+func (s *Storage) UpdateChore(choreId uint, apply func(*Chore) error) (Chore, error) {
+	var chore Chore
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&chore, choreId).Error; err != nil {
+			return err
+		}
+		if err := apply(&chore); err != nil {
+			return err
+		}
+		chore.Version++
+		return tx.Omit("Understaffed").Save(&chore).Error
+	})
+	if err != nil {
+		return chore, err
+	}
+	s.publishChore(chore, false)
+	return chore, nil
+}
+
+// UpdateChoreAssignment applies the change to the current state of the assignment in a transaction,
+// e.g. a timeout does not overwrite an ack which came in meanwhile. An error from apply aborts the update.
+func (s *Storage) UpdateChoreAssignment(id uint, apply func(*ChoreAssignment) error) (ChoreAssignment, error) {
+	var ca ChoreAssignment
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&ca, id).Error; err != nil {
+			return err
+		}
+		if err := apply(&ca); err != nil {
+			return err
+		}
+		return tx.Omit("Chore").Save(&ca).Error
+	})
+	if err != nil {
+		return ca, err
+	}
+	s.publishAssignment(ca, false)
+	return ca, nil
+}
+
+// AddChoreAssignments stores the assignments picked by the strategy unless the slots were filled meanwhile.
+// At most limit non-manual assignments are active at once, users who already have an assignment are skipped.
+func (s *Storage) AddChoreAssignments(choreId uint, assignments []ChoreAssignment, limit uint) ([]ChoreAssignment, error) {
+	added := []ChoreAssignment{}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var active int64
+		if err := activeAssignments(tx, choreId).Where("manual = ?", false).Count(&active).Error; err != nil {
+			return err
+		}
+		for _, ca := range assignments {
+			if uint(active) >= limit {
+				break
+			}
+			var existing int64
+			if err := tx.Model(&ChoreAssignment{}).Where("chore_id = ? AND user_id = ?", choreId, ca.UserId).Count(&existing).Error; err != nil {
+				return err
+			}
+			if existing > 0 {
+				continue
+			}
+			ca.ChoreId = choreId
+			if err := tx.Omit("Chore").Create(&ca).Error; err != nil {
+				return err
+			}
+			added = append(added, ca)
+			active++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, ca := range added {
+		s.publishAssignment(ca, true)
+	}
+	return added, nil
+}
+
+// AckChoreAssignment acks the user's assignment in a transaction, a user without one volunteers and gets a new one.
+// check gets the current chore to validate and update its status. Once the chore has as many acked workers
+// as it needs the user is put on its waitlist instead and a WaitlistedError is returned. A ResourceLockedError is returned
+// when other chores hold the resources the ack would lock.
+func (s *Storage) AckChoreAssignment(choreId uint, userId string, check func(*Chore) error) (Chore, ChoreAssignment, error) {
+	var chore Chore
+	var ca ChoreAssignment
+	isNew := false
+	waitlisted := 0
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&chore, choreId).Error; err != nil {
+			return err
+		}
+		if err := check(&chore); err != nil {
+			return err
+		}
+
+		r := tx.Where("chore_id = ? AND user_id = ?", choreId, userId).Limit(1).Find(&ca)
+		if r.Error != nil {
+			return r.Error
+		}
+		if r.RowsAffected > 0 && ca.Active() && ca.Acked != nil {
+			return nil
+		}
+
+		var acked int64
+		if err := activeAssignments(tx, choreId).Where("acked IS NOT NULL").Count(&acked).Error; err != nil {
+			return err
+		}
+		if uint(acked) >= max(chore.NecessaryWorkers, 1) {
+			var err error
+			waitlisted, err = joinWaitlist(tx, choreId, userId)
+			return err
+		}
+		// The resources are locked by the ack, checked in the same transaction so two chores cannot take them at once.
+		if err := checkResources(tx, chore); err != nil {
+			return err
+		}
+
+		if r.RowsAffected == 0 || !ca.Active() {
+			// Somebody who turned the chore down before volunteers with their old assignment.
+			isNew = r.RowsAffected == 0
+			ca.ChoreId = choreId
+			ca.UserId = userId
+			ca.Created = time.Now()
+			ca.Refused = nil
+			ca.Timeouted = nil
+			ca.Transferred = nil
+			ca.Released = nil
+			ca.Volunteered = true
+		}
+		ca.Ack()
+		if err := tx.Omit("Chore").Save(&ca).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("chore_id = ? AND user_id = ?", choreId, userId).Delete(&WaitlistEntry{}).Error; err != nil {
+			return err
+		}
+		return saveChoreStatus(tx, &chore)
+	})
+	if err != nil {
+		return chore, ca, err
+	}
+	if waitlisted > 0 {
+		return chore, ca, &WaitlistedError{ChoreId: choreId, Position: waitlisted}
+	}
+	ca.Chore = chore
+	if isNew {
+		s.publishAssignment(ca, true)
+	}
+	s.publishAssignment(ca, false)
+	return chore, ca, nil
+}
+
+func (s *Storage) publishAssignment(ca ChoreAssignment, isNew bool) {
+	if s.Events == nil {
+		return
+	}
+	eventType := TaskUpdated
+	if isNew {
+		eventType = TaskAssigned
+	} else if ca.Acked != nil {
+		eventType = TaskAcked
+	} else if ca.Refused != nil {
+		eventType = TaskRefused
+	} else if ca.Timeouted != nil {
+		eventType = TaskTimeout
+	} else if ca.Transferred != nil {
+		eventType = TaskTransferred
+	} else if ca.Released != nil {
+		eventType = TaskReleased
+	}
+	s.Events.Publish(Event{
+		Type:       eventType,
+		Assignment: &ca,
+	})
+}
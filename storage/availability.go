@@ -0,0 +1,135 @@
+package storage
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func (s *Storage) GetUserSettings(userId string) (UserSettings, error) {
+	settings := UserSettings{UserId: userId}
+	r := s.db.Where("user_id = ?", userId).First(&settings)
+	if r.Error == gorm.ErrRecordNotFound {
+		return settings, nil
+	}
+	return settings, r.Error
+}
+
+func (s *Storage) SaveUserSettings(settings UserSettings) (UserSettings, error) {
+	r := s.db.Save(&settings)
+	return settings, r.Error
+}
+
+// GetAllUserSettings returns the settings of all users who have any, keyed by the user ID.
+func (s *Storage) GetAllUserSettings() (map[string]UserSettings, error) {
+	var all []UserSettings
+	r := s.db.Find(&all)
+	if r.Error != nil {
+		return nil, r.Error
+	}
+	settings := map[string]UserSettings{}
+	for _, us := range all {
+		settings[us.UserId] = us
+	}
+	return settings, nil
+}
+
+// GetUserAvailabilities returns the user's unavailability blocks which did not end yet.
+func (s *Storage) GetUserAvailabilities(userId string) ([]UserAvailability, error) {
+	var blocks []UserAvailability
+	r := s.db.Where("user_id = ? AND \"to\" > ?", userId, time.Now()).Order("\"from\" ASC").Find(&blocks)
+	return blocks, r.Error
+}
+
+func (s *Storage) SaveUserAvailability(block UserAvailability) (UserAvailability, error) {
+	r := s.db.Save(&block)
+	return block, r.Error
+}
+
+// RemoveUserAvailabilities drops the user's unavailability blocks which did not end yet.
+func (s *Storage) RemoveUserAvailabilities(userId string) error {
+	r := s.db.Where("user_id = ? AND \"to\" > ?", userId, time.Now()).Delete(&UserAvailability{})
+	return r.Error
+}
+
+// Location is the timezone quiet hours and availability times are given in.
+func (s *Storage) Location() *time.Location {
+	return s.loc
+}
+
+// GetUnavailableUsers returns the users who are in their quiet hours or blocked at the given time,
+// mapped to the time they become available again.
+func (s *Storage) GetUnavailableUsers(at time.Time) (map[string]time.Time, error) {
+	unavailable := map[string]time.Time{}
+
+	var settings []UserSettings
+	r := s.db.Where("quiet_start != '' AND quiet_end != ''").Find(&settings)
+	if r.Error != nil {
+		return unavailable, r.Error
+	}
+	var blocks []UserAvailability
+	r = s.db.Where("\"to\" > ?", at).Order("\"from\" ASC").Find(&blocks)
+	if r.Error != nil {
+		return unavailable, r.Error
+	}
+
+	userSettings := map[string]UserSettings{}
+	userBlocks := map[string][]UserAvailability{}
+	for _, us := range settings {
+		userSettings[us.UserId] = us
+	}
+	for _, b := range blocks {
+		userBlocks[b.UserId] = append(userBlocks[b.UserId], b)
+	}
+
+	users := map[string]struct{}{}
+	for u := range userSettings {
+		users[u] = struct{}{}
+	}
+	for u := range userBlocks {
+		users[u] = struct{}{}
+	}
+	for u := range users {
+		if until, ok := availableAt(at, userSettings[u], userBlocks[u], s.loc); ok {
+			unavailable[u] = until
+		}
+	}
+	return unavailable, nil
+}
+
+// UnavailableUntil returns when the user becomes available again if they are not available at the given time.
+func (s *Storage) UnavailableUntil(userId string, at time.Time) (time.Time, bool, error) {
+	settings, err := s.GetUserSettings(userId)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	var blocks []UserAvailability
+	r := s.db.Where("user_id = ? AND \"to\" > ?", userId, at).Order("\"from\" ASC").Find(&blocks)
+	if r.Error != nil {
+		return time.Time{}, false, r.Error
+	}
+	until, ok := availableAt(at, settings, blocks, s.loc)
+	return until, ok, nil
+}
+
+// maxAvailabilitySteps bounds how many chained quiet hours and blocks availableAt follows.
+const maxAvailabilitySteps = 100
+
+// availableAt follows quiet hours and blocks chained one after another to the time the user is available again.
+func availableAt(at time.Time, settings UserSettings, blocks []UserAvailability, loc *time.Location) (time.Time, bool) {
+	until := at
+	for moved, steps := true, 0; moved && steps < maxAvailabilitySteps; steps++ {
+		moved = false
+		if end, ok := settings.QuietUntil(until, loc); ok {
+			until = end
+			moved = true
+		}
+		for _, b := range blocks {
+			if !until.Before(b.From) && until.Before(b.To) {
+				until = b.To
+				moved = true
+			}
+		}
+	}
+	return until, until.After(at)
+}
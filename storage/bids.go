@@ -0,0 +1,75 @@
+package storage
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SaveBid stores the user's bid on the chore, replacing their previous one.
+func (s *Storage) SaveBid(bid Bid) (Bid, error) {
+	var existing Bid
+	r := s.db.Where("chore_id = ? AND user_id = ?", bid.ChoreId, bid.UserId).First(&existing)
+	if r.Error != nil && !errors.Is(r.Error, gorm.ErrRecordNotFound) {
+		return bid, r.Error
+	}
+	bid.ID = existing.ID
+	r = s.db.Save(&bid)
+	return bid, r.Error
+}
+
+// GetBids returns the bids on the chore, lowest (and earliest among equal) first.
+func (s *Storage) GetBids(choreId uint) ([]Bid, error) {
+	var bids []Bid
+	r := s.db.Where("chore_id = ?", choreId).Order("time_min ASC").Order("created ASC").Find(&bids)
+	return bids, r.Error
+}
+
+// AwardAuction acks the assignments of the auction winners in one transaction, all of them or none.
+// pick gets the bids (lowest first) and the users already holding the chore and returns the winning bids.
+func (s *Storage) AwardAuction(choreId uint, pick func(bids []Bid, holders []string) []Bid) ([]ChoreAssignment, error) {
+	awarded := []ChoreAssignment{}
+	isNew := []bool{}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var bids []Bid
+		if err := tx.Where("chore_id = ?", choreId).Order("time_min ASC").Order("created ASC").Find(&bids).Error; err != nil {
+			return err
+		}
+		var holders []string
+		if err := activeAssignments(tx, choreId).Pluck("user_id", &holders).Error; err != nil {
+			return err
+		}
+		for _, b := range pick(bids, holders) {
+			// A winner might have turned the chore down before the auction, their assignment is reused then.
+			var ca ChoreAssignment
+			r := tx.Where("chore_id = ? AND user_id = ?", choreId, b.UserId).Limit(1).Find(&ca)
+			if r.Error != nil {
+				return r.Error
+			}
+			ca.ChoreId = choreId
+			ca.UserId = b.UserId
+			ca.Created = time.Now()
+			ca.Manual = true
+			ca.BidMin = b.TimeMin
+			// Bidding is a commitment, the winners do not have to ack.
+			ca.Ack()
+			if err := tx.Omit("Chore").Save(&ca).Error; err != nil {
+				return err
+			}
+			awarded = append(awarded, ca)
+			isNew = append(isNew, r.RowsAffected == 0)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i, ca := range awarded {
+		if isNew[i] {
+			s.publishAssignment(ca, true)
+		}
+		s.publishAssignment(ca, false)
+	}
+	return awarded, nil
+}
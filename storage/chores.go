@@ -1,23 +1,57 @@
 package storage
 
-import "gorm.io/gorm/clause"
+import (
+	"fmt"
+	"time"
 
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SaveChore stores a new chore or the whole row of a chore read before, ErrVersionConflict is returned
+// when the chore was changed since.
 func (s *Storage) SaveChore(chore Chore) (Chore, error) {
 	isNew := chore.ID == 0
-	r := s.db.Save(&chore)
-	if r.Error == nil && s.Events != nil {
-		eventType := TaskUpdated
-		if isNew {
-			eventType = TaskCreated
-		} else if chore.Completed != nil {
-			eventType = TaskDone
+	if chore.Status == "" {
+		chore.Status = ChoreDraft
+	}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// A stale copy would overwrite changes made since it was read, e.g. an ack, use UpdateChore to change a field.
+		version := uint(1)
+		if !isNew {
+			var current uint
+			if err := tx.Model(&Chore{}).Where("id = ?", chore.ID).Select("version").Scan(&current).Error; err != nil {
+				return err
+			}
+			if chore.Version != current {
+				return ErrVersionConflict
+			}
+			version = current + 1
 		}
-		s.Events.Publish(Event{
-			Type:  eventType,
-			Chore: &chore,
-		})
+		chore.Version = version
+		// Staffing is owned by the assignment logic, see SetChoreUnderstaffed.
+		return tx.Omit("Understaffed").Save(&chore).Error
+	})
+	if err == nil {
+		s.publishChore(chore, isNew)
 	}
-	return chore, r.Error
+	return chore, err
+}
+
+func (s *Storage) publishChore(chore Chore, isNew bool) {
+	if s.Events == nil {
+		return
+	}
+	eventType := TaskUpdated
+	if isNew {
+		eventType = TaskCreated
+	} else if chore.Completed != nil {
+		eventType = TaskDone
+	}
+	s.Events.Publish(Event{
+		Type:  eventType,
+		Chore: &chore,
+	})
 }
 
 func (s *Storage) GetChore(Id uint) (Chore, error) {
@@ -40,6 +74,104 @@ func (s *Storage) GetChores() ([]Chore, error) {
 	return chores, r.Error
 }
 
+// ChoreFilter narrows down and orders FindChores, zero values do not filter.
+type ChoreFilter struct {
+	Statuses     []ChoreStatus
+	Assignee     string // Only chores the user holds an active assignment for.
+	Creator      string
+	Capability   string
+	DeadlineFrom *time.Time
+	DeadlineTo   *time.Time
+	CreatedFrom  *time.Time
+	CreatedTo    *time.Time
+	Sort         string // created (default), deadline or name
+	Desc         bool
+	AfterId      uint // ID of the last chore of the previous page.
+	Limit        int
+}
+
+// noDeadline sorts the chores without a deadline after all the others.
+var noDeadline = time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC)
+
+// FindChores returns a page of the chores matching the filter. The pages are cut by the sort key
+// of the last chore of the previous page, so chores created meanwhile do not shift them.
+func (s *Storage) FindChores(f ChoreFilter) ([]Chore, error) {
+	var chores []Chore
+	q := s.db.Model(&Chore{})
+	if len(f.Statuses) > 0 {
+		q = q.Where("status IN ?", f.Statuses)
+	}
+	if f.Assignee != "" {
+		q = q.Where("id IN (?)", s.db.Model(&ChoreAssignment{}).Select("chore_id").
+			Where("user_id = ? AND refused IS NULL AND timeouted IS NULL AND transferred IS NULL AND released IS NULL", f.Assignee))
+	}
+	if f.Creator != "" {
+		q = q.Where("creator_id = ?", f.Creator)
+	}
+	if f.Capability != "" {
+		q = q.Where("(',' || necessary_capabilities || ',') LIKE ?", "%,"+f.Capability+",%")
+	}
+	if f.DeadlineFrom != nil {
+		q = q.Where("deadline >= ?", *f.DeadlineFrom)
+	}
+	if f.DeadlineTo != nil {
+		q = q.Where("deadline < ?", *f.DeadlineTo)
+	}
+	if f.CreatedFrom != nil {
+		q = q.Where("created >= ?", *f.CreatedFrom)
+	}
+	if f.CreatedTo != nil {
+		q = q.Where("created < ?", *f.CreatedTo)
+	}
+
+	key := "created"
+	sortValue := func(c Chore) any { return c.Created }
+	switch f.Sort {
+	case "deadline":
+		key = "COALESCE(deadline, ?)"
+		sortValue = func(c Chore) any {
+			if c.Deadline == nil {
+				return noDeadline
+			}
+			return *c.Deadline
+		}
+	case "name":
+		key = "name"
+		sortValue = func(c Chore) any { return c.Name }
+	}
+	keyVars := func(vars ...any) []any {
+		if f.Sort == "deadline" {
+			vars = append([]any{noDeadline}, vars...)
+		}
+		return vars
+	}
+	dir, cmp := "ASC", ">"
+	if f.Desc {
+		dir, cmp = "DESC", "<"
+	}
+
+	if f.AfterId != 0 {
+		var after Chore
+		if err := s.db.First(&after, f.AfterId).Error; err != nil {
+			return nil, err
+		}
+		v := sortValue(after)
+		cond := fmt.Sprintf("(%s %s ? OR (%s = ? AND id %s ?))", key, cmp, key, cmp)
+		vars := append(keyVars(v), keyVars(v, after.ID)...)
+		q = q.Where(cond, vars...)
+	}
+	q = q.Order(clause.OrderBy{Expression: clause.Expr{
+		SQL:                fmt.Sprintf("%s %s, id %s", key, dir, dir),
+		Vars:               keyVars(),
+		WithoutParentheses: true,
+	}})
+	if f.Limit > 0 {
+		q = q.Limit(f.Limit)
+	}
+	r := q.Find(&chores)
+	return chores, r.Error
+}
+
 func (s *Storage) GetCompletedChores() ([]Chore, error) {
 	var chores []Chore
 	r := s.db.Where("completed IS NOT NULL").Order("chores.created DESC").Find(&chores)
@@ -65,7 +197,7 @@ func (s *Storage) GetUnfinishedChores() ([]Chore, error) {
 func (s *Storage) GetAssignedChoresForUser(userId string) ([]Chore, error) {
 	var chores []Chore
 	r := s.db.Joins("JOIN chore_assignments ON chore_assignments.chore_id = chores.id").
-		Where("chore_assignments.user_id = ? AND chore_assignments.acked IS NULL AND chore_assignments.refused IS NULL AND chore_assignments.timeouted IS NULL and chores.completed IS NULL and chores.cancelled IS NULL", userId).
+		Where("chore_assignments.user_id = ? AND chore_assignments.acked IS NULL AND chore_assignments.refused IS NULL AND chore_assignments.timeouted IS NULL AND chore_assignments.transferred IS NULL AND chore_assignments.released IS NULL and chores.completed IS NULL and chores.cancelled IS NULL", userId).
 		Order("chores.created DESC").
 		Find(&chores)
 	return chores, r.Error
@@ -80,6 +212,50 @@ func (s *Storage) GetAckedChoresForUser(userId string) ([]Chore, error) {
 	return chores, r.Error
 }
 
+func (s *Storage) GetUnderstaffedChores() ([]Chore, error) {
+	var chores []Chore
+	r := s.db.Where("understaffed = ? AND completed IS NULL and cancelled IS NULL", true).Order("created ASC").Find(&chores)
+	if r.Error == nil {
+		for i := range chores {
+			chores[i].GetCapabilities()
+		}
+	}
+	return chores, r.Error
+}
+
+// GetDrafts returns the chores which are not published yet.
+func (s *Storage) GetDrafts() ([]Chore, error) {
+	var chores []Chore
+	r := s.db.Where("status = ?", ChoreDraft).Order("not_before ASC").Find(&chores)
+	return chores, r.Error
+}
+
+// GetDueDrafts returns the drafts which should be published at the given time.
+func (s *Storage) GetDueDrafts(now time.Time) ([]Chore, error) {
+	var chores []Chore
+	r := s.db.Where("status = ? AND not_before <= ?", ChoreDraft, now).Order("not_before ASC").Find(&chores)
+	return chores, r.Error
+}
+
+func (s *Storage) SetChoreUnderstaffed(choreId uint, understaffed bool) error {
+	r := s.db.Model(&Chore{}).Where("id = ?", choreId).UpdateColumn("understaffed", understaffed)
+	return r.Error
+}
+
+func (s *Storage) SetChoreBounty(choreId uint, bounty float64) error {
+	r := s.db.Model(&Chore{}).Where("id = ?", choreId).UpdateColumn("bounty", bounty)
+	return r.Error
+}
+
+// GetOpenAssignmentsForUser returns assigned or acked assignments of the user on unfinished chores.
+func (s *Storage) GetOpenAssignmentsForUser(userId string) ([]ChoreAssignment, error) {
+	var assignments []ChoreAssignment
+	r := s.db.Preload(clause.Associations).Joins("JOIN chores ON chore_assignments.chore_id = chores.id").
+		Where("chore_assignments.user_id = ? AND chore_assignments.refused IS NULL AND chore_assignments.timeouted IS NULL AND chore_assignments.transferred IS NULL AND chore_assignments.released IS NULL and chores.completed IS NULL and chores.cancelled IS NULL", userId).
+		Find(&assignments)
+	return assignments, r.Error
+}
+
 func (s *Storage) SaveWorkLog(wl WorkLog) (WorkLog, error) {
 	r := s.db.Save(&wl)
 	return wl, r.Error
@@ -97,6 +273,11 @@ func (s *Storage) GetWorkLogsForChore(choreId uint) ([]WorkLog, error) {
 	return worklogs, r.Error
 }
 
+func (s *Storage) RemoveWorkLogs(choreId uint) error {
+	r := s.db.Where("chore_id = ?", choreId).Delete(&WorkLog{})
+	return r.Error
+}
+
 func (s *Storage) GetWorkLogForChoreAndUser(choreId uint, userId string) (WorkLog, error) {
 	var worklog WorkLog
 	r := s.db.Preload(clause.Associations).Where("chore_id = ? AND user_id = ?", choreId, userId).First(&worklog)
@@ -6,4 +6,7 @@ type Config struct {
 	PresentRole    string `mapstructure:"presentrole"`
 	SkillPrefix    string `mapstructure:"skillprefix"`
 	DiscordGuildId string `mapstructure:"discordguildid"`
+	Timezone       string `mapstructure:"timezone"` // Quiet hours are interpreted in this IANA zone.
+	// How long the event log keeps the events for replays, 0 keeps them forever.
+	EventRetentionHours int `mapstructure:"eventretentionhours"`
 }
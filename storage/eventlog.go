@@ -0,0 +1,44 @@
+package storage
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// eventRetentionPeriod is how often the old events are removed from the event log.
+const eventRetentionPeriod = time.Hour
+
+// PruneEvents removes the events logged before the time, replays from them start at the oldest kept event.
+// The newest event is always kept, the numbering continues after it when the bus restarts.
+func (s *Storage) PruneEvents(before time.Time) (int64, error) {
+	r := s.db.Where("created < ? AND seq < (SELECT MAX(seq) FROM event_logs)", before).Delete(&EventLog{})
+	return r.RowsAffected, r.Error
+}
+
+// RunEventRetention keeps the event log within the configured retention, it does nothing when the retention is off.
+func (s *Storage) RunEventRetention(ctx context.Context, wg *sync.WaitGroup) {
+	if s.conf.EventRetentionHours <= 0 {
+		return
+	}
+	wg.Add(1)
+	defer wg.Done()
+
+	retention := time.Duration(s.conf.EventRetentionHours) * time.Hour
+	ticker := time.NewTicker(eventRetentionPeriod)
+	defer ticker.Stop()
+	for {
+		pruned, err := s.PruneEvents(time.Now().Add(-retention))
+		if err != nil {
+			s.logger.Error("failed to prune the event log", "error", err)
+		} else if pruned > 0 {
+			s.logger.Debug("Pruned the event log", "events", pruned)
+		}
+		select {
+		case <-ctx.Done():
+			s.logger.Debug("Event retention stopped: context cancelled", "reason", ctx.Err())
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -1,61 +1,246 @@
 package storage
 
-import "sync"
+import (
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type EventType string
 
 const (
-	TaskCreated  EventType = "task_created"
-	TaskUpdated  EventType = "task_updated"
-	TaskAssigned EventType = "task_assigned"
-	TaskAcked    EventType = "task_acked"
-	TaskRefused  EventType = "task_refused"
-	TaskTimeout  EventType = "task_timeout"
-	TaskDone     EventType = "task_done"
+	TaskCreated     EventType = "task_created"
+	TaskUpdated     EventType = "task_updated"
+	TaskAssigned    EventType = "task_assigned"
+	TaskAcked       EventType = "task_acked"
+	TaskRefused     EventType = "task_refused"
+	TaskTimeout     EventType = "task_timeout"
+	TaskTransferred EventType = "task_transferred"
+	TaskDone        EventType = "task_done"
+	TaskCancelled   EventType = "task_cancelled"
+	TaskReleased    EventType = "task_released"
+
+	UserCheckedIn EventType = "user_checked_in"
+	UserLeft      EventType = "user_left"
+
+	WebhookTest EventType = "webhook_test"
+
+	// Published by the Discord UI under their own names.
+	ChoreBid           EventType = "chore_bid"
+	ChoreAuctionClosed EventType = "chore_auction_closed"
+	ChoreWaitlisted    EventType = "chore_waitlisted"
+	ChoreWaitlistLeft  EventType = "chore_waitlist_left"
+	WorklogAdded       EventType = "worklog_added"
+	WorklogUpdated     EventType = "worklog_updated"
 )
 
+// EventTypes are all the types the events are published with.
+var EventTypes = []EventType{
+	TaskCreated, TaskUpdated, TaskAssigned, TaskAcked, TaskRefused, TaskTimeout, TaskTransferred, TaskDone, TaskCancelled, TaskReleased,
+	UserCheckedIn, UserLeft,
+	WebhookTest,
+	ChoreBid, ChoreAuctionClosed, ChoreWaitlisted, ChoreWaitlistLeft, WorklogAdded, WorklogUpdated,
+}
+
 type Event struct {
+	Seq        uint64           `json:"seq"` // Increasing number of the event, the SSE event ID.
 	Type       EventType        `json:"type"`
 	Chore      *Chore           `json:"chore,omitempty"`
 	Assignment *ChoreAssignment `json:"assignment,omitempty"`
+	UserId     string           `json:"user_id,omitempty"`
 }
 
+// recentEvents is how many of the last events are kept in memory, subscribers further behind read the event log.
+const recentEvents = 1000
+
+// eventBatch is how many events a subscriber catching up reads from the event log at once.
+const eventBatch = 100
+
+// EventBus hands every event to all its subscribers in order. Events are numbered and written to the event log,
+// a subscriber can start at an earlier event and a slow one falls behind instead of losing events.
 type EventBus struct {
-	listeners []chan Event
-	mu        sync.RWMutex
+	subscribers map[chan Event]*subscription
+	mu          sync.RWMutex
+	seq         uint64
+	recent      []Event
+	db          *gorm.DB // The event log, nil keeps the events only in memory.
+	logger      *slog.Logger
+}
+
+type subscription struct {
+	out    chan Event
+	notify chan struct{} // Signalled by every published event.
+	done   chan struct{}
 }
 
 func NewEventBus() *EventBus {
-	return &EventBus{}
+	return &EventBus{
+		subscribers: map[chan Event]*subscription{},
+		logger:      slog.Default(),
+	}
+}
+
+// newEventLog returns a bus writing the events to the event log, the numbering continues after the logged events.
+func newEventLog(db *gorm.DB, logger *slog.Logger) (*EventBus, error) {
+	var last EventLog
+	if err := db.Order("seq DESC").Limit(1).Find(&last).Error; err != nil {
+		return nil, err
+	}
+	b := NewEventBus()
+	b.db = db
+	b.logger = logger
+	b.seq = last.Seq
+	return b, nil
 }
 
+// Subscribe returns the events published from now on.
 func (b *EventBus) Subscribe() chan Event {
-	ch := make(chan Event, 100)
 	b.mu.Lock()
 	defer b.mu.Unlock()
-	b.listeners = append(b.listeners, ch)
-	return ch
+	return b.subscribe(b.seq)
+}
+
+// SubscribeFrom replays the events published after seq and continues with the new ones. Events already
+// removed from the event log are skipped. A seq beyond the last event comes from an older numbering,
+// all the kept events are replayed then.
+func (b *EventBus) SubscribeFrom(seq uint64) chan Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if seq > b.seq {
+		seq = 0
+	}
+	return b.subscribe(seq)
+}
+
+func (b *EventBus) subscribe(seq uint64) chan Event {
+	sub := &subscription{
+		out:    make(chan Event, 100),
+		notify: make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+	b.subscribers[sub.out] = sub
+	go b.pump(sub, seq)
+	return sub.out
 }
 
 func (b *EventBus) Unsubscribe(ch chan Event) {
 	b.mu.Lock()
 	defer b.mu.Unlock()
-	for i, listener := range b.listeners {
-		if listener == ch {
-			b.listeners = append(b.listeners[:i], b.listeners[i+1:]...)
-			close(ch)
-			return
-		}
+	if sub, ok := b.subscribers[ch]; ok {
+		delete(b.subscribers, ch)
+		close(sub.done)
 	}
 }
 
-func (b *EventBus) Publish(e Event) {
+// Seq returns the number of the last published event.
+func (b *EventBus) Seq() uint64 {
 	b.mu.RLock()
 	defer b.mu.RUnlock()
-	for _, ch := range b.listeners {
+	return b.seq
+}
+
+func (b *EventBus) Publish(e Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.seq++
+	e.Seq = b.seq
+	if b.db != nil {
+		if err := b.writeLog(e); err != nil {
+			// The subscribers still get the event, only a replay from the event log misses it.
+			b.logger.Error("failed to write the event log", "error", err, "seq", e.Seq, "type", e.Type)
+		}
+	}
+	if len(b.recent) >= recentEvents {
+		b.recent = b.recent[1:]
+	}
+	b.recent = append(b.recent, e)
+	for _, sub := range b.subscribers {
 		select {
-		case ch <- e:
+		case sub.notify <- struct{}{}:
 		default:
 		}
 	}
 }
+
+func (b *EventBus) writeLog(e Event) error {
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	return b.db.Create(&EventLog{
+		Seq:     e.Seq,
+		Type:    e.Type,
+		Payload: string(payload),
+		Created: time.Now(),
+	}).Error
+}
+
+// pump sends the events after seq to the subscriber, it waits for the subscriber instead of dropping events.
+func (b *EventBus) pump(sub *subscription, seq uint64) {
+	defer close(sub.out)
+	for {
+		events := b.after(seq)
+		for _, e := range events {
+			select {
+			case sub.out <- e:
+				seq = e.Seq
+			case <-sub.done:
+				return
+			}
+		}
+		if len(events) > 0 {
+			continue
+		}
+		select {
+		case <-sub.notify:
+		case <-sub.done:
+			return
+		}
+	}
+}
+
+// after returns the next events published after seq, from memory when they are still kept there.
+func (b *EventBus) after(seq uint64) []Event {
+	b.mu.RLock()
+	if b.db == nil || seq >= b.seq || (len(b.recent) > 0 && b.recent[0].Seq <= seq+1) {
+		defer b.mu.RUnlock()
+		events := []Event{}
+		for _, e := range b.recent {
+			if e.Seq > seq {
+				events = append(events, e)
+			}
+		}
+		return events
+	}
+	b.mu.RUnlock()
+
+	var logged []EventLog
+	if err := b.db.Where("seq > ?", seq).Order("seq ASC").Limit(eventBatch).Find(&logged).Error; err != nil {
+		b.logger.Error("failed to read the event log", "error", err, "after", seq)
+		return b.skipToRecent()
+	}
+	if len(logged) == 0 {
+		// The events were removed by the retention, the subscriber continues with the kept ones.
+		return b.skipToRecent()
+	}
+	events := make([]Event, 0, len(logged))
+	for _, l := range logged {
+		var e Event
+		if err := json.Unmarshal([]byte(l.Payload), &e); err != nil {
+			b.logger.Error("failed to decode a logged event", "error", err, "seq", l.Seq)
+			e = Event{Type: l.Type}
+		}
+		e.Seq = l.Seq
+		events = append(events, e)
+	}
+	return events
+}
+
+func (b *EventBus) skipToRecent() []Event {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return append([]Event{}, b.recent...)
+}
@@ -0,0 +1,75 @@
+package storage
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+)
+
+// receive reads n events from the subscription, they have to come in order after seq.
+func receive(t *testing.T, sub chan Event, seq uint64, n int) {
+	t.Helper()
+	for range n {
+		select {
+		case e := <-sub:
+			if e.Seq != seq+1 {
+				t.Fatalf("Expected event %d, got %d", seq+1, e.Seq)
+			}
+			seq = e.Seq
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Expected event %d, got nothing", seq+1)
+		}
+	}
+}
+
+func TestEventLog(t *testing.T) {
+	s := createTestStorage(t)
+
+	// The subscriber reads only after all the events were published, far more than it buffers.
+	slow := s.Events.Subscribe()
+	for i := range recentEvents + 500 {
+		s.Events.Publish(Event{Type: UserCheckedIn, UserId: fmt.Sprintf("u%d", i)})
+	}
+	receive(t, slow, 0, recentEvents+500)
+	s.Events.Unsubscribe(slow)
+
+	// After a restart the numbering continues and the events are replayed from the log.
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	restarted, err := New(s.conf, logger)
+	if err != nil {
+		t.Fatalf("Failed to reopen the storage: %v", err)
+	}
+	if restarted.Events.Seq() != recentEvents+500 {
+		t.Fatalf("Expected the numbering to continue at %d, got %d", recentEvents+500, restarted.Events.Seq())
+	}
+	replay := restarted.Events.SubscribeFrom(recentEvents + 450)
+	restarted.Events.Publish(Event{Type: UserLeft, UserId: "u1"})
+	receive(t, replay, recentEvents+450, 51)
+
+	// Pruned events are skipped, the replay starts at the oldest kept one.
+	if _, err := restarted.PruneEvents(time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("Failed to prune the events: %v", err)
+	}
+	last := restarted.Events.Seq()
+	restarted.Events.Publish(Event{Type: UserLeft, UserId: "u2"})
+	after := restarted.Events.SubscribeFrom(0)
+	receive(t, after, last-1, 2)
+
+	// The newest event outlives the retention, the numbering does not start over.
+	if _, err := restarted.PruneEvents(time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("Failed to prune the events: %v", err)
+	}
+	again, err := New(s.conf, logger)
+	if err != nil {
+		t.Fatalf("Failed to reopen the storage: %v", err)
+	}
+	if again.Events.Seq() != last+1 {
+		t.Fatalf("Expected the numbering to continue at %d, got %d", last+1, again.Events.Seq())
+	}
+
+	// A client from a numbering ahead of the log gets all the kept events.
+	reset := again.Events.SubscribeFrom(last + 100)
+	receive(t, reset, last, 1)
+}
@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// idempotencyInFlightTimeout is how long a reservation without a response holds its key, so the key of a request
+// whose process died is freed for the retries.
+const idempotencyInFlightTimeout = time.Minute
+
+// ReserveIdempotencyKey claims the key for a new request. When the key was used before, the stored one is returned
+// with reserved false. Keys past their retention and abandoned reservations are dropped on the way.
+func (s *Storage) ReserveIdempotencyKey(k IdempotencyKey, ttl time.Duration) (IdempotencyKey, bool, error) {
+	reserved := false
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		stale := tx.Where("expires <= ?", now).Or("response = '' AND created <= ?", now.Add(-idempotencyInFlightTimeout))
+		if err := stale.Delete(&IdempotencyKey{}).Error; err != nil {
+			return err
+		}
+		var existing IdempotencyKey
+		r := tx.Where("owner = ? AND idempotency_key = ?", k.Owner, k.Key).Limit(1).Find(&existing)
+		if r.Error != nil {
+			return r.Error
+		}
+		if r.RowsAffected > 0 {
+			k = existing
+			return nil
+		}
+		k.ID = 0
+		k.Response = ""
+		k.Created = now
+		k.Expires = now.Add(ttl)
+		reserved = true
+		return tx.Create(&k).Error
+	})
+	return k, reserved, err
+}
+
+// CompleteIdempotencyKey stores the response the retries of the request get.
+func (s *Storage) CompleteIdempotencyKey(id uint, response string) error {
+	return s.db.Model(&IdempotencyKey{}).Where("id = ?", id).UpdateColumn("response", response).Error
+}
+
+// ReleaseIdempotencyKey frees the key of a failed request, so it can be retried.
+func (s *Storage) ReleaseIdempotencyKey(id uint) error {
+	return s.db.Delete(&IdempotencyKey{}, id).Error
+}
@@ -0,0 +1,33 @@
+package storage
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAbandonedIdempotencyKeyIsFreed(t *testing.T) {
+	s := createTestStorage(t)
+	key := IdempotencyKey{Owner: "1/bot/", Key: "bread", RequestHash: "abc"}
+
+	first, reserved, err := s.ReserveIdempotencyKey(key, time.Hour)
+	if err != nil || !reserved {
+		t.Fatalf("Expected the key to be reserved, got %v, %v", reserved, err)
+	}
+	if _, reserved, _ := s.ReserveIdempotencyKey(key, time.Hour); reserved {
+		t.Fatal("Expected the key to be held while the first request is running")
+	}
+
+	// The first request died without a response.
+	s.db.Model(&IdempotencyKey{}).Where("id = ?", first.ID).UpdateColumn("created", time.Now().Add(-2*idempotencyInFlightTimeout))
+	if _, reserved, err := s.ReserveIdempotencyKey(key, time.Hour); err != nil || !reserved {
+		t.Fatalf("Expected the abandoned key to be reserved again, got %v, %v", reserved, err)
+	}
+
+	// A completed key is kept until its retention.
+	done, _, _ := s.ReserveIdempotencyKey(IdempotencyKey{Owner: "1/bot/", Key: "milk"}, time.Hour)
+	s.CompleteIdempotencyKey(done.ID, `{"id":1}`)
+	s.db.Model(&IdempotencyKey{}).Where("id = ?", done.ID).UpdateColumn("created", time.Now().Add(-2*idempotencyInFlightTimeout))
+	if k, reserved, _ := s.ReserveIdempotencyKey(IdempotencyKey{Owner: "1/bot/", Key: "milk"}, time.Hour); reserved || k.Response == "" {
+		t.Fatalf("Expected the completed key to be replayed, got %+v", k)
+	}
+}
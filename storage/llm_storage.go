@@ -64,7 +64,7 @@ func (s *Storage) GetTasksActivitySince(since time.Time) (*TasksActivity, error)
 	// 4. Assignments created or updated since the cutoff
 	var assignments []ChoreAssignment
 	r = s.db.Preload(clause.Associations).
-		Where("created >= ? OR acked >= ? OR refused >= ? OR timeouted >= ?", since, since, since, since).
+		Where("created >= ? OR acked >= ? OR refused >= ? OR timeouted >= ? OR transferred >= ? OR released >= ?", since, since, since, since, since, since).
 		Find(&assignments)
 	if r.Error != nil {
 		return nil, r.Error
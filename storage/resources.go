@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+var ErrResourceLocked = errors.New("a required resource is in use by another chore")
+
+// ResourceLockedError lists the resources of the chore held by other chores, with the holding chore ID.
+type ResourceLockedError struct {
+	ChoreId   uint
+	Conflicts map[string]uint
+}
+
+func (e *ResourceLockedError) Error() string {
+	return fmt.Sprintf("chore `id: %d`: %s", e.ChoreId, ErrResourceLocked)
+}
+
+func (e *ResourceLockedError) Unwrap() error {
+	return ErrResourceLocked
+}
+
+func (s *Storage) SaveResource(resource Resource) (Resource, error) {
+	resource.Name = strings.ToLower(strings.TrimSpace(resource.Name))
+	r := s.db.Save(&resource)
+	return resource, r.Error
+}
+
+func (s *Storage) GetResources() ([]Resource, error) {
+	var resources []Resource
+	r := s.db.Order("name ASC").Find(&resources)
+	return resources, r.Error
+}
+
+// GetResourceLocks returns the resources held by unfinished chores somebody acked, keyed by the resource name.
+func (s *Storage) GetResourceLocks() (map[string]uint, error) {
+	return resourceLocks(s.db)
+}
+
+func resourceLocks(tx *gorm.DB) (map[string]uint, error) {
+	var holders []Chore
+	r := tx.Model(&Chore{}).Distinct("chores.id", "chores.resources").
+		Joins("JOIN chore_assignments ON chore_assignments.chore_id = chores.id").
+		Where("chores.completed IS NULL AND chores.cancelled IS NULL AND chores.resources != ''").
+		Where("chore_assignments.acked IS NOT NULL AND chore_assignments.refused IS NULL AND chore_assignments.timeouted IS NULL AND chore_assignments.transferred IS NULL AND chore_assignments.released IS NULL").
+		Order("chores.id ASC").Find(&holders)
+	if r.Error != nil {
+		return nil, r.Error
+	}
+	locks := map[string]uint{}
+	for _, c := range holders {
+		for _, res := range c.GetResources() {
+			if _, ok := locks[res]; !ok {
+				locks[res] = c.ID
+			}
+		}
+	}
+	return locks, nil
+}
+
+// checkResources fails with a ResourceLockedError when other chores hold any of the chore's resources.
+func checkResources(tx *gorm.DB, chore Chore) error {
+	if len(chore.GetResources()) == 0 {
+		return nil
+	}
+	locks, err := resourceLocks(tx)
+	if err != nil {
+		return err
+	}
+	conflicts := map[string]uint{}
+	for _, res := range chore.GetResources() {
+		if holder, ok := locks[res]; ok && holder != chore.ID {
+			conflicts[res] = holder
+		}
+	}
+	if len(conflicts) > 0 {
+		return &ResourceLockedError{ChoreId: chore.ID, Conflicts: conflicts}
+	}
+	return nil
+}
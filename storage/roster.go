@@ -0,0 +1,82 @@
+package storage
+
+import (
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+func (s *Storage) SaveDuty(duty Duty) (Duty, error) {
+	r := s.db.Save(&duty)
+	return duty, r.Error
+}
+
+func (s *Storage) GetDuty(id uint) (Duty, error) {
+	var duty Duty
+	r := s.db.First(&duty, id)
+	return duty, r.Error
+}
+
+func (s *Storage) GetDuties() ([]Duty, error) {
+	var duties []Duty
+	r := s.db.Order("name ASC").Find(&duties)
+	return duties, r.Error
+}
+
+func (s *Storage) SaveShift(shift Shift) (Shift, error) {
+	shift.DurationMin = uint(shift.End.Sub(shift.Start).Minutes())
+	r := s.db.Save(&shift)
+	return shift, r.Error
+}
+
+// GetUpcomingShifts returns the shifts which did not end yet ordered by their start, dutyId 0 means all duties.
+func (s *Storage) GetUpcomingShifts(dutyId uint) ([]Shift, error) {
+	var shifts []Shift
+	q := s.db.Preload(clause.Associations).Where("\"end\" > ?", time.Now())
+	if dutyId != 0 {
+		q = q.Where("duty_id = ?", dutyId)
+	}
+	r := q.Order("start ASC").Order("duty_id ASC").Find(&shifts)
+	return shifts, r.Error
+}
+
+// GetShiftsToRemind returns the shifts starting between now and the given time whose holders were not reminded yet,
+// a shift which already started gets no reminder.
+func (s *Storage) GetShiftsToRemind(before time.Time) ([]Shift, error) {
+	var shifts []Shift
+	r := s.db.Preload(clause.Associations).Where("reminded = ? AND start <= ? AND start > ?", false, before, time.Now()).Find(&shifts)
+	return shifts, r.Error
+}
+
+// GetBusyUsers returns the users holding a shift which overlaps the given span.
+func (s *Storage) GetBusyUsers(start, end time.Time) ([]string, error) {
+	var users []string
+	r := s.db.Model(&Shift{}).Distinct().Where("start < ? AND \"end\" > ?", end, start).Pluck("user_id", &users)
+	return users, r.Error
+}
+
+// getShiftStats sums the shift minutes per user, ended shifts count as worked, the others as assigned.
+func (s *Storage) getShiftStats(ended bool) (UserChoreStats, error) {
+	type result struct {
+		UserId     string
+		TotalTime  int
+		TotalCount int
+	}
+	var results []result
+	stats := UserChoreStats{}
+	cond := "\"end\" > ?"
+	if ended {
+		cond = "\"end\" <= ?"
+	}
+	r := s.db.Model(&Shift{}).Select("user_id, sum(duration_min) as total_time, count(*) as total_count").Where(cond, time.Now()).Group("user_id").Find(&results)
+	if r.Error != nil {
+		return stats, r.Error
+	}
+	for _, r := range results {
+		stats[r.UserId] = ChoreStats{
+			Count:    float64(r.TotalCount),
+			TotalMin: float64(r.TotalTime),
+		}
+	}
+	return stats, nil
+}
@@ -0,0 +1,37 @@
+package storage
+
+import "time"
+
+// SessionPrefix starts the session tokens, it tells them apart from the API keys.
+const SessionPrefix = "gts_"
+
+// CreateSession logs the user in until the session expires, the token is returned only here.
+// Expired sessions are dropped on the way.
+func (s *Storage) CreateSession(session Session, ttl time.Duration) (Session, string, error) {
+	token, err := newSecret(SessionPrefix)
+	if err != nil {
+		return session, "", err
+	}
+	now := time.Now()
+	if err := s.db.Where("expires <= ?", now).Delete(&Session{}).Error; err != nil {
+		return session, "", err
+	}
+	session.ID = 0
+	session.TokenHash = hashSecret(token)
+	session.Created = now
+	session.Expires = now.Add(ttl)
+	r := s.db.Create(&session)
+	return session, token, r.Error
+}
+
+// GetSession returns the session of the token unless it expired.
+func (s *Storage) GetSession(token string) (Session, error) {
+	var session Session
+	r := s.db.Where("token_hash = ? AND expires > ?", hashSecret(token), time.Now()).First(&session)
+	return session, r.Error
+}
+
+// DeleteSession logs the session of the token out.
+func (s *Storage) DeleteSession(token string) error {
+	return s.db.Where("token_hash = ?", hashSecret(token)).Delete(&Session{}).Error
+}
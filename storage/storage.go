@@ -15,6 +15,7 @@ type Storage struct {
 	logger  *slog.Logger
 	discord *discordgo.Session
 	conf    Config
+	loc     *time.Location
 	Events  *EventBus
 }
 
@@ -28,17 +29,76 @@ func dbConnect(conf Config, logger *slog.Logger) (*gorm.DB, error) {
 		return nil, err
 	}
 
+	// SQLite allows a single writer, one connection serializes the transactions instead of failing them as busy.
+	sqlDB, err := db.DB()
+	if err != nil {
+		logger.Error("failed to get the database connection", "error", err)
+		return nil, err
+	}
+	sqlDB.SetMaxOpenConns(1)
+
+	if err := dedupeAssignments(db); err != nil {
+		logger.Error("failed to remove duplicate assignments", "error", err)
+		return nil, err
+	}
+
 	// Migrate the schema
-	db.AutoMigrate(&Chore{}, &WorkLog{}, &ChoreAssignment{}, &PresenceLog{}, &LLMSummaryLog{})
+	err = db.AutoMigrate(&Chore{}, &WorkLog{}, &ChoreAssignment{}, &PresenceLog{}, &LLMSummaryLog{}, &AssignmentTransfer{}, &UserSettings{}, &UserAvailability{}, &Bid{}, &Duty{}, &Shift{}, &Resource{}, &WaitlistEntry{}, &ApiKey{}, &Session{}, &Webhook{}, &WebhookDelivery{}, &IdempotencyKey{}, &EventLog{})
+	if err != nil {
+		logger.Error("failed to migrate the database", "error", err)
+		return nil, err
+	}
+
+	// Chores from before the status column get it derived from their timestamps and assignments.
+	db.Exec(`UPDATE chores SET status = CASE
+		WHEN completed IS NOT NULL THEN ?
+		WHEN cancelled IS NOT NULL THEN ?
+		WHEN EXISTS (SELECT 1 FROM chore_assignments a WHERE a.chore_id = chores.id AND a.acked IS NOT NULL AND a.refused IS NULL AND a.timeouted IS NULL AND a.transferred IS NULL) THEN ?
+		ELSE ? END
+		WHERE status IS NULL OR status = ''`, ChoreDone, ChoreCancelled, ChoreInProgress, ChoreOpen)
+	// Chores from before the version column start at the first version.
+	db.Exec("UPDATE chores SET version = 1 WHERE version IS NULL OR version = 0")
 	return db, nil
 }
 
+// dedupeAssignments keeps a single assignment per chore and user, so the unique index can be created on databases
+// from before it. The active one is kept, then the acked one, then the newest one.
+func dedupeAssignments(db *gorm.DB) error {
+	if !db.Migrator().HasTable(&ChoreAssignment{}) {
+		return nil
+	}
+	active := "refused IS NULL AND timeouted IS NULL"
+	if db.Migrator().HasColumn(&ChoreAssignment{}, "Transferred") {
+		active += " AND transferred IS NULL"
+	}
+	return db.Exec(`DELETE FROM chore_assignments WHERE id NOT IN (
+		SELECT id FROM (
+			SELECT id, ROW_NUMBER() OVER (
+				PARTITION BY chore_id, user_id
+				ORDER BY (` + active + `) DESC, acked IS NOT NULL DESC, id DESC
+			) AS n FROM chore_assignments
+		) WHERE n = 1
+	)`).Error
+}
+
 func New(conf Config, logger *slog.Logger) (*Storage, error) {
 	db, err := dbConnect(conf, logger)
 	if err != nil {
 		return nil, err
 	}
 
+	loc, err := time.LoadLocation(conf.Timezone)
+	if err != nil {
+		logger.Error("failed to load timezone", "timezone", conf.Timezone, "error", err)
+		return nil, err
+	}
+
+	events, err := newEventLog(db, logger)
+	if err != nil {
+		logger.Error("failed to open the event log", "error", err)
+		return nil, err
+	}
+
 	var dg *discordgo.Session
 	if conf.DiscordToken != "" && conf.DiscordToken != "???" {
 		dg, err = discordConnect(conf.DiscordToken)
@@ -66,7 +126,8 @@ func New(conf Config, logger *slog.Logger) (*Storage, error) {
 		logger:  logger,
 		discord: dg,
 		conf:    conf,
-		Events:  NewEventBus(),
+		loc:     loc,
+		Events:  events,
 	}, nil
 }
 
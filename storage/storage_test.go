@@ -0,0 +1,99 @@
+package storage
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// legacyAssignment is the assignment table from before the unique index on the chore and user.
+type legacyAssignment struct {
+	ID        uint
+	UserId    string
+	ChoreId   uint
+	Created   time.Time
+	Acked     *time.Time
+	Refused   *time.Time
+	Timeouted *time.Time
+}
+
+func (legacyAssignment) TableName() string {
+	return "chore_assignments"
+}
+
+func TestMigrateDuplicateAssignments(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "legacy.sqlite")
+	legacy, err := gorm.Open(sqlite.Open(dbPath), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to open the legacy database: %v", err)
+	}
+	if err := legacy.AutoMigrate(&legacyAssignment{}); err != nil {
+		t.Fatalf("Failed to create the legacy table: %v", err)
+	}
+	now := time.Now()
+	legacy.Create(&[]legacyAssignment{
+		{UserId: "u1", ChoreId: 1, Refused: &now},
+		{UserId: "u1", ChoreId: 1, Acked: &now},
+		{UserId: "u1", ChoreId: 1, Timeouted: &now},
+		{UserId: "u2", ChoreId: 1},
+	})
+	sqlDB, _ := legacy.DB()
+	sqlDB.Close()
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	s, err := New(Config{DbPath: dbPath}, logger)
+	if err != nil {
+		t.Fatalf("Failed to migrate the legacy database: %v", err)
+	}
+
+	all, err := s.GetChoreAssignments(1)
+	if err != nil {
+		t.Fatalf("Failed to get assignments: %v", err)
+	}
+	if len(all) != 2 {
+		t.Fatalf("Expected one assignment per user, got %+v", all)
+	}
+	for _, a := range all {
+		if a.UserId == "u1" && (a.ID != 2 || a.Acked == nil) {
+			t.Errorf("Expected the acked assignment of u1 to be kept, got %+v", a)
+		}
+	}
+	// The tables after the assignments were created too.
+	if _, err := s.GetWaitlist(1); err != nil {
+		t.Errorf("Expected the later tables to be migrated: %v", err)
+	}
+}
+
+func TestSaveChoreRejectsStaleCopy(t *testing.T) {
+	s := createTestStorage(t)
+	chore, err := s.SaveChore(Chore{Name: "Dishes", Status: ChoreOpen})
+	if err != nil {
+		t.Fatalf("Failed to save chore: %v", err)
+	}
+	stale := chore
+
+	// An ack moves the chore on meanwhile.
+	if _, err := s.UpdateChore(chore.ID, func(c *Chore) error {
+		c.Status = ChoreInProgress
+		return nil
+	}); err != nil {
+		t.Fatalf("Failed to update chore: %v", err)
+	}
+	stale.Name = "Dirty dishes"
+	if _, err := s.SaveChore(stale); !errors.Is(err, ErrVersionConflict) {
+		t.Fatalf("Expected the stale copy to be rejected, got %v", err)
+	}
+	current, _ := s.GetChore(chore.ID)
+	if current.Status != ChoreInProgress || current.Name != "Dishes" {
+		t.Fatalf("Expected the chore to be unchanged, got %+v", current)
+	}
+	if _, err := s.SaveChore(current); err != nil {
+		t.Fatalf("Expected the current copy to be saved, got %v", err)
+	}
+}
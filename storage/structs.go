@@ -1,6 +1,8 @@
 package storage
 
 import (
+	"math"
+	"slices"
 	"strings"
 	"time"
 )
@@ -11,6 +13,18 @@ type User struct {
 	Capabilities []string
 }
 
+// ChoreStatus is the lifecycle state of a chore, it is changed by chores.Transition only.
+type ChoreStatus string
+
+const (
+	ChoreDraft      ChoreStatus = "draft"       // Not published yet, nobody is assigned.
+	ChoreOpen       ChoreStatus = "open"        // Published, waiting for somebody to ack it.
+	ChoreInProgress ChoreStatus = "in_progress" // Somebody acked it.
+	ChoreDone       ChoreStatus = "done"
+	ChoreCancelled  ChoreStatus = "cancelled"
+	ChoreReopened   ChoreStatus = "reopened" // Done or cancelled before, open again.
+)
+
 type Chore struct {
 	ID                    uint
 	Name                  string
@@ -26,6 +40,19 @@ type Chore struct {
 	Deadline              *time.Time
 	necessaryCapabilities []string
 	AfterDeadlineReminded bool
+	Understaffed          bool       // The chore is waiting for more assignees.
+	TeamMode              bool       // Assignees are picked as a team covering the skills and rotating partners.
+	TrainingMode          bool       // The team pairs skilled mentors with trainees lacking the skills.
+	Categories            string     // Comma separated list of categories (e.g. cooking, toilets)
+	Bounty                float64    // Multiplier of the credited minutes raised by refusals and timeouts, 0 means none.
+	AuctionMin            uint       // Length of the bidding window in minutes, 0 means the chore is assigned directly.
+	AuctionUntil          *time.Time // End of the bidding window, set once the chore is published.
+	AuctionClosed         bool
+	Resources             string      // Comma separated list of required resources (e.g. van, grill)
+	NotBefore             *time.Time  // The chore is not published before this time.
+	Status                ChoreStatus `gorm:"index"`
+	Version               uint        // Raised by every change of the chore, used to detect conflicting edits.
+	HeldAssignees         string      // Comma separated Discord IDs of the manual assignees of a draft, assigned once it is published.
 }
 
 func (c *Chore) GetCapabilities() []string {
@@ -41,6 +68,56 @@ func (c *Chore) SetCapabilities(capabilities []string) {
 	c.NecessaryCapabilities = strings.Join(capabilities, ",")
 }
 
+func (c *Chore) GetCategories() []string {
+	return splitTags(c.Categories)
+}
+
+func (c *Chore) SetCategories(categories []string) {
+	c.Categories = strings.Join(normalizeTags(categories), ",")
+}
+
+func (c *Chore) GetResources() []string {
+	return splitTags(c.Resources)
+}
+
+func (c *Chore) SetResources(resources []string) {
+	c.Resources = strings.Join(normalizeTags(resources), ",")
+}
+
+func (c *Chore) GetHeldAssignees() []string {
+	return splitTags(c.HeldAssignees)
+}
+
+func (c *Chore) SetHeldAssignees(userIds []string) {
+	held := []string{}
+	for _, u := range userIds {
+		if u != "" && !slices.Contains(held, u) {
+			held = append(held, u)
+		}
+	}
+	c.HeldAssignees = strings.Join(held, ",")
+}
+
+// Tags are the categories and capabilities of the chore users can express their preferences about.
+func (c *Chore) Tags() []string {
+	return append(c.GetCategories(), c.GetCapabilities()...)
+}
+
+// BountyMultiplier returns the multiplier of the minutes credited for the chore (at least 1).
+func (c *Chore) BountyMultiplier() float64 {
+	return max(c.Bounty, 1)
+}
+
+// Credit applies the bounty to the minutes spent on the chore.
+func (c *Chore) Credit(timeSpentMin uint) uint {
+	return uint(math.Round(float64(timeSpentMin) * c.BountyMultiplier()))
+}
+
+// AuctionOpen reports whether users can bid on the chore.
+func (c *Chore) AuctionOpen() bool {
+	return c.AuctionUntil != nil && !c.AuctionClosed
+}
+
 func (c *Chore) Complete() {
 	now := time.Now()
 	c.Completed = &now
@@ -51,6 +128,37 @@ func (c *Chore) Cancel() {
 	c.Cancelled = &now
 }
 
+const (
+	StaffingStaffed      = "staffed"
+	StaffingUnderstaffed = "understaffed"
+	StaffingUnstaffed    = "unstaffed"
+)
+
+type Staffing struct {
+	State    string
+	Assigned uint
+	Needed   uint
+}
+
+// Staffing counts the active (assigned or acked) assignments of the chore.
+func (c *Chore) Staffing(assignments []ChoreAssignment) Staffing {
+	st := Staffing{Needed: c.NecessaryWorkers}
+	for _, a := range assignments {
+		if a.ChoreId == c.ID && a.Active() {
+			st.Assigned++
+		}
+	}
+	switch {
+	case st.Assigned >= st.Needed:
+		st.State = StaffingStaffed
+	case st.Assigned == 0:
+		st.State = StaffingUnstaffed
+	default:
+		st.State = StaffingUnderstaffed
+	}
+	return st
+}
+
 type WorkLog struct {
 	ID           uint
 	UserId       string
@@ -62,17 +170,31 @@ type WorkLog struct {
 
 type ChoreAssignment struct {
 	ID                    uint
-	UserId                string
-	ChoreId               uint
+	UserId                string `gorm:"uniqueIndex:idx_assignment_chore_user"`
+	ChoreId               uint   `gorm:"uniqueIndex:idx_assignment_chore_user"`
 	Chore                 Chore
 	Created               time.Time
 	Acked                 *time.Time
 	Refused               *time.Time
 	Timeouted             *time.Time
 	Volunteered           bool
+	Manual                bool // Assigned explicitly by the creator, bypassing the assignment strategy.
 	DeadlineReminded      bool
 	AfterDeadlineReminded bool
 	Reminded              bool
+	ReleaseRequested      *time.Time // The user left while holding the acked chore and was asked to hand it back.
+	Transferred           *time.Time // The chore was handed off or swapped to another user.
+	Released              *time.Time // The user handed the chore back, e.g. after leaving, to be assigned to somebody else.
+	PreviousAssignmentId  *uint      // The assignment this one was handed off or swapped from.
+	BidMin                uint       // Minutes credited for the chore as won in the auction, 0 means the estimate.
+}
+
+// CreditedMin returns the minutes the assignee gets for completing the chore, before the bounty.
+func (ca *ChoreAssignment) CreditedMin(chore Chore) uint {
+	if ca.BidMin > 0 {
+		return ca.BidMin
+	}
+	return chore.EstimatedTimeMin
 }
 
 func (ca *ChoreAssignment) Ack() {
@@ -80,6 +202,8 @@ func (ca *ChoreAssignment) Ack() {
 	ca.Acked = &now
 	ca.Refused = nil
 	ca.Timeouted = nil
+	ca.Transferred = nil
+	ca.Released = nil
 }
 
 func (ca *ChoreAssignment) Refuse() {
@@ -87,6 +211,8 @@ func (ca *ChoreAssignment) Refuse() {
 	ca.Refused = &now
 	ca.Acked = nil
 	ca.Timeouted = nil
+	ca.Transferred = nil
+	ca.Released = nil
 }
 
 func (ca *ChoreAssignment) Timeout() {
@@ -94,6 +220,188 @@ func (ca *ChoreAssignment) Timeout() {
 	ca.Timeouted = &now
 	ca.Acked = nil
 	ca.Refused = nil
+	ca.Transferred = nil
+	ca.Released = nil
+}
+
+func (ca *ChoreAssignment) Transfer() {
+	now := time.Now()
+	ca.Transferred = &now
+	ca.Acked = nil
+	ca.Refused = nil
+	ca.Timeouted = nil
+	ca.Released = nil
+}
+
+// Release hands the assignment back without counting it as ignored, unlike Timeout.
+func (ca *ChoreAssignment) Release() {
+	now := time.Now()
+	ca.Released = &now
+	ca.Acked = nil
+	ca.Refused = nil
+	ca.Timeouted = nil
+	ca.Transferred = nil
+	ca.ReleaseRequested = nil
+}
+
+// Active reports whether the user still holds the assignment (assigned or acked).
+func (ca *ChoreAssignment) Active() bool {
+	return ca.Refused == nil && ca.Timeouted == nil && ca.Transferred == nil && ca.Released == nil
+}
+
+const (
+	TransferHandoff = "handoff"
+	TransferSwap    = "swap"
+)
+
+// Bid is a user's offer to do an auctioned chore for the given minutes of credit.
+type Bid struct {
+	ID      uint
+	ChoreId uint   `gorm:"uniqueIndex:idx_bid_chore_user"`
+	UserId  string `gorm:"uniqueIndex:idx_bid_chore_user"`
+	TimeMin uint
+	Created time.Time
+}
+
+const (
+	ScopeRead      = "read"
+	ScopeWrite     = "write"
+	ScopeAdmin     = "admin"
+	ScopeAckAsSelf = "ack-as-self" // Ack and reject chores as the user the key is bound to.
+)
+
+var ApiKeyScopes = []string{ScopeRead, ScopeWrite, ScopeAdmin, ScopeAckAsSelf}
+
+// ApiKey grants access to the REST API, only the hash of the secret is stored.
+type ApiKey struct {
+	ID         uint
+	Name       string
+	SecretHash string `gorm:"uniqueIndex"`
+	Hint       string // The beginning of the secret to tell the keys apart.
+	Scopes     string // Comma separated list of scopes
+	UserId     string // Discord ID of the user the key acts as, empty when it can act as anybody.
+	Created    time.Time
+	LastUsed   *time.Time
+	Revoked    *time.Time
+}
+
+func (k *ApiKey) GetScopes() []string {
+	return splitTags(k.Scopes)
+}
+
+func (k *ApiKey) SetScopes(scopes []string) {
+	k.Scopes = strings.Join(normalizeTags(scopes), ",")
+}
+
+// Allows tells whether the key grants the scope, admin grants everything and write grants read and acking.
+func (k *ApiKey) Allows(scope string) bool {
+	for _, s := range k.GetScopes() {
+		if s == scope || s == ScopeAdmin || (s == ScopeWrite && (scope == ScopeRead || scope == ScopeAckAsSelf)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Session is a user logged in to the API with Discord, only the hash of the session token is stored.
+type Session struct {
+	ID        uint
+	TokenHash string `gorm:"uniqueIndex"`
+	UserId    string // Discord ID of the logged in user.
+	Username  string
+	Admin     bool // The user has one of the admin roles in the guild.
+	Created   time.Time
+	Expires   time.Time `gorm:"index"`
+}
+
+// Webhook is a subscription which gets the events POSTed, signed with its secret.
+type Webhook struct {
+	ID         uint
+	Url        string
+	Secret     string // Key of the HMAC signature, kept in plain text to sign the deliveries.
+	EventTypes string // Comma separated list of event types, empty for all events.
+	Created    time.Time
+}
+
+func (w *Webhook) GetEventTypes() []string {
+	return splitTags(w.EventTypes)
+}
+
+func (w *Webhook) SetEventTypes(types []string) {
+	w.EventTypes = strings.Join(normalizeTags(types), ",")
+}
+
+// Wants tells whether the webhook subscribed to the event type.
+func (w *Webhook) Wants(t EventType) bool {
+	return w.EventTypes == "" || slices.Contains(w.GetEventTypes(), string(t))
+}
+
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliveryDelivered DeliveryStatus = "delivered"
+	DeliveryDead      DeliveryStatus = "dead" // Gave up after too many failed attempts.
+)
+
+// WebhookDelivery is one event sent to a webhook, retried until it is delivered or dead.
+type WebhookDelivery struct {
+	ID           uint
+	WebhookId    uint `gorm:"index"`
+	EventType    EventType
+	Payload      string
+	Status       DeliveryStatus `gorm:"index"`
+	Attempts     uint
+	NextAttempt  time.Time `gorm:"index"`
+	ResponseCode int
+	LastError    string
+	Created      time.Time
+	Delivered    *time.Time
+}
+
+// IdempotencyKey remembers the response to a request, so its retries do not repeat it.
+type IdempotencyKey struct {
+	ID          uint
+	Owner       string `gorm:"uniqueIndex:idx_idempotency_owner_key"` // The client which sent the key, keys of different clients do not clash.
+	Key         string `gorm:"column:idempotency_key;uniqueIndex:idx_idempotency_owner_key"`
+	RequestHash string
+	Response    string // JSON of the response, empty while the first request is still running.
+	Created     time.Time
+	Expires     time.Time `gorm:"index"`
+}
+
+// EventLog is a published event, the log is append-only and its entries are removed only by the retention.
+type EventLog struct {
+	Seq     uint64 `gorm:"primaryKey;autoIncrement:false"`
+	Type    EventType
+	Payload string    // JSON of the event.
+	Created time.Time `gorm:"index"`
+}
+
+// WaitlistEntry is a user who acked a chore which already had enough acked workers.
+// The entries are promoted in the order they were created once a slot frees up.
+type WaitlistEntry struct {
+	ID      uint
+	ChoreId uint   `gorm:"uniqueIndex:idx_waitlist_chore_user"`
+	UserId  string `gorm:"uniqueIndex:idx_waitlist_chore_user"`
+	Created time.Time
+}
+
+// AssignmentTransfer is an offer to hand a chore off to another user or to swap chores with them.
+type AssignmentTransfer struct {
+	ID          uint
+	Kind        string
+	ChoreId     uint // Chore offered by FromUserId.
+	FromUserId  string
+	ToUserId    string
+	SwapChoreId uint // Chore of ToUserId taken in exchange, swaps only.
+	Created     time.Time
+	Accepted    *time.Time
+	Declined    *time.Time
+}
+
+func (t *AssignmentTransfer) Pending() bool {
+	return t.Accepted == nil && t.Declined == nil
 }
 
 type ChoreStats struct {
@@ -131,6 +439,131 @@ func (st UserChoreStats) Add(other UserChoreStats) UserChoreStats {
 	return sum
 }
 
+// UserSettings holds per-user preferences, quiet hours are "HH:MM" in the configured timezone.
+type UserSettings struct {
+	UserId     string `gorm:"primaryKey"`
+	QuietStart string
+	QuietEnd   string
+	Likes      string // Comma separated list of preferred chore categories or skills
+	Dislikes   string // Comma separated list of avoided chore categories or skills
+}
+
+func (us *UserSettings) GetLikes() []string {
+	return splitTags(us.Likes)
+}
+
+func (us *UserSettings) SetLikes(tags []string) {
+	us.Likes = strings.Join(normalizeTags(tags), ",")
+}
+
+func (us *UserSettings) GetDislikes() []string {
+	return splitTags(us.Dislikes)
+}
+
+func (us *UserSettings) SetDislikes(tags []string) {
+	us.Dislikes = strings.Join(normalizeTags(tags), ",")
+}
+
+// Preference is the number of liked minus the number of disliked tags.
+func (us UserSettings) Preference(tags []string) int {
+	pref := 0
+	for _, t := range normalizeTags(tags) {
+		if slices.Contains(us.GetLikes(), t) {
+			pref++
+		}
+		if slices.Contains(us.GetDislikes(), t) {
+			pref--
+		}
+	}
+	return pref
+}
+
+// normalizeTags trims and lowercases the tags and drops empty and duplicate ones.
+func normalizeTags(tags []string) []string {
+	normalized := []string{}
+	for _, t := range tags {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if t != "" && !slices.Contains(normalized, t) {
+			normalized = append(normalized, t)
+		}
+	}
+	return normalized
+}
+
+func splitTags(s string) []string {
+	if s == "" {
+		return []string{}
+	}
+	return strings.Split(s, ",")
+}
+
+// QuietUntil returns the end of the quiet hours the time falls into.
+func (us UserSettings) QuietUntil(t time.Time, loc *time.Location) (time.Time, bool) {
+	if us.QuietStart == "" || us.QuietEnd == "" {
+		return time.Time{}, false
+	}
+	start, err := time.Parse("15:04", us.QuietStart)
+	if err != nil {
+		return time.Time{}, false
+	}
+	end, err := time.Parse("15:04", us.QuietEnd)
+	if err != nil || start.Equal(end) {
+		// An empty window, not a whole day of quiet hours.
+		return time.Time{}, false
+	}
+
+	lt := t.In(loc)
+	// The quiet hours might have started yesterday (e.g. 23:00 - 07:00).
+	for _, day := range []int{-1, 0} {
+		from := time.Date(lt.Year(), lt.Month(), lt.Day()+day, start.Hour(), start.Minute(), 0, 0, loc)
+		to := time.Date(lt.Year(), lt.Month(), lt.Day()+day, end.Hour(), end.Minute(), 0, 0, loc)
+		if !to.After(from) {
+			to = to.AddDate(0, 0, 1)
+		}
+		if !lt.Before(from) && lt.Before(to) {
+			return to, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// UserAvailability is an ad-hoc block of time in which the user is not available.
+type UserAvailability struct {
+	ID     uint
+	UserId string `gorm:"index"`
+	From   time.Time
+	To     time.Time
+	Reason string
+}
+
+// Resource is a shared piece of equipment (vehicle, tool, room), a chore requiring it locks it while acked.
+type Resource struct {
+	ID      uint
+	Name    string `gorm:"uniqueIndex"`
+	Kind    string
+	Created time.Time
+}
+
+// Duty is a role held over a time span (e.g. kitchen duty, night watch) instead of a single chore.
+type Duty struct {
+	ID              uint
+	Name            string `gorm:"uniqueIndex"`
+	WorkersPerShift uint
+	Created         time.Time
+}
+
+// Shift is a user's slot of a duty, its minutes are credited as work.
+type Shift struct {
+	ID          uint
+	DutyId      uint `gorm:"index"`
+	Duty        Duty
+	UserId      string `gorm:"index"`
+	Start       time.Time
+	End         time.Time
+	DurationMin uint
+	Reminded    bool
+}
+
 type PresenceLog struct {
 	ID        uint
 	UserId    string
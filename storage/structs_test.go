@@ -3,6 +3,7 @@ package storage
 import (
 	"slices"
 	"testing"
+	"time"
 )
 
 func TestChoreCapabilities(t *testing.T) {
@@ -26,3 +27,133 @@ func TestChoreCapabilities(t *testing.T) {
 		t.Error("Expected capabilities to be 'cap1,cap2'")
 	}
 }
+
+func TestChoreStaffing(t *testing.T) {
+	now := time.Now()
+	c := Chore{ID: 1, NecessaryWorkers: 2}
+
+	st := c.Staffing(nil)
+	if st.State != StaffingUnstaffed || st.Assigned != 0 || st.Needed != 2 {
+		t.Errorf("Expected unstaffed 0/2, got %+v", st)
+	}
+
+	ass := []ChoreAssignment{
+		{ChoreId: 1, UserId: "u1", Acked: &now},
+		{ChoreId: 1, UserId: "u2", Refused: &now},
+		{ChoreId: 2, UserId: "u3"},
+	}
+	st = c.Staffing(ass)
+	if st.State != StaffingUnderstaffed || st.Assigned != 1 {
+		t.Errorf("Expected understaffed 1/2, got %+v", st)
+	}
+
+	ass = append(ass, ChoreAssignment{ChoreId: 1, UserId: "u4"})
+	st = c.Staffing(ass)
+	if st.State != StaffingStaffed || st.Assigned != 2 {
+		t.Errorf("Expected staffed 2/2, got %+v", st)
+	}
+}
+
+func TestChoreAssignmentTransfer(t *testing.T) {
+	ca := ChoreAssignment{}
+	if !ca.Active() {
+		t.Error("Expected a new assignment to be active")
+	}
+	ca.Ack()
+	ca.Transfer()
+	if ca.Active() || ca.Acked != nil || ca.Transferred == nil {
+		t.Errorf("Expected a transferred inactive assignment, got %+v", ca)
+	}
+	ca.Ack()
+	if !ca.Active() || ca.Transferred != nil {
+		t.Errorf("Expected the ack to reactivate the assignment, got %+v", ca)
+	}
+}
+
+func TestChoreAssignmentRelease(t *testing.T) {
+	now := time.Now()
+	ca := ChoreAssignment{ReleaseRequested: &now}
+	ca.Ack()
+	ca.Release()
+	if ca.Active() || ca.Acked != nil || ca.Timeouted != nil || ca.Released == nil || ca.ReleaseRequested != nil {
+		t.Errorf("Expected a released inactive assignment not counted as a timeout, got %+v", ca)
+	}
+	if err := ReleasePending(&ca); err != ErrAssignmentInactive {
+		t.Errorf("Expected a released assignment not to be released again, got %v", err)
+	}
+	ca.Ack()
+	if !ca.Active() || ca.Released != nil {
+		t.Errorf("Expected the ack to reactivate the assignment, got %+v", ca)
+	}
+}
+
+func TestQuietUntil(t *testing.T) {
+	loc := time.UTC
+	us := UserSettings{QuietStart: "23:00", QuietEnd: "07:00"}
+
+	until, quiet := us.QuietUntil(time.Date(2025, 7, 1, 3, 0, 0, 0, loc), loc)
+	if !quiet || !until.Equal(time.Date(2025, 7, 1, 7, 0, 0, 0, loc)) {
+		t.Errorf("Expected quiet until 07:00, got %v %v", quiet, until)
+	}
+	until, quiet = us.QuietUntil(time.Date(2025, 7, 1, 23, 30, 0, 0, loc), loc)
+	if !quiet || !until.Equal(time.Date(2025, 7, 2, 7, 0, 0, 0, loc)) {
+		t.Errorf("Expected quiet until 07:00 the next day, got %v %v", quiet, until)
+	}
+	if _, quiet = us.QuietUntil(time.Date(2025, 7, 1, 12, 0, 0, 0, loc), loc); quiet {
+		t.Error("Expected noon not to be quiet")
+	}
+	if _, quiet = (UserSettings{}).QuietUntil(time.Date(2025, 7, 1, 3, 0, 0, 0, loc), loc); quiet {
+		t.Error("Expected no quiet hours without settings")
+	}
+	if _, quiet = (UserSettings{QuietStart: "22:00", QuietEnd: "22:00"}).QuietUntil(time.Date(2025, 7, 1, 22, 0, 0, 0, loc), loc); quiet {
+		t.Error("Expected no quiet hours when they start and end at the same time")
+	}
+}
+
+func TestAvailableAtTerminates(t *testing.T) {
+	loc := time.UTC
+	at := time.Date(2025, 7, 1, 22, 0, 0, 0, loc)
+	// Quiet hours chained with blocks back to back never leave a gap, the search still ends.
+	blocks := []UserAvailability{}
+	for day := range 3 * maxAvailabilitySteps {
+		from := time.Date(2025, 7, 2+day, 7, 0, 0, 0, loc)
+		blocks = append(blocks, UserAvailability{From: from, To: from.Add(15 * time.Hour)})
+	}
+	us := UserSettings{QuietStart: "22:00", QuietEnd: "07:00"}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if _, ok := availableAt(at, us, blocks, loc); !ok {
+			t.Error("Expected the user to be unavailable")
+		}
+		if _, ok := availableAt(at, UserSettings{QuietStart: "22:00", QuietEnd: "22:00"}, nil, loc); ok {
+			t.Error("Expected empty quiet hours to keep the user available")
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("availableAt did not return")
+	}
+}
+
+func TestUserSettingsPreference(t *testing.T) {
+	us := UserSettings{}
+	us.SetLikes([]string{" Cooking", "shopping", "cooking", ""})
+	us.SetDislikes([]string{"toilets"})
+	if us.Likes != "cooking,shopping" {
+		t.Errorf("Expected normalized likes, got %q", us.Likes)
+	}
+
+	chore := Chore{Categories: "cooking", NecessaryCapabilities: "Toilets"}
+	if p := us.Preference(chore.Tags()); p != 0 {
+		t.Errorf("Expected a liked and a disliked tag to cancel out, got %d", p)
+	}
+	if p := us.Preference([]string{"Shopping"}); p != 1 {
+		t.Errorf("Expected preference 1, got %d", p)
+	}
+	if p := (UserSettings{}).Preference(chore.Tags()); p != 0 {
+		t.Errorf("Expected no preference without settings, got %d", p)
+	}
+}
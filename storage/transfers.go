@@ -0,0 +1,132 @@
+package storage
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var ErrAlreadyAssigned = errors.New("user is already assigned to the chore")
+
+func (s *Storage) SaveAssignmentTransfer(t AssignmentTransfer) (AssignmentTransfer, error) {
+	r := s.db.Save(&t)
+	return t, r.Error
+}
+
+func (s *Storage) GetAssignmentTransfer(id uint) (AssignmentTransfer, error) {
+	var t AssignmentTransfer
+	r := s.db.First(&t, id)
+	return t, r.Error
+}
+
+// GetPendingTransfers returns the offers waiting for an answer which were sent or received by the user (all of them for an empty user).
+func (s *Storage) GetPendingTransfers(userId string) ([]AssignmentTransfer, error) {
+	var transfers []AssignmentTransfer
+	q := s.db.Where("accepted IS NULL AND declined IS NULL")
+	if userId != "" {
+		q = q.Where("from_user_id = ? OR to_user_id = ?", userId, userId)
+	}
+	r := q.Order("created ASC").Find(&transfers)
+	return transfers, r.Error
+}
+
+// GetOpenAssignments returns all active assignments on unfinished chores.
+func (s *Storage) GetOpenAssignments() ([]ChoreAssignment, error) {
+	var assignments []ChoreAssignment
+	r := s.db.Preload("Chore").
+		Joins("JOIN chores ON chores.id = chore_assignments.chore_id").
+		Where("chore_assignments.refused IS NULL AND chore_assignments.timeouted IS NULL AND chore_assignments.transferred IS NULL AND chore_assignments.released IS NULL and chores.completed IS NULL and chores.cancelled IS NULL").
+		Find(&assignments)
+	return assignments, r.Error
+}
+
+// TransferAssignment hands the assignment over to another user who accepted it.
+// The original assignment is marked as transferred and the new one links back to it.
+func (s *Storage) TransferAssignment(from ChoreAssignment, toUserId string) (ChoreAssignment, error) {
+	var to ChoreAssignment
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		to, err = transferAssignment(tx, &from, toUserId)
+		return err
+	})
+	if err != nil {
+		return to, err
+	}
+	s.publishTransfer(from, to)
+	return to, nil
+}
+
+// SwapAssignments exchanges the chores of two assignments between their users.
+func (s *Storage) SwapAssignments(a, b ChoreAssignment) (ChoreAssignment, ChoreAssignment, error) {
+	var newA, newB ChoreAssignment
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		aUserId, bUserId := a.UserId, b.UserId
+		newB, err = transferAssignment(tx, &a, bUserId)
+		if err != nil {
+			return err
+		}
+		newA, err = transferAssignment(tx, &b, aUserId)
+		return err
+	})
+	if err != nil {
+		return newA, newB, err
+	}
+	s.publishTransfer(a, newB)
+	s.publishTransfer(b, newA)
+	return newA, newB, nil
+}
+
+func transferAssignment(tx *gorm.DB, from *ChoreAssignment, toUserId string) (ChoreAssignment, error) {
+	var to ChoreAssignment
+	// The assignment might have changed since the offer was made.
+	if err := tx.First(from, from.ID).Error; err != nil {
+		return to, err
+	}
+	if !from.Active() {
+		return to, ErrAssignmentInactive
+	}
+
+	// The receiving user might have turned the chore down before, reuse their assignment then.
+	r := tx.Where("chore_id = ? AND user_id = ?", from.ChoreId, toUserId).Limit(1).Find(&to)
+	if r.Error != nil {
+		return to, r.Error
+	}
+	if r.RowsAffected > 0 && to.Active() {
+		return to, ErrAlreadyAssigned
+	}
+
+	from.Transfer()
+	from.ReleaseRequested = nil
+	r = tx.Omit("Chore").Save(from)
+	if r.Error != nil {
+		return to, r.Error
+	}
+
+	to.ChoreId = from.ChoreId
+	to.UserId = toUserId
+	to.Created = time.Now()
+	to.Manual = from.Manual
+	to.Volunteered = false
+	to.Reminded = false
+	to.PreviousAssignmentId = &from.ID
+	// Accepting the transfer is an ack.
+	to.Ack()
+	r = tx.Omit("Chore").Save(&to)
+	return to, r.Error
+}
+
+func (s *Storage) publishTransfer(from, to ChoreAssignment) {
+	if s.Events == nil {
+		return
+	}
+	s.Events.Publish(Event{
+		Type:       TaskTransferred,
+		Assignment: &from,
+	})
+	s.Events.Publish(Event{
+		Type:       TaskAcked,
+		Assignment: &to,
+	})
+}
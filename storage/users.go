@@ -120,7 +120,13 @@ func (s *Storage) GetUserStats() (UserChoreStats, error) {
 			TotalMin: float64(r.TotalTime),
 		}
 	}
-	return stats, nil
+
+	// Finished shifts are credited as work.
+	shiftStats, err := s.getShiftStats(true)
+	if err != nil {
+		return stats, err
+	}
+	return stats.Add(shiftStats), nil
 }
 
 func (s *Storage) GetAssignedStats() (UserChoreStats, error) {
@@ -131,7 +137,7 @@ func (s *Storage) GetAssignedStats() (UserChoreStats, error) {
 	}
 	var results []result
 	stats := UserChoreStats{}
-	r := s.db.Model(&ChoreAssignment{}).Select("user_id, sum(chores.estimated_time_min) as total_time, count(*) as total_count").Joins("left join chores on chore_assignments.chore_id = chores.id").Where("refused IS NULL and timeouted IS NULL and chores.completed IS NULL and chores.cancelled IS NULL").Group("user_id").Find(&results)
+	r := s.db.Model(&ChoreAssignment{}).Select("user_id, sum(chores.estimated_time_min) as total_time, count(*) as total_count").Joins("left join chores on chore_assignments.chore_id = chores.id").Where("refused IS NULL and timeouted IS NULL and transferred IS NULL and released IS NULL and chores.completed IS NULL and chores.cancelled IS NULL").Group("user_id").Find(&results)
 	if r.Error != nil {
 		return stats, r.Error
 	}
@@ -141,7 +147,31 @@ func (s *Storage) GetAssignedStats() (UserChoreStats, error) {
 			TotalMin: float64(r.TotalTime),
 		}
 	}
-	return stats, nil
+
+	// Upcoming and running shifts count as assigned work.
+	shiftStats, err := s.getShiftStats(false)
+	if err != nil {
+		return stats, err
+	}
+	return stats.Add(shiftStats), nil
+}
+
+// GetOpenAssignmentCounts returns the number of assigned or acked chores which are not finished yet per user.
+func (s *Storage) GetOpenAssignmentCounts() (map[string]int, error) {
+	type result struct {
+		UserId string
+		Count  int
+	}
+	var results []result
+	counts := make(map[string]int)
+	r := s.db.Model(&ChoreAssignment{}).Select("user_id, count(*) as count").Joins("left join chores on chore_assignments.chore_id = chores.id").Where("refused IS NULL and timeouted IS NULL and transferred IS NULL and released IS NULL and chores.completed IS NULL and chores.cancelled IS NULL").Group("user_id").Find(&results)
+	if r.Error != nil {
+		return counts, r.Error
+	}
+	for _, res := range results {
+		counts[res.UserId] = res.Count
+	}
+	return counts, nil
 }
 
 func (s *Storage) GetTotalChoreStats() (UserChoreStats, error) {
@@ -196,21 +226,8 @@ func (s *Storage) AssignChore(chore Chore, userId string) (ChoreAssignment, erro
 func (s *Storage) SaveChoreAssignment(ca ChoreAssignment) (ChoreAssignment, error) {
 	isNew := ca.ID == 0
 	r := s.db.Save(&ca)
-	if r.Error == nil && s.Events != nil {
-		eventType := TaskUpdated
-		if isNew {
-			eventType = TaskAssigned
-		} else if ca.Acked != nil {
-			eventType = TaskAcked
-		} else if ca.Refused != nil {
-			eventType = TaskRefused
-		} else if ca.Timeouted != nil {
-			eventType = TaskTimeout
-		}
-		s.Events.Publish(Event{
-			Type:       eventType,
-			Assignment: &ca,
-		})
+	if r.Error == nil {
+		s.publishAssignment(ca, isNew)
 	}
 	return ca, r.Error
 }
@@ -231,9 +248,10 @@ func (s *Storage) SaveChoreAssignments(assignments []ChoreAssignment) ([]ChoreAs
 	return assignments, r.Error
 }
 
-func (s *Storage) GetChoresAssignments() ([]ChoreAssignment, error) {
+// GetChoresAssignments returns the assignments of the given chores.
+func (s *Storage) GetChoresAssignments(choreIds []uint) ([]ChoreAssignment, error) {
 	var assignments []ChoreAssignment
-	r := s.db.Preload(clause.Associations).Find(&assignments)
+	r := s.db.Preload(clause.Associations).Where("chore_id IN ?", choreIds).Find(&assignments)
 	return assignments, r.Error
 }
 
@@ -254,6 +272,11 @@ func (s *Storage) RemoveStorageAssignments(choreId uint) error {
 	return r.Error
 }
 
+func (s *Storage) RemoveChoreAssignment(ca ChoreAssignment) error {
+	r := s.db.Delete(&ca)
+	return r.Error
+}
+
 func (s *Storage) LogUserPresence(userId string) (PresenceLog, error) {
 	log := PresenceLog{
 		UserId:    userId,
@@ -279,3 +302,32 @@ func (s *Storage) GetUsersPresenceCounts() (map[string]int, error) {
 	}
 	return counts, r.Error
 }
+
+// GetCoworkCounts returns how many chores completed since the given time each pair of users worked on together.
+func (s *Storage) GetCoworkCounts(since time.Time) (map[string]map[string]int, error) {
+	var logs []WorkLog
+	counts := map[string]map[string]int{}
+	r := s.db.Joins("JOIN chores ON chores.id = work_logs.chore_id").Where("chores.completed >= ?", since).Find(&logs)
+	if r.Error != nil {
+		return counts, r.Error
+	}
+
+	workers := map[uint][]string{}
+	for _, wl := range logs {
+		workers[wl.ChoreId] = append(workers[wl.ChoreId], wl.UserId)
+	}
+	for _, users := range workers {
+		for _, a := range users {
+			for _, b := range users {
+				if a == b {
+					continue
+				}
+				if counts[a] == nil {
+					counts[a] = map[string]int{}
+				}
+				counts[a][b]++
+			}
+		}
+	}
+	return counts, nil
+}
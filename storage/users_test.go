@@ -0,0 +1,139 @@
+package storage
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGetCoworkCounts(t *testing.T) {
+	s := createTestStorage(t)
+
+	recent := time.Now().Add(-time.Hour)
+	old := time.Now().Add(-72 * time.Hour)
+	for _, c := range []struct {
+		completed time.Time
+		workers   []string
+	}{
+		{recent, []string{"u1", "u2"}},
+		{recent, []string{"u1", "u2", "u3"}},
+		{old, []string{"u2", "u3"}},
+	} {
+		chore, err := s.SaveChore(Chore{Name: "Chore", Completed: &c.completed})
+		if err != nil {
+			t.Fatalf("Error saving chore: %v", err)
+		}
+		for _, w := range c.workers {
+			if _, err := s.SaveWorkLog(WorkLog{ChoreId: chore.ID, UserId: w, TimeSpentMin: 10}); err != nil {
+				t.Fatalf("Error saving work log: %v", err)
+			}
+		}
+	}
+
+	counts, err := s.GetCoworkCounts(time.Now().Add(-24 * time.Hour))
+	if err != nil {
+		t.Fatalf("Error getting cowork counts: %v", err)
+	}
+	if counts["u1"]["u2"] != 2 || counts["u2"]["u1"] != 2 {
+		t.Errorf("Expected u1 and u2 to work together twice, got %v", counts)
+	}
+	if counts["u2"]["u3"] != 1 {
+		t.Errorf("Expected the old chore not to count, got %v", counts)
+	}
+}
+
+func TestUnavailableUntil(t *testing.T) {
+	s := createTestStorage(t)
+
+	now := time.Now().UTC()
+	// An away block running into the quiet hours keeps the user unavailable until they end.
+	start := now.Add(time.Hour)
+	_, err := s.SaveUserSettings(UserSettings{UserId: "u1", QuietStart: start.Format("15:04"), QuietEnd: start.Add(time.Hour).Format("15:04")})
+	if err != nil {
+		t.Fatalf("Error saving settings: %v", err)
+	}
+	_, err = s.SaveUserAvailability(UserAvailability{UserId: "u1", From: now.Add(-time.Minute), To: now.Add(90 * time.Minute)})
+	if err != nil {
+		t.Fatalf("Error saving availability: %v", err)
+	}
+
+	until, unavailable, err := s.UnavailableUntil("u1", now)
+	if err != nil {
+		t.Fatalf("Error getting availability: %v", err)
+	}
+	expected := start.Add(time.Hour).Truncate(time.Minute)
+	if !unavailable || !until.Equal(expected) {
+		t.Errorf("Expected unavailable until %v, got %v %v", expected, unavailable, until)
+	}
+
+	users, err := s.GetUnavailableUsers(now)
+	if err != nil {
+		t.Fatalf("Error getting unavailable users: %v", err)
+	}
+	if _, ok := users["u1"]; !ok || len(users) != 1 {
+		t.Errorf("Expected only u1 to be unavailable, got %v", users)
+	}
+}
+
+func TestShiftsCreditedAsWork(t *testing.T) {
+	s := createTestStorage(t)
+
+	duty, err := s.SaveDuty(Duty{Name: "kitchen", WorkersPerShift: 1})
+	if err != nil {
+		t.Fatalf("Error saving duty: %v", err)
+	}
+	now := time.Now()
+	for _, sh := range []Shift{
+		{DutyId: duty.ID, UserId: "u1", Start: now.Add(-3 * time.Hour), End: now.Add(-2 * time.Hour)},
+		{DutyId: duty.ID, UserId: "u2", Start: now.Add(10 * time.Minute), End: now.Add(130 * time.Minute)},
+	} {
+		if _, err := s.SaveShift(sh); err != nil {
+			t.Fatalf("Error saving shift: %v", err)
+		}
+	}
+
+	worked, err := s.GetUserStats()
+	if err != nil {
+		t.Fatalf("Error getting user stats: %v", err)
+	}
+	if worked["u1"].TotalMin != 60 || worked["u2"].TotalMin != 0 {
+		t.Errorf("Expected only the finished shift to be worked, got %v", worked)
+	}
+	assigned, err := s.GetAssignedStats()
+	if err != nil {
+		t.Fatalf("Error getting assigned stats: %v", err)
+	}
+	if assigned["u2"].TotalMin != 120 {
+		t.Errorf("Expected the upcoming shift to be assigned, got %v", assigned)
+	}
+
+	busy, err := s.GetBusyUsers(now.Add(time.Hour), now.Add(2*time.Hour))
+	if err != nil || len(busy) != 1 || busy[0] != "u2" {
+		t.Errorf("Expected u2 to be busy, got %v (%v)", busy, err)
+	}
+
+	upcoming, err := s.GetUpcomingShifts(0)
+	if err != nil || len(upcoming) != 1 || upcoming[0].Duty.Name != "kitchen" {
+		t.Errorf("Expected one upcoming kitchen shift, got %v (%v)", upcoming, err)
+	}
+	toRemind, err := s.GetShiftsToRemind(now.Add(30 * time.Minute))
+	if err != nil || len(toRemind) != 1 {
+		t.Errorf("Expected one shift to remind, got %v (%v)", toRemind, err)
+	}
+}
+
+func TestStartedShiftIsNotReminded(t *testing.T) {
+	s := createTestStorage(t)
+
+	duty, err := s.SaveDuty(Duty{Name: "kitchen", WorkersPerShift: 1})
+	if err != nil {
+		t.Fatalf("Error saving duty: %v", err)
+	}
+	now := time.Now()
+	if _, err := s.SaveShift(Shift{DutyId: duty.ID, UserId: "u1", Start: now.Add(-10 * time.Minute), End: now.Add(time.Hour)}); err != nil {
+		t.Fatalf("Error saving shift: %v", err)
+	}
+	toRemind, err := s.GetShiftsToRemind(now.Add(30 * time.Minute))
+	if err != nil || len(toRemind) != 0 {
+		t.Errorf("Expected no reminder for a running shift, got %v (%v)", toRemind, err)
+	}
+}
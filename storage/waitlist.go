@@ -0,0 +1,133 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var ErrWaitlisted = errors.New("the chore already has enough acked workers, you are on its waitlist")
+
+// WaitlistedError tells the user where on the waitlist of the chore they are.
+type WaitlistedError struct {
+	ChoreId  uint
+	Position int
+}
+
+func (e *WaitlistedError) Error() string {
+	return fmt.Sprintf("chore `id: %d`: %s at position %d", e.ChoreId, ErrWaitlisted, e.Position)
+}
+
+func (e *WaitlistedError) Unwrap() error {
+	return ErrWaitlisted
+}
+
+// joinWaitlist puts the user at the end of the waitlist unless they are on it already and returns their position.
+func joinWaitlist(tx *gorm.DB, choreId uint, userId string) (int, error) {
+	entry := WaitlistEntry{ChoreId: choreId, UserId: userId}
+	if err := tx.Where(&entry).Attrs(WaitlistEntry{Created: time.Now()}).FirstOrCreate(&entry).Error; err != nil {
+		return 0, err
+	}
+	var ahead int64
+	err := tx.Model(&WaitlistEntry{}).
+		Where("chore_id = ? AND (created < ? OR (created = ? AND id < ?))", choreId, entry.Created, entry.Created, entry.ID).
+		Count(&ahead).Error
+	return int(ahead) + 1, err
+}
+
+// GetWaitlist returns the users waiting for a slot on the chore, first in line first.
+func (s *Storage) GetWaitlist(choreId uint) ([]WaitlistEntry, error) {
+	var entries []WaitlistEntry
+	r := s.db.Where("chore_id = ?", choreId).Order("created ASC").Order("id ASC").Find(&entries)
+	return entries, r.Error
+}
+
+// GetWaitlists returns the waitlists of the given chores, first in line first.
+func (s *Storage) GetWaitlists(choreIds []uint) ([]WaitlistEntry, error) {
+	var entries []WaitlistEntry
+	r := s.db.Where("chore_id IN ?", choreIds).Order("created ASC").Order("id ASC").Find(&entries)
+	return entries, r.Error
+}
+
+// LeaveWaitlist takes the user off the waitlist of the chore, it reports whether they were on it.
+func (s *Storage) LeaveWaitlist(choreId uint, userId string) (bool, error) {
+	r := s.db.Where("chore_id = ? AND user_id = ?", choreId, userId).Delete(&WaitlistEntry{})
+	return r.RowsAffected > 0, r.Error
+}
+
+// ClearWaitlist drops the waitlist of a finished chore.
+func (s *Storage) ClearWaitlist(choreId uint) error {
+	return s.db.Where("chore_id = ?", choreId).Delete(&WaitlistEntry{}).Error
+}
+
+// PromoteWaitlist acks the first users on the waitlist while the chore has fewer acked workers than it needs.
+// check gets the current chore to validate and update its status before anybody is promoted.
+func (s *Storage) PromoteWaitlist(choreId uint, check func(*Chore) error) ([]ChoreAssignment, error) {
+	promoted := []ChoreAssignment{}
+	created := map[uint]bool{}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var chore Chore
+		if err := tx.First(&chore, choreId).Error; err != nil {
+			return err
+		}
+		var acked int64
+		if err := activeAssignments(tx, choreId).Where("acked IS NOT NULL").Count(&acked).Error; err != nil {
+			return err
+		}
+		slots := int(max(chore.NecessaryWorkers, 1)) - int(acked)
+		if slots <= 0 {
+			return nil
+		}
+		var entries []WaitlistEntry
+		if err := tx.Where("chore_id = ?", choreId).Order("created ASC").Order("id ASC").Limit(slots).Find(&entries).Error; err != nil {
+			return err
+		}
+		if len(entries) == 0 {
+			return nil
+		}
+		if err := check(&chore); err != nil {
+			return err
+		}
+
+		for _, e := range entries {
+			var ca ChoreAssignment
+			r := tx.Where("chore_id = ? AND user_id = ?", choreId, e.UserId).Limit(1).Find(&ca)
+			if r.Error != nil {
+				return r.Error
+			}
+			if r.RowsAffected == 0 || !ca.Active() {
+				ca.ChoreId = choreId
+				ca.UserId = e.UserId
+				ca.Created = time.Now()
+				ca.Refused = nil
+				ca.Timeouted = nil
+				ca.Transferred = nil
+				ca.Released = nil
+				ca.Volunteered = true
+			}
+			ca.Ack()
+			if err := tx.Omit("Chore").Save(&ca).Error; err != nil {
+				return err
+			}
+			if err := tx.Delete(&e).Error; err != nil {
+				return err
+			}
+			created[ca.ID] = r.RowsAffected == 0
+			ca.Chore = chore
+			promoted = append(promoted, ca)
+		}
+		return saveChoreStatus(tx, &chore)
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, ca := range promoted {
+		if created[ca.ID] {
+			s.publishAssignment(ca, true)
+		}
+		s.publishAssignment(ca, false)
+	}
+	return promoted, nil
+}
@@ -0,0 +1,106 @@
+package storage
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SaveWebhook stores the subscription, a new one without a secret gets a random one.
+func (s *Storage) SaveWebhook(w Webhook) (Webhook, error) {
+	if w.Secret == "" {
+		secret, err := newSecret("whs_")
+		if err != nil {
+			return w, err
+		}
+		w.Secret = secret
+	}
+	if w.Created.IsZero() {
+		w.Created = time.Now()
+	}
+	r := s.db.Save(&w)
+	return w, r.Error
+}
+
+func (s *Storage) GetWebhook(id uint) (Webhook, error) {
+	var w Webhook
+	r := s.db.First(&w, id)
+	return w, r.Error
+}
+
+func (s *Storage) GetWebhooks() ([]Webhook, error) {
+	var webhooks []Webhook
+	r := s.db.Order("id ASC").Find(&webhooks)
+	return webhooks, r.Error
+}
+
+// RemoveWebhook drops the subscription together with its delivery log.
+func (s *Storage) RemoveWebhook(id uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		r := tx.Delete(&Webhook{}, id)
+		if r.Error != nil {
+			return r.Error
+		}
+		if r.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return tx.Where("webhook_id = ?", id).Delete(&WebhookDelivery{}).Error
+	})
+}
+
+// QueueDelivery schedules the payload to be sent to the webhook right away.
+func (s *Storage) QueueDelivery(webhookId uint, eventType EventType, payload string) (WebhookDelivery, error) {
+	now := time.Now()
+	d := WebhookDelivery{
+		WebhookId:   webhookId,
+		EventType:   eventType,
+		Payload:     payload,
+		Status:      DeliveryPending,
+		NextAttempt: now,
+		Created:     now,
+	}
+	r := s.db.Create(&d)
+	return d, r.Error
+}
+
+func (s *Storage) SaveDelivery(d WebhookDelivery) (WebhookDelivery, error) {
+	r := s.db.Save(&d)
+	return d, r.Error
+}
+
+func (s *Storage) GetDelivery(id uint) (WebhookDelivery, error) {
+	var d WebhookDelivery
+	r := s.db.First(&d, id)
+	return d, r.Error
+}
+
+// GetDueDeliveries returns the pending deliveries whose next attempt has come, oldest first.
+func (s *Storage) GetDueDeliveries(now time.Time) ([]WebhookDelivery, error) {
+	var deliveries []WebhookDelivery
+	r := s.db.Where("status = ? AND next_attempt <= ?", DeliveryPending, now).Order("id ASC").Find(&deliveries)
+	return deliveries, r.Error
+}
+
+// GetDeliveries returns the delivery log of the webhook, newest first.
+func (s *Storage) GetDeliveries(webhookId uint, status DeliveryStatus, limit int) ([]WebhookDelivery, error) {
+	var deliveries []WebhookDelivery
+	q := s.db.Where("webhook_id = ?", webhookId)
+	if status != "" {
+		q = q.Where("status = ?", status)
+	}
+	r := q.Order("id DESC").Limit(limit).Find(&deliveries)
+	return deliveries, r.Error
+}
+
+// RetryDelivery sends a delivery again from scratch, e.g. a dead-lettered one once the receiver is fixed.
+func (s *Storage) RetryDelivery(id uint) (WebhookDelivery, error) {
+	d, err := s.GetDelivery(id)
+	if err != nil {
+		return d, err
+	}
+	d.Status = DeliveryPending
+	d.Attempts = 0
+	d.NextAttempt = time.Now()
+	d.LastError = ""
+	return s.SaveDelivery(d)
+}
@@ -0,0 +1,191 @@
+package ui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/gdg-garage/garage-trip-chores/chores"
+	"github.com/gdg-garage/garage-trip-chores/storage"
+)
+
+// PlaceBid offers to do the auctioned chore for the given minutes of credit, a new bid replaces the previous one.
+func (ui *Ui) PlaceBid(choreId uint, userId string, timeMin uint) (storage.Bid, error) {
+	bid := storage.Bid{
+		ChoreId: choreId,
+		UserId:  userId,
+		TimeMin: timeMin,
+		Created: time.Now(),
+	}
+	c, err := ui.storage.GetChore(choreId)
+	if err != nil {
+		return bid, fmt.Errorf("failed to get chore: %w", err)
+	}
+	if !c.AuctionOpen() || c.Completed != nil || c.Cancelled != nil {
+		return bid, fmt.Errorf("chore `id: %d`: %w", choreId, chores.ErrAuctionNotOpen)
+	}
+	if timeMin == 0 {
+		return bid, fmt.Errorf("the bid has to be at least 1 minute")
+	}
+
+	bid, err = ui.storage.SaveBid(bid)
+	if err != nil {
+		return bid, fmt.Errorf("failed to save bid: %w", err)
+	}
+
+	_ = ui.UpdateChoreMessage(c)
+	ui.EmitChoreEvent("chore_bid", c)
+	return bid, nil
+}
+
+// CloseAuction assigns the chore to the lowest bidders, who get their bids credited.
+// Slots nobody bid on are filled by the normal assignment.
+func (ui *Ui) CloseAuction(choreId uint) (storage.Chore, []storage.ChoreAssignment, error) {
+	assigned := []storage.ChoreAssignment{}
+	// Closing is atomic, the reminders and the API can race to close the same auction.
+	c, err := ui.storage.UpdateChore(choreId, func(c *storage.Chore) error {
+		if !c.AuctionOpen() {
+			return fmt.Errorf("chore `id: %d`: %w", choreId, chores.ErrAuctionNotOpen)
+		}
+		c.AuctionClosed = true
+		return nil
+	})
+	if err != nil {
+		return c, assigned, err
+	}
+
+	bids := 0
+	assigned, err = ui.storage.AwardAuction(c.ID, func(all []storage.Bid, holders []string) []storage.Bid {
+		bids = len(all)
+		return chores.AuctionWinners(all, int(c.NecessaryWorkers)-len(holders), holders)
+	})
+	if err != nil {
+		return c, assigned, fmt.Errorf("failed to award the auction: %w", err)
+	}
+	if ui.discord != nil {
+		for _, a := range assigned {
+			_ = ui.SendDM(a.UserId, &discordgo.MessageSend{
+				Content: fmt.Sprintf("You won the auction for chore `id: %d` `%s` with `%d` minutes %s.", c.ID, c.Name, a.BidMin, ui.GetChoreMessageUrl(c)),
+			})
+		}
+	}
+	if len(assigned) > 0 {
+		c, err = ui.transitionChore(c.ID, storage.ChoreInProgress)
+		if err != nil {
+			return c, assigned, err
+		}
+	}
+	ui.logger.Info("Chore auction closed", "chore_id", c.ID, "bids", bids, "winners", len(assigned))
+
+	users, err := ui.storage.GetPresentUsers()
+	if err != nil {
+		ui.logger.Error("Error getting present users", "error", err)
+	} else {
+		_, err = ui.chores.AssignChoresToUsers(users, c)
+		if err != nil {
+			ui.logger.Error("Error assigning chores to users", "error", err, "chore_id", c.ID)
+		}
+	}
+
+	_ = ui.UpdateChoreMessage(c)
+	ui.EmitChoreEvent("chore_auction_closed", c)
+	return c, assigned, nil
+}
+
+func (ui *Ui) generateBidsEmbed(bids []storage.Bid) *discordgo.MessageEmbed {
+	if len(bids) == 0 {
+		return nil
+	}
+	md := ""
+	for _, b := range bids {
+		md += fmt.Sprintf("* <@%s>: %d min\n", b.UserId, b.TimeMin)
+	}
+	return &discordgo.MessageEmbed{
+		Type:        discordgo.EmbedTypeRich,
+		Title:       "Bids",
+		Description: md,
+		Color:       ui.colors.OrangeColor,
+	}
+}
+
+func auctionButtons(choreId uint) discordgo.ActionsRow {
+	return discordgo.ActionsRow{
+		Components: []discordgo.MessageComponent{
+			&discordgo.Button{
+				Style:    discordgo.PrimaryButton,
+				Label:    "Bid",
+				CustomID: BidButtonClick + fmt.Sprint(choreId),
+			},
+		},
+	}
+}
+
+func (ui *Ui) bidButtonClick(d string, s *discordgo.Session, i *discordgo.InteractionCreate) {
+	failedText := "Failed to place a bid."
+
+	choreId, err := getChoreIdFromCustomID(d)
+	if err != nil {
+		ui.logger.Error("failed to parse chore ID from button", "error", err, "custom_id", d)
+		s.InteractionRespond(i.Interaction, ui.errorInteractionResponse(failedText))
+		return
+	}
+	err = ui.discord.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseModal,
+		Data: &discordgo.InteractionResponseData{
+			CustomID: BidModal + fmt.Sprint(choreId),
+			Title:    fmt.Sprintf("Bid on chore %d", choreId),
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{
+					Components: []discordgo.MessageComponent{
+						&discordgo.TextInput{
+							CustomID:    "bid_min",
+							Label:       "Credit you want (minutes)",
+							Style:       discordgo.TextInputShort,
+							MinLength:   1,
+							MaxLength:   4,
+							Placeholder: "The lowest bids win",
+							Required:    true,
+						},
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		ui.logger.Error("failed to send modal", "error", err)
+		s.InteractionRespond(i.Interaction, ui.errorInteractionResponse(failedText))
+		return
+	}
+}
+
+func (ui *Ui) placeBid(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	failedText := "Failed to place a bid."
+	data := i.Interaction.ModalSubmitData()
+
+	choreId, err := getChoreIdFromCustomID(data.CustomID)
+	if err != nil {
+		ui.logger.Error("failed to parse chore ID from modal", "error", err, "custom_id", data.CustomID)
+		s.InteractionRespond(i.Interaction, ui.errorInteractionResponse(failedText))
+		return
+	}
+
+	bidStr := strings.TrimSpace(data.Components[0].(*discordgo.ActionsRow).Components[0].(*discordgo.TextInput).Value)
+	bidMin, err := strconv.Atoi(bidStr)
+	if err != nil || bidMin <= 0 {
+		s.InteractionRespond(i.Interaction, ui.errorInteractionResponse(fmt.Sprintf("`%s` is not a valid number of minutes.", bidStr)))
+		return
+	}
+
+	userId := interactionUserId(i)
+	_, err = ui.PlaceBid(choreId, userId, uint(bidMin))
+	if err != nil {
+		ui.logger.Error("failed to place bid", "error", err, "chore_id", choreId, "user_id", userId)
+		s.InteractionRespond(i.Interaction, ui.errorInteractionResponse(err.Error()))
+		return
+	}
+
+	r := simpleContainerizedInteractionResponse(fmt.Sprintf("You bid `%d` min on chore `id: %d`.", bidMin, choreId), &ui.colors.GreenColor)
+	s.InteractionRespond(i.Interaction, r)
+}
@@ -0,0 +1,246 @@
+package ui
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/gdg-garage/garage-trip-chores/storage"
+)
+
+const clockLayout = "15:04"
+
+// SetQuietHours sets the daily window ("HH:MM") in which the user is neither assigned nor reminded, empty values clear it.
+func (ui *Ui) SetQuietHours(userId, start, end string) (storage.UserSettings, error) {
+	settings, err := ui.storage.GetUserSettings(userId)
+	if err != nil {
+		return settings, fmt.Errorf("failed to get user settings: %w", err)
+	}
+	if (start == "") != (end == "") {
+		return settings, fmt.Errorf("quiet hours need both start and end")
+	}
+	for _, t := range []string{start, end} {
+		if _, err := time.Parse(clockLayout, t); t != "" && err != nil {
+			return settings, fmt.Errorf("invalid time `%s`, use HH:MM", t)
+		}
+	}
+	if start != "" && start == end {
+		return settings, fmt.Errorf("quiet hours need to end at a different time than they start")
+	}
+
+	settings.QuietStart = start
+	settings.QuietEnd = end
+	settings, err = ui.storage.SaveUserSettings(settings)
+	if err != nil {
+		return settings, fmt.Errorf("failed to save user settings: %w", err)
+	}
+	return settings, nil
+}
+
+// SetPreferences replaces the user's liked and disliked chore categories or skills, nil keeps the list.
+func (ui *Ui) SetPreferences(userId string, likes, dislikes []string) (storage.UserSettings, error) {
+	settings, err := ui.storage.GetUserSettings(userId)
+	if err != nil {
+		return settings, fmt.Errorf("failed to get user settings: %w", err)
+	}
+	if likes != nil {
+		settings.SetLikes(likes)
+	}
+	if dislikes != nil {
+		settings.SetDislikes(dislikes)
+	}
+	for _, t := range settings.GetLikes() {
+		if slices.Contains(settings.GetDislikes(), t) {
+			return settings, fmt.Errorf("`%s` cannot be both liked and disliked", t)
+		}
+	}
+
+	settings, err = ui.storage.SaveUserSettings(settings)
+	if err != nil {
+		return settings, fmt.Errorf("failed to save user settings: %w", err)
+	}
+	return settings, nil
+}
+
+// AddAway marks the user unavailable for the given time span.
+func (ui *Ui) AddAway(userId string, from, to time.Time, reason string) (storage.UserAvailability, error) {
+	block := storage.UserAvailability{
+		UserId: userId,
+		From:   from,
+		To:     to,
+		Reason: reason,
+	}
+	if !to.After(from) {
+		return block, fmt.Errorf("the end of the block has to be after its start")
+	}
+	block, err := ui.storage.SaveUserAvailability(block)
+	if err != nil {
+		return block, fmt.Errorf("failed to save availability: %w", err)
+	}
+	return block, nil
+}
+
+// ClearAway drops the user's current and upcoming away blocks.
+func (ui *Ui) ClearAway(userId string) error {
+	err := ui.storage.RemoveUserAvailabilities(userId)
+	if err != nil {
+		return fmt.Errorf("failed to remove availability: %w", err)
+	}
+	return nil
+}
+
+// ParseAwaySpan turns "HH:MM" times into the next span they describe, the end wraps over midnight.
+func ParseAwaySpan(from, to string, now time.Time, loc *time.Location) (time.Time, time.Time, error) {
+	f, err := time.Parse(clockLayout, from)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid time `%s`, use HH:MM", from)
+	}
+	t, err := time.Parse(clockLayout, to)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid time `%s`, use HH:MM", to)
+	}
+
+	ln := now.In(loc)
+	start := time.Date(ln.Year(), ln.Month(), ln.Day(), f.Hour(), f.Minute(), 0, 0, loc)
+	end := time.Date(ln.Year(), ln.Month(), ln.Day(), t.Hour(), t.Minute(), 0, 0, loc)
+	if !end.After(start) {
+		end = end.AddDate(0, 0, 1)
+	}
+	// A span which is already over means the next day.
+	if !end.After(now) {
+		start = start.AddDate(0, 0, 1)
+		end = end.AddDate(0, 0, 1)
+	}
+	return start, end, nil
+}
+
+func (ui *Ui) availabilityMd(userId string) string {
+	md := "### Availability\n"
+	settings, err := ui.storage.GetUserSettings(userId)
+	if err != nil {
+		ui.logger.Error("failed to get user settings", "error", err, "user_id", userId)
+	}
+	if settings.QuietStart != "" {
+		md += fmt.Sprintf("**Quiet hours**: `%s` - `%s`\n", settings.QuietStart, settings.QuietEnd)
+	} else {
+		md += "**Quiet hours**: none\n"
+	}
+
+	blocks, err := ui.storage.GetUserAvailabilities(userId)
+	if err != nil {
+		ui.logger.Error("failed to get user availabilities", "error", err, "user_id", userId)
+	}
+	for _, b := range blocks {
+		md += fmt.Sprintf("* Away %s - %s", b.From.In(ui.storage.Location()).Format(time.RFC822), b.To.In(ui.storage.Location()).Format(time.RFC822))
+		if b.Reason != "" {
+			md += fmt.Sprintf(" (%s)", b.Reason)
+		}
+		md += "\n"
+	}
+	return md
+}
+
+func (ui *Ui) preferencesMd(userId string) string {
+	settings, err := ui.storage.GetUserSettings(userId)
+	if err != nil {
+		ui.logger.Error("failed to get user settings", "error", err, "user_id", userId)
+	}
+	md := "### Preferences\n"
+	for _, p := range []struct {
+		label string
+		tags  []string
+	}{{"Likes", settings.GetLikes()}, {"Dislikes", settings.GetDislikes()}} {
+		if len(p.tags) > 0 {
+			md += fmt.Sprintf("**%s**: `%s`\n", p.label, strings.Join(p.tags, ", "))
+		} else {
+			md += fmt.Sprintf("**%s**: none\n", p.label)
+		}
+	}
+	return md
+}
+
+func (ui *Ui) preferences(i *discordgo.InteractionCreate) {
+	userId := i.Interaction.Member.User.ID
+	var likes, dislikes []string
+	for _, opt := range i.ApplicationCommandData().Options {
+		// "-" clears the list.
+		tags := []string{}
+		if opt.StringValue() != "-" {
+			tags = strings.Split(opt.StringValue(), ",")
+		}
+		switch opt.Name {
+		case "likes":
+			likes = tags
+		case "dislikes":
+			dislikes = tags
+		}
+	}
+
+	_, err := ui.SetPreferences(userId, likes, dislikes)
+	if err != nil {
+		ui.logger.Error("failed to set preferences", "error", err, "user_id", userId)
+		ui.discord.InteractionRespond(i.Interaction, ui.errorInteractionResponse(err.Error()))
+		return
+	}
+	ui.discord.InteractionRespond(i.Interaction, simpleContainerizedInteractionResponse(ui.preferencesMd(userId), &ui.colors.GreenColor))
+}
+
+func (ui *Ui) quietHours(i *discordgo.InteractionCreate) {
+	userId := i.Interaction.Member.User.ID
+	start, end := "", ""
+	for _, opt := range i.ApplicationCommandData().Options {
+		switch opt.Name {
+		case "start":
+			start = opt.StringValue()
+		case "end":
+			end = opt.StringValue()
+		}
+	}
+
+	_, err := ui.SetQuietHours(userId, start, end)
+	if err != nil {
+		ui.logger.Error("failed to set quiet hours", "error", err, "user_id", userId)
+		ui.discord.InteractionRespond(i.Interaction, ui.errorInteractionResponse(err.Error()))
+		return
+	}
+	ui.discord.InteractionRespond(i.Interaction, simpleContainerizedInteractionResponse(ui.availabilityMd(userId), &ui.colors.GreenColor))
+}
+
+func (ui *Ui) away(i *discordgo.InteractionCreate) {
+	userId := i.Interaction.Member.User.ID
+	from, to, reason := "", "", ""
+	for _, opt := range i.ApplicationCommandData().Options {
+		switch opt.Name {
+		case "from":
+			from = opt.StringValue()
+		case "to":
+			to = opt.StringValue()
+		case "reason":
+			reason = opt.StringValue()
+		}
+	}
+
+	start, end, err := ParseAwaySpan(from, to, time.Now(), ui.storage.Location())
+	if err == nil {
+		_, err = ui.AddAway(userId, start, end, reason)
+	}
+	if err != nil {
+		ui.logger.Error("failed to add away block", "error", err, "user_id", userId)
+		ui.discord.InteractionRespond(i.Interaction, ui.errorInteractionResponse(err.Error()))
+		return
+	}
+	ui.discord.InteractionRespond(i.Interaction, simpleContainerizedInteractionResponse(ui.availabilityMd(userId), &ui.colors.GreenColor))
+}
+
+func (ui *Ui) back(i *discordgo.InteractionCreate) {
+	userId := i.Interaction.Member.User.ID
+	err := ui.ClearAway(userId)
+	if err != nil {
+		ui.logger.Error("failed to clear away blocks", "error", err, "user_id", userId)
+		ui.discord.InteractionRespond(i.Interaction, ui.errorInteractionResponse("Failed to clear your away blocks."))
+		return
+	}
+	ui.discord.InteractionRespond(i.Interaction, simpleContainerizedInteractionResponse(ui.availabilityMd(userId), &ui.colors.GreenColor))
+}
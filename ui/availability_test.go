@@ -0,0 +1,30 @@
+package ui
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseAwaySpan(t *testing.T) {
+	loc := time.UTC
+	now := time.Date(2025, 7, 1, 12, 0, 0, 0, loc)
+
+	from, to, err := ParseAwaySpan("14:00", "18:00", now, loc)
+	if err != nil || !from.Equal(time.Date(2025, 7, 1, 14, 0, 0, 0, loc)) || !to.Equal(time.Date(2025, 7, 1, 18, 0, 0, 0, loc)) {
+		t.Errorf("Expected today 14:00 - 18:00, got %v - %v (%v)", from, to, err)
+	}
+
+	from, to, err = ParseAwaySpan("22:00", "02:00", now, loc)
+	if err != nil || !from.Equal(time.Date(2025, 7, 1, 22, 0, 0, 0, loc)) || !to.Equal(time.Date(2025, 7, 2, 2, 0, 0, 0, loc)) {
+		t.Errorf("Expected the span to wrap over midnight, got %v - %v (%v)", from, to, err)
+	}
+
+	from, _, err = ParseAwaySpan("08:00", "10:00", now, loc)
+	if err != nil || !from.Equal(time.Date(2025, 7, 2, 8, 0, 0, 0, loc)) {
+		t.Errorf("Expected a past span to mean tomorrow, got %v (%v)", from, err)
+	}
+
+	if _, _, err = ParseAwaySpan("soon", "10:00", now, loc); err == nil {
+		t.Error("Expected an invalid time to fail")
+	}
+}
@@ -0,0 +1,76 @@
+package ui
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/gdg-garage/garage-trip-chores/chores"
+	"github.com/gdg-garage/garage-trip-chores/storage"
+)
+
+// deferChore keeps the chore as a draft without assignments until its not before time.
+func (ui *Ui) deferChore(c storage.Chore) (storage.Chore, error) {
+	if err := chores.Transition(&c, storage.ChoreDraft); err != nil {
+		return c, err
+	}
+	c, err := ui.storage.SaveChore(c)
+	if err != nil {
+		return c, fmt.Errorf("failed to save chore: %w", err)
+	}
+	ui.logger.Info("Chore deferred", "chore_id", c.ID, "not_before", c.NotBefore)
+	return c, nil
+}
+
+// PublishDueDrafts publishes the drafts whose not before time has come.
+func (ui *Ui) PublishDueDrafts() {
+	drafts, err := ui.storage.GetDueDrafts(time.Now())
+	if err != nil {
+		ui.logger.Error("failed to get due drafts", "error", err)
+		return
+	}
+	for _, c := range drafts {
+		_, _, err = ui.PublishChore(c)
+		if err != nil {
+			ui.logger.Error("failed to publish draft", "error", err, "chore_id", c.ID)
+			continue
+		}
+		ui.logger.Info("Draft published", "chore_id", c.ID)
+	}
+}
+
+func (ui *Ui) choresDrafts(i *discordgo.InteractionCreate) {
+	drafts, err := ui.storage.GetDrafts()
+	if err != nil {
+		ui.logger.Error("failed to get drafts", "error", err)
+		ui.discord.InteractionRespond(i.Interaction, ui.errorInteractionResponse("Failed to get drafts."))
+		return
+	}
+	md := ""
+	for _, c := range drafts {
+		when := "not scheduled yet"
+		if c.NotBefore != nil {
+			when = "published at " + c.NotBefore.In(ui.storage.Location()).Format(time.RFC822)
+		}
+		md += fmt.Sprintf("* %s (id: `%d`) %s\n", c.Name, c.ID, when)
+	}
+	if md == "" {
+		md = "No drafts."
+	}
+
+	r := &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: "Here are the chores waiting to be published:",
+			Embeds: []*discordgo.MessageEmbed{
+				{
+					Title:       "Drafts",
+					Description: md,
+					Color:       ui.colors.OrangeColor,
+				},
+			},
+			Flags: discordgo.MessageFlagsEphemeral,
+		},
+	}
+	ui.discord.InteractionRespond(i.Interaction, r)
+}
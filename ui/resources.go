@@ -0,0 +1,114 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/gdg-garage/garage-trip-chores/storage"
+)
+
+// CreateResource adds a shared resource to the catalogue chores can require.
+func (ui *Ui) CreateResource(name, kind string) (storage.Resource, error) {
+	resource := storage.Resource{
+		Name:    name,
+		Kind:    strings.TrimSpace(kind),
+		Created: time.Now(),
+	}
+	if strings.TrimSpace(name) == "" || strings.Contains(name, ",") {
+		return resource, fmt.Errorf("the resource needs a name without commas")
+	}
+	resource, err := ui.storage.SaveResource(resource)
+	if err != nil {
+		return resource, fmt.Errorf("failed to save resource: %w", err)
+	}
+	return resource, nil
+}
+
+// CheckResources makes sure all the required resources are in the catalogue.
+func (ui *Ui) CheckResources(names []string) error {
+	if len(names) == 0 {
+		return nil
+	}
+	resources, err := ui.storage.GetResources()
+	if err != nil {
+		return fmt.Errorf("failed to get resources: %w", err)
+	}
+	known := map[string]struct{}{}
+	for _, r := range resources {
+		known[r.Name] = struct{}{}
+	}
+	for _, n := range names {
+		if _, ok := known[n]; !ok {
+			return fmt.Errorf("there is no resource `%s`", n)
+		}
+	}
+	return nil
+}
+
+func resourceConflictsMd(conflicts map[string]uint) string {
+	names := make([]string, 0, len(conflicts))
+	for name := range conflicts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	held := []string{}
+	for _, name := range names {
+		held = append(held, fmt.Sprintf("`%s` is held by chore `id: %d`", name, conflicts[name]))
+	}
+	return strings.Join(held, ", ")
+}
+
+// flagResourceConflicts lets the creator know the chore waits for resources held by other chores.
+func (ui *Ui) flagResourceConflicts(c storage.Chore) {
+	conflicts, err := ui.chores.ResourceConflicts(c)
+	if err != nil || len(conflicts) == 0 {
+		return
+	}
+	ui.logger.Info("chore waits for resources", "chore_id", c.ID, "conflicts", conflicts)
+	if ui.discord == nil || c.CreatorId == "" {
+		return
+	}
+	_ = ui.SendDM(c.CreatorId, &discordgo.MessageSend{
+		Content: fmt.Sprintf("Your chore `id: %d` `%s` waits in the queue, %s %s.", c.ID, c.Name, resourceConflictsMd(conflicts), ui.GetChoreMessageUrl(c)),
+	})
+}
+
+func (ui *Ui) resourcesMd() (string, error) {
+	resources, err := ui.storage.GetResources()
+	if err != nil {
+		return "", fmt.Errorf("failed to get resources: %w", err)
+	}
+	locks, err := ui.storage.GetResourceLocks()
+	if err != nil {
+		return "", fmt.Errorf("failed to get resource locks: %w", err)
+	}
+	md := "### Resources\n"
+	if len(resources) == 0 {
+		return md + "No resources in the catalogue.", nil
+	}
+	for _, r := range resources {
+		kind := ""
+		if r.Kind != "" {
+			kind = fmt.Sprintf(" (%s)", r.Kind)
+		}
+		state := "free ✅"
+		if holder, ok := locks[r.Name]; ok {
+			state = fmt.Sprintf("in use by chore `id: %d` 🔒", holder)
+		}
+		md += fmt.Sprintf("* **%s**%s: %s\n", r.Name, kind, state)
+	}
+	return md, nil
+}
+
+func (ui *Ui) resources(i *discordgo.InteractionCreate) {
+	md, err := ui.resourcesMd()
+	if err != nil {
+		ui.logger.Error("failed to generate resources", "error", err)
+		ui.discord.InteractionRespond(i.Interaction, ui.errorInteractionResponse("Failed to get the resources."))
+		return
+	}
+	ui.discord.InteractionRespond(i.Interaction, simpleContainerizedInteractionResponse(md, &ui.colors.GreenColor))
+}
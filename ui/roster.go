@@ -0,0 +1,153 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/gdg-garage/garage-trip-chores/storage"
+)
+
+// CreateDuty adds a duty shifts can be scheduled for.
+func (ui *Ui) CreateDuty(name string, workersPerShift uint) (storage.Duty, error) {
+	duty := storage.Duty{
+		Name:            strings.TrimSpace(name),
+		WorkersPerShift: max(workersPerShift, 1),
+		Created:         time.Now(),
+	}
+	if duty.Name == "" {
+		return duty, fmt.Errorf("the duty needs a name")
+	}
+	duty, err := ui.storage.SaveDuty(duty)
+	if err != nil {
+		return duty, fmt.Errorf("failed to save duty: %w", err)
+	}
+	return duty, nil
+}
+
+// ScheduleShifts creates the shift from start to end and its copies on the following days,
+// every shift is given to the least loaded present users who are available and not on another shift.
+func (ui *Ui) ScheduleShifts(dutyId uint, start, end time.Time, days uint) ([]storage.Shift, error) {
+	shifts := []storage.Shift{}
+	duty, err := ui.storage.GetDuty(dutyId)
+	if err != nil {
+		return shifts, fmt.Errorf("failed to get duty: %w", err)
+	}
+	if !end.After(start) {
+		return shifts, fmt.Errorf("the end of the shift has to be after its start")
+	}
+
+	users, err := ui.storage.GetPresentUsers()
+	if err != nil {
+		return shifts, fmt.Errorf("failed to get present users: %w", err)
+	}
+
+	for day := range max(days, 1) {
+		from := start.AddDate(0, 0, int(day))
+		to := end.AddDate(0, 0, int(day))
+
+		exclude, err := ui.storage.GetBusyUsers(from, to)
+		if err != nil {
+			return shifts, fmt.Errorf("failed to get busy users: %w", err)
+		}
+		unavailable, err := ui.storage.GetUnavailableUsers(from)
+		if err != nil {
+			return shifts, fmt.Errorf("failed to get unavailable users: %w", err)
+		}
+		for user := range unavailable {
+			exclude = append(exclude, user)
+		}
+
+		workers, err := ui.chores.SelectShiftWorkers(users, int(duty.WorkersPerShift), exclude)
+		if err != nil {
+			return shifts, fmt.Errorf("failed to select shift workers: %w", err)
+		}
+		if len(workers) < int(duty.WorkersPerShift) {
+			ui.logger.Warn("shift is understaffed", "duty_id", duty.ID, "start", from, "workers", len(workers))
+		}
+
+		for _, userId := range workers {
+			shift, err := ui.storage.SaveShift(storage.Shift{
+				DutyId: duty.ID,
+				UserId: userId,
+				Start:  from,
+				End:    to,
+			})
+			if err != nil {
+				return shifts, fmt.Errorf("failed to save shift: %w", err)
+			}
+			shift.Duty = duty
+			shifts = append(shifts, shift)
+
+			if ui.discord != nil {
+				_ = ui.SendDM(userId, &discordgo.MessageSend{
+					Content: fmt.Sprintf("You are on `%s` duty %s.", duty.Name, ui.shiftSpanMd(shift)),
+				})
+			}
+		}
+	}
+	return shifts, nil
+}
+
+func (ui *Ui) shiftSpanMd(shift storage.Shift) string {
+	loc := ui.storage.Location()
+	return fmt.Sprintf("%s - %s", shift.Start.In(loc).Format(time.RFC822), shift.End.In(loc).Format(time.RFC822))
+}
+
+// rosterMd lists the upcoming shifts, dutyId 0 means all duties.
+func (ui *Ui) rosterMd(dutyId uint) (string, error) {
+	shifts, err := ui.storage.GetUpcomingShifts(dutyId)
+	if err != nil {
+		return "", fmt.Errorf("failed to get shifts: %w", err)
+	}
+	md := "### Roster\n"
+	if len(shifts) == 0 {
+		return md + "No upcoming shifts.", nil
+	}
+
+	// Holders of the same duty slot are listed on one line.
+	for i := 0; i < len(shifts); {
+		j := i
+		holders := []string{}
+		for ; j < len(shifts) && shifts[j].DutyId == shifts[i].DutyId && shifts[j].Start.Equal(shifts[i].Start) && shifts[j].End.Equal(shifts[i].End); j++ {
+			holders = append(holders, fmt.Sprintf("<@%s>", shifts[j].UserId))
+		}
+		md += fmt.Sprintf("* **%s** %s: %s\n", shifts[i].Duty.Name, ui.shiftSpanMd(shifts[i]), strings.Join(holders, ", "))
+		i = j
+	}
+	return md, nil
+}
+
+func (ui *Ui) roster(i *discordgo.InteractionCreate) {
+	failedText := "Failed to get the roster."
+	dutyId := uint(0)
+	for _, opt := range i.ApplicationCommandData().Options {
+		if opt.Name != "duty" {
+			continue
+		}
+		duties, err := ui.storage.GetDuties()
+		if err != nil {
+			ui.logger.Error("failed to get duties", "error", err)
+			ui.discord.InteractionRespond(i.Interaction, ui.errorInteractionResponse(failedText))
+			return
+		}
+		for _, d := range duties {
+			if strings.EqualFold(d.Name, opt.StringValue()) {
+				dutyId = d.ID
+			}
+		}
+		if dutyId == 0 {
+			ui.discord.InteractionRespond(i.Interaction, ui.errorInteractionResponse(fmt.Sprintf("There is no duty `%s`.", opt.StringValue())))
+			return
+		}
+	}
+
+	md, err := ui.rosterMd(dutyId)
+	if err != nil {
+		ui.logger.Error("failed to generate roster", "error", err)
+		ui.discord.InteractionRespond(i.Interaction, ui.errorInteractionResponse(failedText))
+		return
+	}
+	ui.discord.InteractionRespond(i.Interaction, simpleContainerizedInteractionResponse(md, &ui.colors.GreenColor))
+}
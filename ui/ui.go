@@ -2,6 +2,7 @@ package ui
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"math"
@@ -40,6 +41,7 @@ type Ui struct {
 const (
 	ButtonClickSuffix    = "_button_click:"
 	AckButtonClick       = "ack" + ButtonClickSuffix
+	AckConfirmClick      = "ack_confirm" + ButtonClickSuffix
 	CancelButtonClick    = "cancel" + ButtonClickSuffix
 	DeleteButtonClick    = "delete" + ButtonClickSuffix
 	DoneButtonClick      = "done" + ButtonClickSuffix
@@ -48,13 +50,24 @@ const (
 	ScheduleButtonClick  = "schedule" + ButtonClickSuffix
 	HelpedButtonClick    = "helped" + ButtonClickSuffix
 	ReportTimeSpentClick = "report_time_spent" + ButtonClickSuffix
+	ReleaseButtonClick   = "release" + ButtonClickSuffix
+	KeepButtonClick      = "keep" + ButtonClickSuffix
+	HandoffButtonClick   = "handoff" + ButtonClickSuffix
+	SwapButtonClick      = "swap" + ButtonClickSuffix
+	TransferAcceptClick  = "transfer_accept" + ButtonClickSuffix
+	TransferDeclineClick = "transfer_decline" + ButtonClickSuffix
+	BidButtonClick       = "bid" + ButtonClickSuffix
 
 	ModalSubmitSuffix    = "_modal_submit:"
 	ReportTimeSpentModal = "report_time_spent" + ModalSubmitSuffix
 	EditChoreModal       = "edit" + ModalSubmitSuffix
+	BidModal             = "bid" + ModalSubmitSuffix
 
-	SelectMenuSuffix = "_select_menu:"
-	SkillsSelectMenu = "skills" + SelectMenuSuffix
+	SelectMenuSuffix    = "_select_menu:"
+	SkillsSelectMenu    = "skills" + SelectMenuSuffix
+	AssigneesSelectMenu = "assignees" + SelectMenuSuffix
+	HandoffSelectMenu   = "handoff" + SelectMenuSuffix
+	SwapSelectMenu      = "swap" + SelectMenuSuffix
 )
 
 func simpleInteractionResponse(content string) *discordgo.InteractionResponse {
@@ -94,6 +107,24 @@ func (ui *Ui) errorInteractionResponse(content string) *discordgo.InteractionRes
 	return simpleContainerizedInteractionResponse(content, &ui.colors.RedColor)
 }
 
+// getChoreVersionFromCustomID parses "modal_id:<chore_id>:<version>", IDs without a version give version 0.
+func getChoreVersionFromCustomID(customID string) (uint, uint, error) {
+	parts := strings.Split(customID, ":")
+	if len(parts) == 2 {
+		choreId, err := getChoreIdFromCustomID(customID)
+		return choreId, 0, err
+	}
+	if len(parts) != 3 {
+		return 0, 0, fmt.Errorf("invalid custom ID format: %s", customID)
+	}
+
+	var choreId, version uint
+	if _, err := fmt.Sscanf(parts[1]+":"+parts[2], "%d:%d", &choreId, &version); err != nil {
+		return 0, 0, fmt.Errorf("failed to parse chore ID and version from custom ID: %w", err)
+	}
+	return choreId, version, nil
+}
+
 func getChoreIdFromCustomID(customID string) (uint, error) {
 	// Extract the chore ID from the custom ID.
 	// The custom ID format is "button_id:<chore_id>"
@@ -111,6 +142,17 @@ func getChoreIdFromCustomID(customID string) (uint, error) {
 	return choreId, nil
 }
 
+// interactionUserId works both for guild and DM interactions.
+func interactionUserId(i *discordgo.InteractionCreate) string {
+	if i.Member != nil && i.Member.User != nil {
+		return i.Member.User.ID
+	}
+	if i.User != nil {
+		return i.User.ID
+	}
+	return ""
+}
+
 func (ui *Ui) SendDM(discordId string, message *discordgo.MessageSend) error {
 	if ui.discord == nil || discordId == "" {
 		return nil
@@ -150,6 +192,8 @@ func (ui *Ui) EmitChoreEvent(eventType string, chore storage.Chore) {
 		storageEventType = storage.TaskRefused
 	case "chore_completed":
 		storageEventType = storage.TaskDone
+	case "chore_cancelled":
+		storageEventType = storage.TaskCancelled
 	default:
 		storageEventType = storage.EventType(eventType)
 	}
@@ -175,59 +219,125 @@ func (ui *Ui) PublishChore(c storage.Chore) (storage.Chore, []storage.ChoreAssig
 		}
 	}
 
-	users, err := ui.storage.GetPresentUsers()
+	if c.NotBefore != nil && c.NotBefore.After(time.Now()) {
+		// Drafts are published by the reminders once their time comes.
+		c, err = ui.deferChore(c)
+		return c, nil, err
+	}
+	if c.Status != storage.ChoreInProgress {
+		if err := chores.Transition(&c, storage.ChoreOpen); err != nil {
+			return c, nil, err
+		}
+	}
+	// Auctioned chores are assigned once the bidding window closes.
+	auction := c.AuctionMin > 0 && c.AuctionUntil == nil
+	if auction {
+		until := time.Now().Add(time.Duration(c.AuctionMin) * time.Minute)
+		c.AuctionUntil = &until
+	}
+	held := c.GetHeldAssignees()
+	c.HeldAssignees = ""
+	c, err = ui.storage.SaveChore(c)
 	if err != nil {
-		ui.logger.Error("Error getting present users", "error", err)
-		return c, nil, fmt.Errorf("error getting present users: %w", err)
+		return c, nil, fmt.Errorf("failed to save chore: %w", err)
 	}
-	ass, err := ui.chores.AssignChoresToUsers(users, c)
+	if len(held) > 0 {
+		// The manual assignees of the draft go first, the strategy fills the rest.
+		c, _, err = ui.AssignUsers(c.ID, held)
+		if err != nil {
+			return c, nil, err
+		}
+	}
+
+	var ass []storage.ChoreAssignment
+	if auction {
+		ui.logger.Info("Chore auction opened", "chore_id", c.ID, "until", c.AuctionUntil)
+	} else {
+		users, err := ui.storage.GetPresentUsers()
+		if err != nil {
+			ui.logger.Error("Error getting present users", "error", err)
+			return c, nil, fmt.Errorf("error getting present users: %w", err)
+		}
+		ass, err = ui.chores.AssignChoresToUsers(users, c)
+		if err != nil {
+			ui.logger.Error("Error assigning chores to users", "error", err)
+			return c, nil, fmt.Errorf("error assigning chores to users: %w", err)
+		}
+		ui.flagResourceConflicts(c)
+	}
+
+	// Manual assignees were added before publishing, show them along with the picked ones.
+	assignmentsAll, err := ui.storage.GetChoreAssignments(c.ID)
 	if err != nil {
-		ui.logger.Error("Error assigning chores to users", "error", err)
-		return c, nil, fmt.Errorf("error assigning chores to users: %w", err)
+		ui.logger.Error("failed to get chore assignments", "error", err, "chore_id", c.ID)
+		return c, ass, fmt.Errorf("failed to get chore assignments: %w", err)
+	}
+	active := []storage.ChoreAssignment{}
+	for _, a := range assignmentsAll {
+		if a.Active() {
+			active = append(active, a)
+		}
 	}
 
 	embeds := []*discordgo.MessageEmbed{}
 
-	choreMd := ui.generateChoreMd(c)
+	choreMd := ui.generateChoreMd(c) + ui.generateStaffingMd(c, active)
 	choreEmbed := discordgo.MessageEmbed{
 		Type:        discordgo.EmbedTypeRich,
 		Description: choreMd,
 	}
 	embeds = append(embeds, &choreEmbed)
 
-	assignmentsEmbed := ui.generateAssignmentEmbed(ass, "Assignments", ui.colors.OrangeColor)
+	assignmentsEmbed := ui.generateAssignmentEmbed(active, "Assignments", ui.colors.OrangeColor)
+	if c.TeamMode {
+		assignmentsEmbed = ui.generateTeamEmbed(c, active)
+	}
 	if assignmentsEmbed != nil {
 		embeds = append(embeds, assignmentsEmbed)
 	}
 
 	if ui.discord != nil {
-		m, err := ui.discord.ChannelMessageSendComplex(ui.conf.DiscordChannelId, &discordgo.MessageSend{
-			Content: c.Name,
+		buttons := discordgo.ActionsRow{
 			Components: []discordgo.MessageComponent{
-				discordgo.ActionsRow{
-					Components: []discordgo.MessageComponent{
-						&discordgo.Button{
-							Style:    discordgo.PrimaryButton,
-							Label:    "Ack",
-							CustomID: AckButtonClick + fmt.Sprint(c.ID),
-						},
-						&discordgo.Button{
-							Style:    discordgo.SecondaryButton,
-							Label:    "Reject",
-							CustomID: RejectButtonClick + fmt.Sprint(c.ID),
-						},
-					},
+				&discordgo.Button{
+					Style:    discordgo.PrimaryButton,
+					Label:    "Ack",
+					CustomID: AckButtonClick + fmt.Sprint(c.ID),
+				},
+				&discordgo.Button{
+					Style:    discordgo.SecondaryButton,
+					Label:    "Reject",
+					CustomID: RejectButtonClick + fmt.Sprint(c.ID),
+				},
+				&discordgo.Button{
+					Style:    discordgo.SecondaryButton,
+					Label:    "Hand off",
+					CustomID: HandoffButtonClick + fmt.Sprint(c.ID),
+				},
+				&discordgo.Button{
+					Style:    discordgo.SecondaryButton,
+					Label:    "Swap",
+					CustomID: SwapButtonClick + fmt.Sprint(c.ID),
 				},
 			},
-			Embeds: embeds,
+		}
+		if c.AuctionOpen() {
+			buttons = auctionButtons(c.ID)
+		}
+		m, err := ui.discord.ChannelMessageSendComplex(ui.conf.DiscordChannelId, &discordgo.MessageSend{
+			Content:    c.Name,
+			Components: []discordgo.MessageComponent{buttons},
+			Embeds:     embeds,
 		})
 		if err != nil {
 			ui.logger.Error("failed to send public chore message", "error", err, "chore_id", c.ID)
 			return c, ass, fmt.Errorf("failed to send public chore message: %w", err)
 		}
 
-		c.MessageId = m.ID
-		c, err = ui.storage.SaveChore(c)
+		c, err = ui.storage.UpdateChore(c.ID, func(c *storage.Chore) error {
+			c.MessageId = m.ID
+			return nil
+		})
 		if err != nil {
 			ui.logger.Error("failed to save chore with message ID", "error", err, "chore_id", c.ID)
 			return c, ass, fmt.Errorf("failed to save chore with message ID: %w", err)
@@ -257,7 +367,7 @@ func (ui *Ui) PublishChore(c storage.Chore) (storage.Chore, []storage.ChoreAssig
 			})
 		}
 	} else {
-		c, err = ui.storage.SaveChore(c)
+		c, err = ui.storage.GetChore(c.ID)
 		if err != nil {
 			return c, ass, err
 		}
@@ -288,7 +398,11 @@ func (ui *Ui) scheduleChore(buttonId string, s *discordgo.Session, i *discordgo.
 		return
 	}
 
-	r := simpleContainerizedInteractionResponse(fmt.Sprintf("This chore `id: %d` was scheduled and published.", choreId), &ui.colors.GreenColor)
+	text := fmt.Sprintf("This chore `id: %d` was scheduled and published.", choreId)
+	if c.Status == storage.ChoreDraft {
+		text = fmt.Sprintf("This chore `id: %d` is a draft until %s, it will be published then.", choreId, c.NotBefore.In(ui.storage.Location()).Format(time.RFC822))
+	}
+	r := simpleContainerizedInteractionResponse(text, &ui.colors.GreenColor)
 	r.Data.Components = append(r.Data.Components, discordgo.ActionsRow{
 		Components: []discordgo.MessageComponent{
 			&discordgo.Button{
@@ -346,6 +460,50 @@ func (ui *Ui) generateAssignmentEmbed(ass []storage.ChoreAssignment, title strin
 	return &assignmentsEmbed
 }
 
+// generateTeamEmbed shows the active assignees of a team chore as a single group.
+func (ui *Ui) generateTeamEmbed(chore storage.Chore, team []storage.ChoreAssignment) *discordgo.MessageEmbed {
+	if len(team) == 0 {
+		return nil
+	}
+	capabilities := map[string][]string{}
+	if chore.TrainingMode {
+		users, err := ui.storage.GetPresentUsers()
+		if err != nil {
+			ui.logger.Error("failed to get present users", "error", err)
+		}
+		for _, u := range users {
+			capabilities[u.DiscordId] = u.Capabilities
+		}
+	}
+
+	teamMd := ""
+	for _, a := range team {
+		teamMd += fmt.Sprintf("* <@%s>", a.UserId)
+		if chore.TrainingMode {
+			if chores.IsMentor(capabilities[a.UserId], chore) {
+				teamMd += " (mentor)"
+			} else {
+				teamMd += " (trainee)"
+			}
+		}
+		if a.Acked != nil {
+			teamMd += " ✅"
+		}
+		teamMd += "\n"
+	}
+
+	color := ui.colors.OrangeColor
+	if uint(len(team)) >= chore.NecessaryWorkers {
+		color = ui.colors.GreenColor
+	}
+	return &discordgo.MessageEmbed{
+		Type:        discordgo.EmbedTypeRich,
+		Title:       "Team",
+		Description: teamMd,
+		Color:       color,
+	}
+}
+
 func (ui *Ui) editChoreModal(buttonId string, s *discordgo.Session, i *discordgo.InteractionCreate) {
 	failedText := "Failed to edit chore."
 	choreId, err := getChoreIdFromCustomID(buttonId)
@@ -370,7 +528,7 @@ func (ui *Ui) editChoreModal(buttonId string, s *discordgo.Session, i *discordgo
 	err = ui.discord.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseModal,
 		Data: &discordgo.InteractionResponseData{
-			CustomID: EditChoreModal + fmt.Sprint(choreId),
+			CustomID: EditChoreModal + fmt.Sprintf("%d:%d", choreId, chore.Version),
 			Title:    fmt.Sprintf("Edit chore %d", choreId),
 			Components: []discordgo.MessageComponent{
 				discordgo.ActionsRow{
@@ -450,25 +608,13 @@ func (ui *Ui) editChoreModal(buttonId string, s *discordgo.Session, i *discordgo
 }
 
 func (ui *Ui) CancelChore(choreId uint) (storage.Chore, error) {
-	chore, err := ui.storage.GetChore(choreId)
-	if err != nil {
-		return chore, fmt.Errorf("failed to get chore: %w", err)
-	}
-	if chore.Cancelled != nil {
-		return chore, fmt.Errorf("chore has already been cancelled")
-	}
-	if chore.Completed != nil {
-		return chore, fmt.Errorf("chore has been completed and cannot be cancelled")
-	}
-
-	t := time.Now()
-	chore.Cancelled = &t
-	chore, err = ui.storage.SaveChore(chore)
+	chore, err := ui.transitionChore(choreId, storage.ChoreCancelled)
 	if err != nil {
-		return chore, fmt.Errorf("failed to save chore: %w", err)
+		return chore, err
 	}
 
 	_ = ui.storage.RemoveStorageAssignments(choreId)
+	_ = ui.storage.ClearWaitlist(choreId)
 	_ = ui.UpdateChoreMessage(chore)
 	ui.EmitChoreEvent("chore_cancelled", chore)
 	return chore, nil
@@ -501,7 +647,17 @@ func (ui *Ui) RejectChore(choreId uint, userId string) (storage.Chore, error) {
 		return c, fmt.Errorf("failed to get chore: %w", err)
 	}
 
+	// Rejecting takes the user off the waitlist, an assignment they still hold is refused as well.
+	left, err := ui.storage.LeaveWaitlist(c.ID, userId)
+	if err != nil {
+		return c, fmt.Errorf("failed to leave waitlist: %w", err)
+	}
 	ass, err := ui.storage.GetChoreAssignment(c.ID, userId)
+	if left && (err == gorm.ErrRecordNotFound || (err == nil && !ass.Active())) {
+		_ = ui.UpdateChoreMessage(c)
+		ui.EmitChoreEvent("chore_waitlist_left", c)
+		return c, nil
+	}
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return c, fmt.Errorf("chore cannot be rejected, you are not assigned to it")
@@ -509,11 +665,25 @@ func (ui *Ui) RejectChore(choreId uint, userId string) (storage.Chore, error) {
 		return c, fmt.Errorf("failed to get chore assignment: %w", err)
 	}
 
-	ass.Refuse()
-	_, err = ui.storage.SaveChoreAssignment(ass)
+	_, err = ui.storage.UpdateChoreAssignment(ass.ID, func(a *storage.ChoreAssignment) error {
+		if !a.Active() {
+			return storage.ErrAssignmentInactive
+		}
+		a.Refuse()
+		return nil
+	})
+	if err != nil {
+		return c, fmt.Errorf("failed to refuse chore assignment: %w", err)
+	}
+	c, err = ui.promoteWaitlist(c)
 	if err != nil {
-		return c, fmt.Errorf("failed to save chore assignment: %w", err)
+		return c, err
 	}
+	c, err = ui.refreshProgress(c)
+	if err != nil {
+		return c, err
+	}
+	c, _ = ui.chores.BumpBounty(c)
 
 	users, err := ui.storage.GetPresentUsers()
 	if err == nil {
@@ -544,51 +714,840 @@ func (ui *Ui) rejectChore(buttonId string, s *discordgo.Session, i *discordgo.In
 	s.InteractionRespond(i.Interaction, simpleInteractionResponse(fmt.Sprintf("Chore `%d` rejected\n\n*... Dissapointing*", choreId)))
 }
 
+// ReleaseChore hands an assignment back so the chore can be assigned to somebody else.
+func (ui *Ui) ReleaseChore(choreId uint, userId string) (storage.Chore, error) {
+	c, err := ui.storage.GetChore(choreId)
+	if err != nil {
+		return c, fmt.Errorf("failed to get chore: %w", err)
+	}
+	if c.Status == storage.ChoreDone || c.Status == storage.ChoreCancelled {
+		return c, fmt.Errorf("chore is already finished")
+	}
+
+	ass, err := ui.storage.GetChoreAssignment(c.ID, userId)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return c, fmt.Errorf("chore cannot be released, you are not assigned to it")
+		}
+		return c, fmt.Errorf("failed to get chore assignment: %w", err)
+	}
+	_, err = ui.storage.UpdateChoreAssignment(ass.ID, func(a *storage.ChoreAssignment) error {
+		if !a.Active() {
+			return fmt.Errorf("chore was already released")
+		}
+		a.Release()
+		return nil
+	})
+	if err != nil {
+		return c, err
+	}
+	c, err = ui.promoteWaitlist(c)
+	if err != nil {
+		return c, err
+	}
+	c, err = ui.refreshProgress(c)
+	if err != nil {
+		return c, err
+	}
+
+	users, err := ui.storage.GetPresentUsers()
+	if err == nil {
+		_, _ = ui.chores.AssignChoresToUsers(users, c)
+	}
+
+	_ = ui.UpdateChoreMessage(c)
+	ui.EmitChoreEvent("chore_reassigned", c)
+	return c, nil
+}
+
+// KeepChore cancels a pending release request, the user keeps the acked chore.
+func (ui *Ui) KeepChore(choreId uint, userId string) (storage.Chore, error) {
+	c, err := ui.storage.GetChore(choreId)
+	if err != nil {
+		return c, fmt.Errorf("failed to get chore: %w", err)
+	}
+
+	ass, err := ui.storage.GetChoreAssignment(c.ID, userId)
+	if err != nil {
+		return c, fmt.Errorf("failed to get chore assignment: %w", err)
+	}
+	_, err = ui.storage.UpdateChoreAssignment(ass.ID, func(a *storage.ChoreAssignment) error {
+		if a.Acked == nil || !a.Active() {
+			return fmt.Errorf("chore is not acknowledged by you anymore")
+		}
+		a.ReleaseRequested = nil
+		return nil
+	})
+	return c, err
+}
+
+func (ui *Ui) releaseChore(customID string, s *discordgo.Session, i *discordgo.InteractionCreate) {
+	failedText := "Failed to hand the chore back."
+	choreId, err := getChoreIdFromCustomID(customID)
+	if err != nil {
+		ui.logger.Error("failed to parse chore ID from button", "error", err, "custom_id", customID)
+		s.InteractionRespond(i.Interaction, ui.errorInteractionResponse(failedText))
+		return
+	}
+
+	_, err = ui.ReleaseChore(choreId, interactionUserId(i))
+	if err != nil {
+		ui.logger.Error("failed to release chore", "error", err, "chore_id", choreId)
+		s.InteractionRespond(i.Interaction, ui.errorInteractionResponse(err.Error()))
+		return
+	}
+
+	r := simpleContainerizedInteractionResponse(fmt.Sprintf("Chore `id: %d` was handed back.", choreId), &ui.colors.OrangeColor)
+	r.Type = discordgo.InteractionResponseUpdateMessage
+	s.InteractionRespond(i.Interaction, r)
+}
+
+func (ui *Ui) keepChore(customID string, s *discordgo.Session, i *discordgo.InteractionCreate) {
+	failedText := "Failed to keep the chore."
+	choreId, err := getChoreIdFromCustomID(customID)
+	if err != nil {
+		ui.logger.Error("failed to parse chore ID from button", "error", err, "custom_id", customID)
+		s.InteractionRespond(i.Interaction, ui.errorInteractionResponse(failedText))
+		return
+	}
+
+	_, err = ui.KeepChore(choreId, interactionUserId(i))
+	if err != nil {
+		ui.logger.Error("failed to keep chore", "error", err, "chore_id", choreId)
+		s.InteractionRespond(i.Interaction, ui.errorInteractionResponse(err.Error()))
+		return
+	}
+
+	r := simpleContainerizedInteractionResponse(fmt.Sprintf("You keep chore `id: %d`.", choreId), &ui.colors.GreenColor)
+	r.Type = discordgo.InteractionResponseUpdateMessage
+	s.InteractionRespond(i.Interaction, r)
+}
+
+// AssignUsers assigns the given users to the chore directly, bypassing the assignment strategy.
+// Manually assigned users occupy the chore's slots, the strategy only fills the remainder.
+func (ui *Ui) AssignUsers(choreId uint, userIds []string) (storage.Chore, []storage.ChoreAssignment, error) {
+	assigned := []storage.ChoreAssignment{}
+	c, err := ui.storage.GetChore(choreId)
+	if err != nil {
+		return c, assigned, fmt.Errorf("failed to get chore: %w", err)
+	}
+	if c.Status == storage.ChoreDone || c.Status == storage.ChoreCancelled {
+		return c, assigned, fmt.Errorf("chore is already finished")
+	}
+	// Assignments of a draft would count toward the caps and get reminders before anybody can see the chore.
+	if c.Status == storage.ChoreDraft {
+		return c, assigned, fmt.Errorf("chore `id: %d`: %w", c.ID, chores.ErrChoreDraft)
+	}
+
+	for _, userId := range userIds {
+		if userId == "" {
+			continue
+		}
+		ass, err := ui.storage.GetChoreAssignment(c.ID, userId)
+		if err != nil && err != gorm.ErrRecordNotFound {
+			return c, assigned, fmt.Errorf("failed to get chore assignment: %w", err)
+		}
+		if err == gorm.ErrRecordNotFound {
+			ass, err = ui.storage.SaveChoreAssignment(storage.ChoreAssignment{
+				ChoreId: c.ID,
+				UserId:  userId,
+				Created: time.Now(),
+				Manual:  true,
+			})
+		} else {
+			ass, err = ui.storage.UpdateChoreAssignment(ass.ID, func(a *storage.ChoreAssignment) error {
+				if !a.Active() {
+					// Assigning somebody who turned the chore down before gives them a fresh assignment.
+					a.Refused = nil
+					a.Timeouted = nil
+					a.Transferred = nil
+					a.Released = nil
+					a.Reminded = false
+					a.Created = time.Now()
+				}
+				a.Manual = true
+				return nil
+			})
+		}
+		if err != nil {
+			return c, assigned, fmt.Errorf("failed to save chore assignment: %w", err)
+		}
+		assigned = append(assigned, ass)
+	}
+
+	err = ui.refreshStaffing(c)
+	if err != nil {
+		return c, assigned, err
+	}
+
+	_ = ui.UpdateChoreMessage(c)
+	ui.EmitChoreEvent("chore_reassigned", c)
+	return c, assigned, nil
+}
+
+// SetAssignees makes the given users the chore's manual assignees.
+// Manual assignees missing from the list are unassigned unless they already acked the chore.
+func (ui *Ui) SetAssignees(choreId uint, userIds []string) (storage.Chore, []storage.ChoreAssignment, error) {
+	c, err := ui.storage.GetChore(choreId)
+	if err != nil {
+		return c, nil, fmt.Errorf("failed to get chore: %w", err)
+	}
+	if c.Status == storage.ChoreDraft {
+		// Drafts hold the assignees until they are published.
+		c, err = ui.storage.UpdateChore(choreId, func(c *storage.Chore) error {
+			if c.Status != storage.ChoreDraft {
+				return chores.ErrChorePublished
+			}
+			c.SetHeldAssignees(userIds)
+			return nil
+		})
+		// A draft published in the meantime is assigned right away.
+		if !errors.Is(err, chores.ErrChorePublished) {
+			return c, nil, err
+		}
+	}
+
+	keep := map[string]struct{}{}
+	for _, userId := range userIds {
+		keep[userId] = struct{}{}
+	}
+
+	all, err := ui.storage.GetChoreAssignments(choreId)
+	if err != nil {
+		return storage.Chore{}, nil, fmt.Errorf("failed to get chore assignments: %w", err)
+	}
+	for _, a := range all {
+		if _, ok := keep[a.UserId]; ok || !a.Manual || a.Acked != nil {
+			continue
+		}
+		err = ui.storage.RemoveChoreAssignment(a)
+		if err != nil {
+			return storage.Chore{}, nil, fmt.Errorf("failed to remove chore assignment: %w", err)
+		}
+	}
+
+	c, assigned, err := ui.AssignUsers(choreId, userIds)
+	if err != nil {
+		return c, assigned, err
+	}
+
+	// Published chores get the freed slots filled by the strategy right away.
+	if c.MessageId != "" {
+		users, err := ui.storage.GetPresentUsers()
+		if err == nil {
+			_, _ = ui.chores.AssignChoresToUsers(users, c)
+			_ = ui.UpdateChoreMessage(c)
+		}
+	}
+	return c, assigned, nil
+}
+
+// transitionChore moves the chore to the status, the move is checked against the chore's current state in the database.
+func (ui *Ui) transitionChore(choreId uint, to storage.ChoreStatus) (storage.Chore, error) {
+	c, err := ui.storage.UpdateChore(choreId, func(c *storage.Chore) error {
+		return chores.Transition(c, to)
+	})
+	if err != nil && !errors.Is(err, chores.ErrInvalidTransition) {
+		return c, fmt.Errorf("failed to update chore: %w", err)
+	}
+	return c, err
+}
+
+// refreshProgress moves a chore in progress back to open once nobody holds it acked.
+func (ui *Ui) refreshProgress(c storage.Chore) (storage.Chore, error) {
+	if c.Status != storage.ChoreInProgress {
+		return c, nil
+	}
+	all, err := ui.storage.GetChoreAssignments(c.ID)
+	if err != nil {
+		return c, fmt.Errorf("failed to get chore assignments: %w", err)
+	}
+	for _, a := range all {
+		if a.Active() && a.Acked != nil {
+			return c, nil
+		}
+	}
+	c, err = ui.storage.UpdateChore(c.ID, func(c *storage.Chore) error {
+		if c.Status != storage.ChoreInProgress {
+			return nil
+		}
+		return chores.Transition(c, storage.ChoreOpen)
+	})
+	if err != nil {
+		return c, fmt.Errorf("failed to update chore: %w", err)
+	}
+	return c, nil
+}
+
+// ReopenChore brings a done or cancelled chore back and assigns it again.
+// The work logs of the previous completion are dropped, the work is credited again once the chore is done.
+func (ui *Ui) ReopenChore(choreId uint) (storage.Chore, error) {
+	c, err := ui.transitionChore(choreId, storage.ChoreReopened)
+	if err != nil {
+		return c, err
+	}
+	err = ui.storage.RemoveWorkLogs(c.ID)
+	if err != nil {
+		return c, fmt.Errorf("failed to remove work logs: %w", err)
+	}
+
+	users, err := ui.storage.GetPresentUsers()
+	if err == nil {
+		_, _ = ui.chores.AssignChoresToUsers(users, c)
+	}
+	// Assigned again the chore is open like any other, or in progress while the earlier acks still hold it.
+	to := storage.ChoreOpen
+	all, err := ui.storage.GetChoreAssignments(c.ID)
+	if err != nil {
+		return c, fmt.Errorf("failed to get chore assignments: %w", err)
+	}
+	for _, a := range all {
+		if a.Active() && a.Acked != nil {
+			to = storage.ChoreInProgress
+		}
+	}
+	c, err = ui.storage.UpdateChore(c.ID, func(c *storage.Chore) error {
+		if c.Status != storage.ChoreReopened {
+			// Somebody acked or finished the chore meanwhile, their status stays.
+			return chores.ErrInvalidTransition
+		}
+		return chores.Transition(c, to)
+	})
+	if err != nil && !errors.Is(err, chores.ErrInvalidTransition) {
+		return c, fmt.Errorf("failed to update chore: %w", err)
+	}
+
+	_ = ui.UpdateChoreMessage(c)
+	ui.EmitChoreEvent("chore_updated", c)
+	return c, nil
+}
+
+// refreshStaffing clears the backlog flag of a chore once manual assignees staffed it.
+func (ui *Ui) refreshStaffing(c storage.Chore) error {
+	if !c.Understaffed {
+		return nil
+	}
+	all, err := ui.storage.GetChoreAssignments(c.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get chore assignments: %w", err)
+	}
+	if c.Staffing(all).State == storage.StaffingStaffed {
+		return ui.storage.SetChoreUnderstaffed(c.ID, false)
+	}
+	return nil
+}
+
+// assigneesSelectMenu lets the creator pick the chore's manual assignees.
+func (ui *Ui) assigneesSelectMenu(chore storage.Chore) discordgo.ActionsRow {
+	minValues := 0
+	defaults := []discordgo.SelectMenuDefaultValue{}
+	for _, userId := range chore.GetHeldAssignees() {
+		defaults = append(defaults, discordgo.SelectMenuDefaultValue{ID: userId, Type: discordgo.SelectMenuDefaultValueUser})
+	}
+	ass, err := ui.storage.GetChoreAssignments(chore.ID)
+	if err != nil {
+		ui.logger.Error("failed to get chore assignments", "error", err, "chore_id", chore.ID)
+	}
+	for _, a := range ass {
+		if a.Manual && a.Active() {
+			defaults = append(defaults, discordgo.SelectMenuDefaultValue{ID: a.UserId, Type: discordgo.SelectMenuDefaultValueUser})
+		}
+	}
+	return discordgo.ActionsRow{
+		Components: []discordgo.MessageComponent{
+			&discordgo.SelectMenu{
+				MenuType:      discordgo.UserSelectMenu,
+				CustomID:      AssigneesSelectMenu + fmt.Sprint(chore.ID),
+				Placeholder:   "Assign specific people (optional)",
+				MinValues:     &minValues,
+				MaxValues:     25,
+				DefaultValues: defaults,
+			},
+		},
+	}
+}
+
+func (ui *Ui) handleAssigneesSelect(d string, s *discordgo.Session, i *discordgo.InteractionCreate) {
+	failedText := "Failed to update assignees."
+
+	choreId, err := getChoreIdFromCustomID(d)
+	if err != nil {
+		ui.logger.Error("failed to parse chore ID from select menu", "error", err, "custom_id", d)
+		s.InteractionRespond(i.Interaction, ui.errorInteractionResponse(failedText))
+		return
+	}
+
+	selectedUsers := i.MessageComponentData().Values
+	_, _, err = ui.SetAssignees(choreId, selectedUsers)
+	if err != nil {
+		ui.logger.Error("failed to set assignees", "error", err, "chore_id", choreId)
+		s.InteractionRespond(i.Interaction, ui.errorInteractionResponse(err.Error()))
+		return
+	}
+
+	r := simpleContainerizedInteractionResponse("Successfully updated assignees for the chore.", &ui.colors.GreenColor)
+	assigneesMd := "### Assignees\n"
+	if len(selectedUsers) == 0 {
+		assigneesMd += "* Picked automatically\n"
+	}
+	for _, userId := range selectedUsers {
+		assigneesMd += fmt.Sprintf("* <@%s>\n", userId)
+	}
+	container := &discordgo.Container{
+		AccentColor: &ui.colors.GreenColor,
+		Components: []discordgo.MessageComponent{
+			&discordgo.TextDisplay{
+				Content: assigneesMd,
+			},
+		},
+	}
+	r.Data.Components = append(r.Data.Components, container)
+	s.InteractionRespond(i.Interaction, r)
+}
+
+// OfferHandoff offers the user's assignment to another user, the chore moves once they accept.
+func (ui *Ui) OfferHandoff(choreId uint, fromUserId, toUserId string) (storage.AssignmentTransfer, error) {
+	t := storage.AssignmentTransfer{
+		Kind:       storage.TransferHandoff,
+		ChoreId:    choreId,
+		FromUserId: fromUserId,
+		ToUserId:   toUserId,
+		Created:    time.Now(),
+	}
+	c, _, err := ui.validateTransfer(t)
+	if err != nil {
+		return t, err
+	}
+
+	t, err = ui.storage.SaveAssignmentTransfer(t)
+	if err != nil {
+		return t, fmt.Errorf("failed to save transfer: %w", err)
+	}
+
+	ui.sendTransferOffer(t, fmt.Sprintf("<@%s> would like to hand chore `id: %d` `%s` over to you %s.", fromUserId, c.ID, c.Name, ui.GetChoreMessageUrl(c)))
+	return t, nil
+}
+
+// OfferSwap offers the user's assignment in exchange for an assignment of another user.
+func (ui *Ui) OfferSwap(choreId uint, fromUserId, toUserId string, swapChoreId uint) (storage.AssignmentTransfer, error) {
+	t := storage.AssignmentTransfer{
+		Kind:        storage.TransferSwap,
+		ChoreId:     choreId,
+		FromUserId:  fromUserId,
+		ToUserId:    toUserId,
+		SwapChoreId: swapChoreId,
+		Created:     time.Now(),
+	}
+	c, swapChore, err := ui.validateTransfer(t)
+	if err != nil {
+		return t, err
+	}
+
+	t, err = ui.storage.SaveAssignmentTransfer(t)
+	if err != nil {
+		return t, fmt.Errorf("failed to save transfer: %w", err)
+	}
+
+	ui.sendTransferOffer(t, fmt.Sprintf("<@%s> would like to swap their chore `id: %d` `%s` %s for your chore `id: %d` `%s` %s.", fromUserId, c.ID, c.Name, ui.GetChoreMessageUrl(c), swapChore.ID, swapChore.Name, ui.GetChoreMessageUrl(swapChore)))
+	return t, nil
+}
+
+// validateTransfer checks that both sides still hold the assignments the transfer is about.
+func (ui *Ui) validateTransfer(t storage.AssignmentTransfer) (storage.Chore, storage.Chore, error) {
+	var swapChore storage.Chore
+	if t.FromUserId == t.ToUserId {
+		return storage.Chore{}, swapChore, fmt.Errorf("chore cannot be handed over to yourself")
+	}
+	c, err := ui.activeChoreAssignment(t.ChoreId, t.FromUserId)
+	if err != nil {
+		return c, swapChore, err
+	}
+	if t.Kind == storage.TransferSwap {
+		if t.SwapChoreId == t.ChoreId {
+			return c, swapChore, fmt.Errorf("chore cannot be swapped for itself")
+		}
+		swapChore, err = ui.activeChoreAssignment(t.SwapChoreId, t.ToUserId)
+		if err != nil {
+			return c, swapChore, err
+		}
+		if _, err := ui.activeChoreAssignment(t.SwapChoreId, t.FromUserId); err == nil {
+			return c, swapChore, storage.ErrAlreadyAssigned
+		}
+	}
+	if _, err := ui.activeChoreAssignment(t.ChoreId, t.ToUserId); err == nil {
+		return c, swapChore, storage.ErrAlreadyAssigned
+	}
+	return c, swapChore, nil
+}
+
+func (ui *Ui) activeChoreAssignment(choreId uint, userId string) (storage.Chore, error) {
+	c, err := ui.storage.GetChore(choreId)
+	if err != nil {
+		return c, fmt.Errorf("failed to get chore: %w", err)
+	}
+	if c.Completed != nil || c.Cancelled != nil {
+		return c, fmt.Errorf("chore `id: %d` is already finished", choreId)
+	}
+	ass, err := ui.storage.GetChoreAssignment(choreId, userId)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return c, fmt.Errorf("<@%s> is not assigned to chore `id: %d`", userId, choreId)
+		}
+		return c, fmt.Errorf("failed to get chore assignment: %w", err)
+	}
+	if !ass.Active() {
+		return c, fmt.Errorf("<@%s> is not assigned to chore `id: %d` anymore", userId, choreId)
+	}
+	return c, nil
+}
+
+func (ui *Ui) sendTransferOffer(t storage.AssignmentTransfer, content string) {
+	if ui.discord == nil {
+		return
+	}
+	_ = ui.SendDM(t.ToUserId, &discordgo.MessageSend{
+		Content: content,
+		Components: []discordgo.MessageComponent{
+			discordgo.ActionsRow{
+				Components: []discordgo.MessageComponent{
+					&discordgo.Button{
+						Style:    discordgo.SuccessButton,
+						Label:    "Accept",
+						CustomID: TransferAcceptClick + fmt.Sprint(t.ID),
+					},
+					&discordgo.Button{
+						Style:    discordgo.DangerButton,
+						Label:    "Decline",
+						CustomID: TransferDeclineClick + fmt.Sprint(t.ID),
+					},
+				},
+			},
+		},
+	})
+}
+
+// AcceptTransfer moves the offered assignment (and for swaps the exchanged one) between the users.
+func (ui *Ui) AcceptTransfer(transferId uint, userId string) (storage.AssignmentTransfer, error) {
+	t, err := ui.pendingTransfer(transferId, userId)
+	if err != nil {
+		return t, err
+	}
+	c, swapChore, err := ui.validateTransfer(t)
+	if err != nil {
+		return t, err
+	}
+
+	from, err := ui.storage.GetChoreAssignment(t.ChoreId, t.FromUserId)
+	if err != nil {
+		return t, fmt.Errorf("failed to get chore assignment: %w", err)
+	}
+	if t.Kind == storage.TransferSwap {
+		other, err := ui.storage.GetChoreAssignment(t.SwapChoreId, t.ToUserId)
+		if err != nil {
+			return t, fmt.Errorf("failed to get chore assignment: %w", err)
+		}
+		_, _, err = ui.storage.SwapAssignments(from, other)
+		if err != nil {
+			return t, fmt.Errorf("failed to swap assignments: %w", err)
+		}
+	} else {
+		_, err = ui.storage.TransferAssignment(from, t.ToUserId)
+		if err != nil {
+			return t, fmt.Errorf("failed to transfer assignment: %w", err)
+		}
+	}
+
+	now := time.Now()
+	t.Accepted = &now
+	t, err = ui.storage.SaveAssignmentTransfer(t)
+	if err != nil {
+		return t, fmt.Errorf("failed to save transfer: %w", err)
+	}
+
+	if ui.discord != nil {
+		content := fmt.Sprintf("<@%s> took over your chore `id: %d` `%s` %s.", t.ToUserId, c.ID, c.Name, ui.GetChoreMessageUrl(c))
+		if t.Kind == storage.TransferSwap {
+			content = fmt.Sprintf("<@%s> accepted the swap, chore `id: %d` `%s` %s is yours now.", t.ToUserId, swapChore.ID, swapChore.Name, ui.GetChoreMessageUrl(swapChore))
+		}
+		_ = ui.SendDM(t.FromUserId, &discordgo.MessageSend{Content: content})
+	}
+
+	_ = ui.UpdateChoreMessage(c)
+	ui.EmitChoreEvent("chore_reassigned", c)
+	if t.Kind == storage.TransferSwap {
+		_ = ui.UpdateChoreMessage(swapChore)
+		ui.EmitChoreEvent("chore_reassigned", swapChore)
+	}
+	return t, nil
+}
+
+// DeclineTransfer turns the offer down, both users keep their assignments.
+func (ui *Ui) DeclineTransfer(transferId uint, userId string) (storage.AssignmentTransfer, error) {
+	t, err := ui.pendingTransfer(transferId, userId)
+	if err != nil {
+		return t, err
+	}
+
+	now := time.Now()
+	t.Declined = &now
+	t, err = ui.storage.SaveAssignmentTransfer(t)
+	if err != nil {
+		return t, fmt.Errorf("failed to save transfer: %w", err)
+	}
+
+	if ui.discord != nil {
+		_ = ui.SendDM(t.FromUserId, &discordgo.MessageSend{
+			Content: fmt.Sprintf("<@%s> declined your offer for chore `id: %d`, it stays yours.", t.ToUserId, t.ChoreId),
+		})
+	}
+	return t, nil
+}
+
+func (ui *Ui) pendingTransfer(transferId uint, userId string) (storage.AssignmentTransfer, error) {
+	t, err := ui.storage.GetAssignmentTransfer(transferId)
+	if err != nil {
+		return t, fmt.Errorf("failed to get transfer: %w", err)
+	}
+	if t.ToUserId != userId {
+		return t, fmt.Errorf("the offer was not made to you")
+	}
+	if !t.Pending() {
+		return t, fmt.Errorf("the offer was already answered")
+	}
+	return t, nil
+}
+
+func (ui *Ui) handoffButtonClick(customID string, s *discordgo.Session, i *discordgo.InteractionCreate) {
+	failedText := "Failed to hand the chore over."
+	choreId, err := getChoreIdFromCustomID(customID)
+	if err != nil {
+		ui.logger.Error("failed to parse chore ID from button", "error", err, "custom_id", customID)
+		s.InteractionRespond(i.Interaction, ui.errorInteractionResponse(failedText))
+		return
+	}
+	if _, err = ui.activeChoreAssignment(choreId, interactionUserId(i)); err != nil {
+		s.InteractionRespond(i.Interaction, ui.errorInteractionResponse(err.Error()))
+		return
+	}
+
+	r := simpleContainerizedInteractionResponse(fmt.Sprintf("Who should take over chore `id: %d`? They have to accept it.", choreId), &ui.colors.OrangeColor)
+	r.Data.Components = append(r.Data.Components, discordgo.ActionsRow{
+		Components: []discordgo.MessageComponent{
+			&discordgo.SelectMenu{
+				MenuType:    discordgo.UserSelectMenu,
+				CustomID:    HandoffSelectMenu + fmt.Sprint(choreId),
+				Placeholder: "Hand the chore over to",
+			},
+		},
+	})
+	s.InteractionRespond(i.Interaction, r)
+}
+
+func (ui *Ui) handleHandoffSelect(d string, s *discordgo.Session, i *discordgo.InteractionCreate) {
+	failedText := "Failed to hand the chore over."
+	choreId, err := getChoreIdFromCustomID(d)
+	if err != nil {
+		ui.logger.Error("failed to parse chore ID from select menu", "error", err, "custom_id", d)
+		s.InteractionRespond(i.Interaction, ui.errorInteractionResponse(failedText))
+		return
+	}
+	values := i.MessageComponentData().Values
+	if len(values) == 0 {
+		s.InteractionRespond(i.Interaction, ui.errorInteractionResponse(failedText))
+		return
+	}
+
+	_, err = ui.OfferHandoff(choreId, interactionUserId(i), values[0])
+	if err != nil {
+		ui.logger.Error("failed to offer handoff", "error", err, "chore_id", choreId)
+		s.InteractionRespond(i.Interaction, ui.errorInteractionResponse(err.Error()))
+		return
+	}
+
+	r := simpleContainerizedInteractionResponse(fmt.Sprintf("Asked <@%s> to take over chore `id: %d`.", values[0], choreId), &ui.colors.GreenColor)
+	r.Type = discordgo.InteractionResponseUpdateMessage
+	s.InteractionRespond(i.Interaction, r)
+}
+
+func (ui *Ui) swapButtonClick(customID string, s *discordgo.Session, i *discordgo.InteractionCreate) {
+	failedText := "Failed to offer a swap."
+	choreId, err := getChoreIdFromCustomID(customID)
+	if err != nil {
+		ui.logger.Error("failed to parse chore ID from button", "error", err, "custom_id", customID)
+		s.InteractionRespond(i.Interaction, ui.errorInteractionResponse(failedText))
+		return
+	}
+	userId := interactionUserId(i)
+	if _, err = ui.activeChoreAssignment(choreId, userId); err != nil {
+		s.InteractionRespond(i.Interaction, ui.errorInteractionResponse(err.Error()))
+		return
+	}
+
+	ass, err := ui.storage.GetOpenAssignments()
+	if err != nil {
+		ui.logger.Error("failed to get open assignments", "error", err)
+		s.InteractionRespond(i.Interaction, ui.errorInteractionResponse(failedText))
+		return
+	}
+	options := []discordgo.SelectMenuOption{}
+	for _, a := range ass {
+		if a.UserId == userId || a.ChoreId == choreId || len(options) == 25 {
+			continue
+		}
+		handle, _ := ui.storage.GetUserHandleByDiscordId(a.UserId)
+		if handle == "" {
+			handle = a.UserId
+		}
+		options = append(options, discordgo.SelectMenuOption{
+			Label:       fmt.Sprintf("%s: %s", handle, a.Chore.Name),
+			Value:       fmt.Sprintf("%d/%s", a.ChoreId, a.UserId),
+			Description: fmt.Sprintf("Chore id: %d", a.ChoreId),
+		})
+	}
+	if len(options) == 0 {
+		s.InteractionRespond(i.Interaction, ui.errorInteractionResponse("Nobody else holds a chore to swap with."))
+		return
+	}
+
+	r := simpleContainerizedInteractionResponse(fmt.Sprintf("Which chore do you want in exchange for chore `id: %d`? Its assignee has to accept the swap.", choreId), &ui.colors.OrangeColor)
+	r.Data.Components = append(r.Data.Components, discordgo.ActionsRow{
+		Components: []discordgo.MessageComponent{
+			&discordgo.SelectMenu{
+				CustomID:    SwapSelectMenu + fmt.Sprint(choreId),
+				Placeholder: "Swap the chore for",
+				Options:     options,
+			},
+		},
+	})
+	s.InteractionRespond(i.Interaction, r)
+}
+
+func (ui *Ui) handleSwapSelect(d string, s *discordgo.Session, i *discordgo.InteractionCreate) {
+	failedText := "Failed to offer a swap."
+	choreId, err := getChoreIdFromCustomID(d)
+	if err != nil {
+		ui.logger.Error("failed to parse chore ID from select menu", "error", err, "custom_id", d)
+		s.InteractionRespond(i.Interaction, ui.errorInteractionResponse(failedText))
+		return
+	}
+	values := i.MessageComponentData().Values
+	if len(values) == 0 {
+		s.InteractionRespond(i.Interaction, ui.errorInteractionResponse(failedText))
+		return
+	}
+	swapChore, toUserId, ok := strings.Cut(values[0], "/")
+	swapChoreId, err := strconv.ParseUint(swapChore, 10, 0)
+	if !ok || err != nil {
+		ui.logger.Error("failed to parse swap option", "error", err, "value", values[0])
+		s.InteractionRespond(i.Interaction, ui.errorInteractionResponse(failedText))
+		return
+	}
+
+	_, err = ui.OfferSwap(choreId, interactionUserId(i), toUserId, uint(swapChoreId))
+	if err != nil {
+		ui.logger.Error("failed to offer swap", "error", err, "chore_id", choreId)
+		s.InteractionRespond(i.Interaction, ui.errorInteractionResponse(err.Error()))
+		return
+	}
+
+	r := simpleContainerizedInteractionResponse(fmt.Sprintf("Offered <@%s> to swap chore `id: %d` for chore `id: %d`.", toUserId, choreId, swapChoreId), &ui.colors.GreenColor)
+	r.Type = discordgo.InteractionResponseUpdateMessage
+	s.InteractionRespond(i.Interaction, r)
+}
+
+func (ui *Ui) answerTransfer(customID string, s *discordgo.Session, i *discordgo.InteractionCreate) {
+	failedText := "Failed to answer the offer."
+	transferId, err := getChoreIdFromCustomID(customID)
+	if err != nil {
+		ui.logger.Error("failed to parse transfer ID from button", "error", err, "custom_id", customID)
+		s.InteractionRespond(i.Interaction, ui.errorInteractionResponse(failedText))
+		return
+	}
+
+	text := "You declined the offer."
+	if strings.HasPrefix(customID, TransferAcceptClick) {
+		_, err = ui.AcceptTransfer(transferId, interactionUserId(i))
+		text = "You accepted the offer, the chore is yours now."
+	} else {
+		_, err = ui.DeclineTransfer(transferId, interactionUserId(i))
+	}
+	if err != nil {
+		ui.logger.Error("failed to answer transfer", "error", err, "transfer_id", transferId)
+		s.InteractionRespond(i.Interaction, ui.errorInteractionResponse(err.Error()))
+		return
+	}
+
+	r := simpleContainerizedInteractionResponse(text, &ui.colors.GreenColor)
+	r.Type = discordgo.InteractionResponseUpdateMessage
+	s.InteractionRespond(i.Interaction, r)
+}
+
 func (ui *Ui) AckChore(choreId uint, userId string) (storage.Chore, storage.ChoreAssignment, error) {
+	return ui.acknowledgeChore(choreId, userId, false)
+}
+
+// AckChoreConfirmed acks the chore even when the fairness policy asks for a confirmation.
+func (ui *Ui) AckChoreConfirmed(choreId uint, userId string) (storage.Chore, storage.ChoreAssignment, error) {
+	return ui.acknowledgeChore(choreId, userId, true)
+}
+
+func (ui *Ui) acknowledgeChore(choreId uint, userId string, confirmed bool) (storage.Chore, storage.ChoreAssignment, error) {
 	var ass storage.ChoreAssignment
 	c, err := ui.storage.GetChore(choreId)
 	if err != nil {
 		return c, ass, fmt.Errorf("failed to get chore: %w", err)
 	}
 
-	ass, err = ui.storage.GetChoreAssignment(choreId, userId)
-	if err != nil {
-		if err == gorm.ErrRecordNotFound {
-			ass, err = ui.storage.AssignChore(c, userId)
-			if err != nil {
-				return c, ass, fmt.Errorf("failed to assign chore: %w", err)
-			}
-			ass.Volunteered = true
-			ass, err = ui.storage.SaveChoreAssignment(ass)
-			if err != nil {
-				return c, ass, fmt.Errorf("failed to save chore assignment: %w", err)
-			}
-		} else {
-			return c, ass, fmt.Errorf("failed to get chore assignment: %w", err)
+	if err := ackable(&c); err != nil {
+		return c, ass, err
+	}
+
+	var verdict chores.FairnessVerdict
+	a, err := ui.storage.GetChoreAssignment(choreId, userId)
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return c, ass, fmt.Errorf("failed to get chore assignment: %w", err)
+	}
+	if err == gorm.ErrRecordNotFound || !a.Active() {
+		// Volunteers, including those who turned the chore down before, are checked against the fairness policy,
+		// assignees were picked by the algorithm.
+		verdict, err = ui.checkFairness(userId)
+		if err != nil {
+			return c, ass, err
+		}
+		if verdict.Exceeded && verdict.Policy == chores.FairnessBlock {
+			return c, ass, fmt.Errorf("%w (your load %.2f, median %.2f)", chores.ErrAckRefused, verdict.Load, verdict.Median)
+		}
+		if verdict.Exceeded && verdict.Policy == chores.FairnessConfirm && !confirmed {
+			return c, ass, fmt.Errorf("%w (your load %.2f, median %.2f)", chores.ErrAckNeedsConfirmation, verdict.Load, verdict.Median)
 		}
 	}
 
-	ass.Ack()
-	ass, err = ui.storage.SaveChoreAssignment(ass)
+	// The status, the number of acked workers and the resources are checked together with the ack.
+	c, ass, err = ui.storage.AckChoreAssignment(choreId, userId, ackable)
+	var locked *storage.ResourceLockedError
+	if errors.As(err, &locked) {
+		ui.flagResourceConflicts(c)
+		return c, ass, fmt.Errorf("%w (%s)", chores.ErrResourceLocked, resourceConflictsMd(locked.Conflicts))
+	}
+	if errors.Is(err, storage.ErrWaitlisted) {
+		ui.logger.Info("User waitlisted", "chore_id", choreId, "user_id", userId)
+		_ = ui.UpdateChoreMessage(c)
+		ui.EmitChoreEvent("chore_waitlisted", c)
+		return c, ass, err
+	}
 	if err != nil {
-		return c, ass, fmt.Errorf("failed to save chore assignment: %w", err)
+		if errors.Is(err, chores.ErrInvalidTransition) || errors.Is(err, chores.ErrAuctionOpen) {
+			return c, ass, err
+		}
+		return c, ass, fmt.Errorf("failed to ack chore assignment: %w", err)
 	}
 
 	if ui.discord != nil && userId != "" {
-		_ = ui.SendDM(userId, &discordgo.MessageSend{
-			Content: fmt.Sprintf("Your acknowledged chore `id: %d` `%s` %s.", c.ID, c.Name, ui.GetChoreMessageUrl(c)),
-			Components: []discordgo.MessageComponent{
-				discordgo.ActionsRow{
-					Components: []discordgo.MessageComponent{
-						&discordgo.Button{
-							Style:    discordgo.SuccessButton,
-							Label:    "Done!",
-							CustomID: DoneButtonClick + fmt.Sprint(c.ID),
-						},
-					},
-				},
-			},
-		})
+		ui.sendAckedDM(c, userId, "Your acknowledged chore")
+	}
+
+	if verdict.Exceeded && verdict.Policy == chores.FairnessNudge {
+		ui.sendFairnessNudge(c, userId, verdict)
 	}
 
 	_ = ui.UpdateChoreMessage(c)
@@ -596,6 +1555,42 @@ func (ui *Ui) AckChore(choreId uint, userId string) (storage.Chore, storage.Chor
 	return c, ass, nil
 }
 
+// ackable moves the chore in progress, unless it is auctioned and the bidding decides who does it.
+func ackable(c *storage.Chore) error {
+	if c.AuctionOpen() {
+		return fmt.Errorf("chore `id: %d`: %w", c.ID, chores.ErrAuctionOpen)
+	}
+	return chores.Transition(c, storage.ChoreInProgress)
+}
+
+func (ui *Ui) checkFairness(userId string) (chores.FairnessVerdict, error) {
+	users, err := ui.storage.GetPresentUsers()
+	if err != nil {
+		return chores.FairnessVerdict{}, fmt.Errorf("failed to get present users: %w", err)
+	}
+	verdict, err := ui.chores.CheckFairness(users, userId)
+	if err != nil {
+		return verdict, fmt.Errorf("failed to check fairness: %w", err)
+	}
+	return verdict, nil
+}
+
+func (ui *Ui) sendFairnessNudge(c storage.Chore, userId string, verdict chores.FairnessVerdict) {
+	if ui.discord == nil || len(verdict.UnderLoaded) == 0 {
+		return
+	}
+	mentions := []string{}
+	for _, u := range verdict.UnderLoaded {
+		mentions = append(mentions, fmt.Sprintf("<@%s>", u))
+	}
+	_, err := ui.discord.ChannelMessageSend(ui.conf.DiscordChannelId, fmt.Sprintf(
+		"<@%s> volunteered for `%s` (id: `%d`) once again. %s, you worked less than the others so far, maybe grab the next one?",
+		userId, c.Name, c.ID, strings.Join(mentions, ", ")))
+	if err != nil {
+		ui.logger.Error("failed to send fairness nudge", "error", err, "chore_id", c.ID)
+	}
+}
+
 func (ui *Ui) ackChore(customID string, s *discordgo.Session, i *discordgo.InteractionCreate) {
 	failedText := "Failed to acknowledge chore."
 	choreId, err := getChoreIdFromCustomID(customID)
@@ -605,7 +1600,35 @@ func (ui *Ui) ackChore(customID string, s *discordgo.Session, i *discordgo.Inter
 		return
 	}
 
-	c, _, err := ui.AckChore(choreId, i.Member.User.ID)
+	confirmed := strings.HasPrefix(customID, AckConfirmClick)
+	c, _, err := ui.acknowledgeChore(choreId, i.Member.User.ID, confirmed)
+	if errors.Is(err, chores.ErrAckNeedsConfirmation) {
+		r := simpleContainerizedInteractionResponse(fmt.Sprintf("You already worked much more than the others. Do you really want to ack chore `id: %d`?", choreId), &ui.colors.OrangeColor)
+		r.Data.Components = append(r.Data.Components, discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				&discordgo.Button{
+					Style:    discordgo.PrimaryButton,
+					Label:    "Ack anyway",
+					CustomID: AckConfirmClick + fmt.Sprint(choreId),
+				},
+			},
+		})
+		s.InteractionRespond(i.Interaction, r)
+		return
+	}
+	if errors.Is(err, chores.ErrAckRefused) {
+		s.InteractionRespond(i.Interaction, ui.errorInteractionResponse(fmt.Sprintf("You already worked much more than the others, let them take chore `id: %d`.", choreId)))
+		return
+	}
+	if errors.Is(err, chores.ErrAuctionOpen) {
+		s.InteractionRespond(i.Interaction, ui.errorInteractionResponse(fmt.Sprintf("Chore `id: %d` is auctioned, place a bid instead.", choreId)))
+		return
+	}
+	var waitlisted *storage.WaitlistedError
+	if errors.As(err, &waitlisted) {
+		s.InteractionRespond(i.Interaction, simpleInteractionResponse(fmt.Sprintf("Chore `id: %d` already has enough workers, you are number %d on its waitlist.", choreId, waitlisted.Position)))
+		return
+	}
 	if err != nil {
 		ui.logger.Error("failed to ack chore", "error", err, "chore_id", choreId)
 		s.InteractionRespond(i.Interaction, ui.errorInteractionResponse(failedText))
@@ -649,11 +1672,16 @@ func (ui *Ui) UpdateChoreMessage(chore storage.Chore) error {
 		ui.logger.Error("failed to get chore assignments", "error", err, "chore_id", chore.ID)
 		return err
 	}
+	if chore.Completed == nil && chore.Cancelled == nil {
+		choreEmbed.Description += ui.generateStaffingMd(chore, assignmentsAll)
+	}
 
 	assignments := []storage.ChoreAssignment{}
 	timeouted := []storage.ChoreAssignment{}
 	acked := []storage.ChoreAssignment{}
 	declined := []storage.ChoreAssignment{}
+	transferred := []storage.ChoreAssignment{}
+	released := []storage.ChoreAssignment{}
 
 	for _, a := range assignmentsAll {
 		if a.Acked != nil {
@@ -662,11 +1690,23 @@ func (ui *Ui) UpdateChoreMessage(chore storage.Chore) error {
 			declined = append(declined, a)
 		} else if a.Timeouted != nil {
 			timeouted = append(timeouted, a)
+		} else if a.Transferred != nil {
+			transferred = append(transferred, a)
+		} else if a.Released != nil {
+			released = append(released, a)
 		} else {
 			assignments = append(assignments, a)
 		}
 	}
+	if chore.TeamMode {
+		// The team is shown as one group, acked members are marked in it.
+		assignments = append(assignments, acked...)
+		acked = nil
+	}
 	assignmentsEmbed := ui.generateAssignmentEmbed(assignments, "Assignments", ui.colors.OrangeColor)
+	if chore.TeamMode {
+		assignmentsEmbed = ui.generateTeamEmbed(chore, assignments)
+	}
 	if assignmentsEmbed != nil {
 		embeds = append(embeds, assignmentsEmbed)
 	}
@@ -686,9 +1726,45 @@ func (ui *Ui) UpdateChoreMessage(chore storage.Chore) error {
 		embeds = append(embeds, declinedEmbed)
 	}
 
-	buttons := []discordgo.MessageComponent{}
+	transferredEmbed := ui.generateAssignmentEmbed(transferred, "Handed over", ui.colors.OrangeColor)
+	if transferredEmbed != nil {
+		embeds = append(embeds, transferredEmbed)
+	}
+
+	releasedEmbed := ui.generateAssignmentEmbed(released, "Handed back", ui.colors.OrangeColor)
+	if releasedEmbed != nil {
+		embeds = append(embeds, releasedEmbed)
+	}
 
 	if chore.Completed == nil && chore.Cancelled == nil {
+		waitlist, err := ui.storage.GetWaitlist(chore.ID)
+		if err != nil {
+			ui.logger.Error("failed to get waitlist", "error", err, "chore_id", chore.ID)
+			return err
+		}
+		waitlistEmbed := ui.generateWaitlistEmbed(waitlist)
+		if waitlistEmbed != nil {
+			embeds = append(embeds, waitlistEmbed)
+		}
+	}
+
+	if chore.AuctionOpen() {
+		bids, err := ui.storage.GetBids(chore.ID)
+		if err != nil {
+			ui.logger.Error("failed to get bids", "error", err, "chore_id", chore.ID)
+			return err
+		}
+		bidsEmbed := ui.generateBidsEmbed(bids)
+		if bidsEmbed != nil {
+			embeds = append(embeds, bidsEmbed)
+		}
+	}
+
+	buttons := []discordgo.MessageComponent{}
+
+	if chore.AuctionOpen() && chore.Completed == nil && chore.Cancelled == nil {
+		buttons = append(buttons, auctionButtons(chore.ID))
+	} else if chore.Completed == nil && chore.Cancelled == nil {
 		buttons = append(buttons,
 			discordgo.ActionsRow{
 				Components: []discordgo.MessageComponent{
@@ -702,6 +1778,16 @@ func (ui *Ui) UpdateChoreMessage(chore storage.Chore) error {
 						Label:    "Reject",
 						CustomID: RejectButtonClick + fmt.Sprint(chore.ID),
 					},
+					&discordgo.Button{
+						Style:    discordgo.SecondaryButton,
+						Label:    "Hand off",
+						CustomID: HandoffButtonClick + fmt.Sprint(chore.ID),
+					},
+					&discordgo.Button{
+						Style:    discordgo.SecondaryButton,
+						Label:    "Swap",
+						CustomID: SwapButtonClick + fmt.Sprint(chore.ID),
+					},
 				},
 			})
 	} else if chore.Completed != nil {
@@ -772,6 +1858,32 @@ func (ui *Ui) generateChoreMd(chore storage.Chore) string {
 	if necessaryCapabilities != "" {
 		choreDesc += fmt.Sprintf("\n**Necessary Capabilities**: `%s`", necessaryCapabilities)
 	}
+	if chore.AuctionOpen() {
+		choreDesc += fmt.Sprintf("\n**Auction**: bidding until %s, the lowest bids win", chore.AuctionUntil.Format(time.RFC822))
+	} else if chore.AuctionUntil != nil {
+		choreDesc += "\n**Auction**: closed"
+	} else if chore.AuctionMin > 0 {
+		choreDesc += fmt.Sprintf("\n**Auction**: `%d` min of bidding after scheduling", chore.AuctionMin)
+	}
+	if chore.BountyMultiplier() > 1 {
+		choreDesc += fmt.Sprintf("\n**Bounty**: `x%.2f` 💰", chore.BountyMultiplier())
+	}
+	if chore.Status == storage.ChoreDraft && chore.NotBefore != nil {
+		choreDesc += fmt.Sprintf("\n**Draft**: published at %s", chore.NotBefore.In(ui.storage.Location()).Format(time.RFC822))
+	} else if chore.NotBefore != nil && chore.NotBefore.After(time.Now()) {
+		choreDesc += fmt.Sprintf("\n**Not Before**: %s", chore.NotBefore.In(ui.storage.Location()).Format(time.RFC822))
+	}
+	if resources := chore.GetResources(); len(resources) > 0 {
+		choreDesc += fmt.Sprintf("\n**Resources**: `%s`", strings.Join(resources, ", "))
+	}
+	if categories := chore.GetCategories(); len(categories) > 0 {
+		choreDesc += fmt.Sprintf("\n**Categories**: `%s`", strings.Join(categories, ", "))
+	}
+	if chore.TrainingMode {
+		choreDesc += "\n**Mode**: `team (training)`"
+	} else if chore.TeamMode {
+		choreDesc += "\n**Mode**: `team`"
+	}
 	if chore.Deadline != nil {
 		choreDesc += fmt.Sprintf("\n**Deadline**: %s", chore.Deadline.Format(time.RFC822))
 	}
@@ -785,6 +1897,15 @@ func (ui *Ui) generateChoreMd(chore storage.Chore) string {
 	return choreDesc
 }
 
+func (ui *Ui) generateStaffingMd(chore storage.Chore, ass []storage.ChoreAssignment) string {
+	st := chore.Staffing(ass)
+	md := fmt.Sprintf("\n**Staffing**: `%d/%d`", st.Assigned, st.Needed)
+	if st.State != storage.StaffingStaffed {
+		md += fmt.Sprintf(" (%s, waiting for more people)", st.State)
+	}
+	return md
+}
+
 func (ui *Ui) choreCreate(i *discordgo.InteractionCreate) {
 	// Respond to the slash command interaction.
 	options := i.ApplicationCommandData().Options
@@ -821,10 +1942,37 @@ func (ui *Ui) choreCreate(i *discordgo.InteractionCreate) {
 			if v.StringValue() != "" {
 				chore.SetCapabilities(strings.Split(v.StringValue(), ","))
 			}
+		case "categories":
+			chore.SetCategories(strings.Split(v.StringValue(), ","))
+		case "not_before":
+			if v.IntValue() > 0 {
+				notBefore := time.Now().Add(time.Duration(v.IntValue()) * time.Minute)
+				chore.NotBefore = &notBefore
+			}
+		case "resources":
+			chore.SetResources(strings.Split(v.StringValue(), ","))
+		case "auction":
+			if v.IntValue() > 0 {
+				chore.AuctionMin = uint(v.IntValue())
+			}
+		case "team":
+			chore.TeamMode = v.BoolValue()
+		case "training":
+			chore.TrainingMode = v.BoolValue()
 		}
 	}
+	// Training only makes sense for a team.
+	chore.TeamMode = chore.TeamMode || chore.TrainingMode
+
+	if assignee, ok := optionMap["assignee"]; ok {
+		// The draft is assigned once it is scheduled.
+		chore.SetHeldAssignees([]string{assignee.UserValue(nil).ID})
+	}
 
-	chore, err := ui.storage.SaveChore(chore)
+	err := ui.CheckResources(chore.GetResources())
+	if err == nil {
+		chore, err = ui.storage.SaveChore(chore)
+	}
 	if err != nil {
 		ui.logger.Error("failed to save chore", "error", err)
 		ui.discord.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
@@ -912,6 +2060,15 @@ func (ui *Ui) choreCreate(i *discordgo.InteractionCreate) {
 					},
 				},
 
+				discordgo.Container{
+					Components: []discordgo.MessageComponent{
+						&discordgo.TextDisplay{
+							Content: "Assignees (the rest is picked automatically):",
+						},
+						ui.assigneesSelectMenu(chore),
+					},
+				},
+
 				discordgo.Container{
 					Components: []discordgo.MessageComponent{
 						discordgo.ActionsRow{
@@ -976,8 +2133,22 @@ func (ui *Ui) Commands(ctx context.Context, wg *sync.WaitGroup) error {
 				ui.choresOpen(i)
 			case "chores_completed":
 				ui.choresCompleted(i)
+			case "chores_drafts":
+				ui.choresDrafts(i)
 			case "stats":
 				ui.stats(i)
+			case "quiet_hours":
+				ui.quietHours(i)
+			case "away":
+				ui.away(i)
+			case "back":
+				ui.back(i)
+			case "preferences":
+				ui.preferences(i)
+			case "roster":
+				ui.roster(i)
+			case "resources":
+				ui.resources(i)
 			}
 		}
 
@@ -992,7 +2163,7 @@ func (ui *Ui) Commands(ctx context.Context, wg *sync.WaitGroup) error {
 				ui.scheduleChore(data.CustomID, s, i)
 			case strings.HasPrefix(data.CustomID, RejectButtonClick):
 				ui.rejectChore(data.CustomID, s, i)
-			case strings.HasPrefix(data.CustomID, AckButtonClick):
+			case strings.HasPrefix(data.CustomID, AckButtonClick) || strings.HasPrefix(data.CustomID, AckConfirmClick):
 				ui.ackChore(data.CustomID, s, i)
 			case strings.HasPrefix(data.CustomID, DoneButtonClick):
 				ui.doneChore(data.CustomID, s, i)
@@ -1000,6 +2171,18 @@ func (ui *Ui) Commands(ctx context.Context, wg *sync.WaitGroup) error {
 				ui.helpedChore(data.CustomID, s, i)
 			case strings.HasPrefix(data.CustomID, ReportTimeSpentClick):
 				ui.reportTimeSpentButtonClick(data.CustomID, s, i)
+			case strings.HasPrefix(data.CustomID, ReleaseButtonClick):
+				ui.releaseChore(data.CustomID, s, i)
+			case strings.HasPrefix(data.CustomID, KeepButtonClick):
+				ui.keepChore(data.CustomID, s, i)
+			case strings.HasPrefix(data.CustomID, HandoffButtonClick):
+				ui.handoffButtonClick(data.CustomID, s, i)
+			case strings.HasPrefix(data.CustomID, SwapButtonClick):
+				ui.swapButtonClick(data.CustomID, s, i)
+			case strings.HasPrefix(data.CustomID, TransferAcceptClick) || strings.HasPrefix(data.CustomID, TransferDeclineClick):
+				ui.answerTransfer(data.CustomID, s, i)
+			case strings.HasPrefix(data.CustomID, BidButtonClick):
+				ui.bidButtonClick(data.CustomID, s, i)
 			}
 		}
 
@@ -1010,6 +2193,8 @@ func (ui *Ui) Commands(ctx context.Context, wg *sync.WaitGroup) error {
 				ui.reportTimeSpent(s, i)
 			case strings.HasPrefix(data.CustomID, EditChoreModal):
 				ui.editChore(s, i)
+			case strings.HasPrefix(data.CustomID, BidModal):
+				ui.placeBid(s, i)
 			}
 		}
 
@@ -1018,6 +2203,12 @@ func (ui *Ui) Commands(ctx context.Context, wg *sync.WaitGroup) error {
 			switch {
 			case strings.HasPrefix(data.CustomID, SkillsSelectMenu):
 				ui.handleSkillsSelect(data.CustomID, s, i)
+			case strings.HasPrefix(data.CustomID, AssigneesSelectMenu):
+				ui.handleAssigneesSelect(data.CustomID, s, i)
+			case strings.HasPrefix(data.CustomID, HandoffSelectMenu):
+				ui.handleHandoffSelect(data.CustomID, s, i)
+			case strings.HasPrefix(data.CustomID, SwapSelectMenu):
+				ui.handleSwapSelect(data.CustomID, s, i)
 			}
 		}
 
@@ -1075,6 +2266,48 @@ func (ui *Ui) Commands(ctx context.Context, wg *sync.WaitGroup) error {
 					Required:    false,
 					Choices:     skillsChoice,
 				},
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "categories",
+					Description: "Comma separated categories (e.g. cooking, toilets) people can like or dislike.",
+					Required:    false,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionInteger,
+					Name:        "not_before",
+					Description: "Keep the chore as a draft for this many minutes, then publish it. [0]",
+					Required:    false,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "resources",
+					Description: "Comma separated resources the chore needs (e.g. van), it waits while they are in use.",
+					Required:    false,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionInteger,
+					Name:        "auction",
+					Description: "Let people bid their credit for this many minutes, the lowest bids win. [0]",
+					Required:    false,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionUser,
+					Name:        "assignee",
+					Description: "Assign the chore to this person directly. More can be picked before scheduling.",
+					Required:    false,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionBoolean,
+					Name:        "team",
+					Description: "Pick the workers as a team covering the skills, rotating partners. [false]",
+					Required:    false,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionBoolean,
+					Name:        "training",
+					Description: "Pair skilled mentors with trainees in the team. [false]",
+					Required:    false,
+				},
 				{
 					Type:        discordgo.ApplicationCommandOptionInteger,
 					Name:        "deadline",
@@ -1141,6 +2374,11 @@ func (ui *Ui) Commands(ctx context.Context, wg *sync.WaitGroup) error {
 			Description: "Lists unfinished chores.",
 			Type:        discordgo.ChatApplicationCommand,
 		},
+		{
+			Name:        "chores_drafts",
+			Description: "Lists chores waiting for their time to be published.",
+			Type:        discordgo.ChatApplicationCommand,
+		},
 		{
 			Name:        "chores_completed",
 			Description: "Lists completed chores.",
@@ -1151,6 +2389,92 @@ func (ui *Ui) Commands(ctx context.Context, wg *sync.WaitGroup) error {
 			Description: "Display chores stats.",
 			Type:        discordgo.ChatApplicationCommand,
 		},
+		{
+			Name:        "quiet_hours",
+			Description: "Sets the daily hours in which you are not assigned nor reminded. Without options clears them.",
+			Type:        discordgo.ChatApplicationCommand,
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "start",
+					Description: "Start of the quiet hours (HH:MM), e.g. 23:00.",
+					Required:    false,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "end",
+					Description: "End of the quiet hours (HH:MM), e.g. 07:00.",
+					Required:    false,
+				},
+			},
+		},
+		{
+			Name:        "away",
+			Description: "Marks you unavailable for a while, e.g. out kayaking 14:00-18:00.",
+			Type:        discordgo.ChatApplicationCommand,
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "from",
+					Description: "Start (HH:MM).",
+					Required:    true,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "to",
+					Description: "End (HH:MM).",
+					Required:    true,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "reason",
+					Description: "What are you up to.",
+					Required:    false,
+				},
+			},
+		},
+		{
+			Name:        "back",
+			Description: "Clears your away blocks.",
+			Type:        discordgo.ChatApplicationCommand,
+		},
+		{
+			Name:        "roster",
+			Description: "Shows the upcoming duty shifts.",
+			Type:        discordgo.ChatApplicationCommand,
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "duty",
+					Description: "Only this duty, e.g. kitchen.",
+					Required:    false,
+				},
+			},
+		},
+		{
+			Name:        "resources",
+			Description: "Shows the shared resources and which chores hold them.",
+			Type:        discordgo.ChatApplicationCommand,
+		},
+		{
+			Name:        "preferences",
+			Description: "Sets the chore categories or skills you like or avoid. Without options shows them.",
+			Type:        discordgo.ChatApplicationCommand,
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "likes",
+					Description: "Comma separated, e.g. cooking,shopping. - clears the list.",
+					Required:    false,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "dislikes",
+					Description: "Comma separated, e.g. toilets. - clears the list.",
+					Required:    false,
+				},
+			},
+		},
 	}
 
 	// 5. Register the slash commands globally.
@@ -1234,9 +2558,10 @@ func (ui *Ui) handleSkillsSelect(d string, s *discordgo.Session, i *discordgo.In
 	}
 
 	selectedSkills := i.MessageComponentData().Values
-	chore.SetCapabilities(selectedSkills)
-
-	chore, err = ui.storage.SaveChore(chore)
+	chore, err = ui.storage.UpdateChore(chore.ID, func(c *storage.Chore) error {
+		c.SetCapabilities(selectedSkills)
+		return nil
+	})
 	if err != nil {
 		ui.logger.Error("failed to save chore", "error", err, "chore_id", choreId)
 		s.InteractionRespond(i.Interaction, ui.errorInteractionResponse(failedText))
@@ -1266,44 +2591,105 @@ func (ui *Ui) handleSkillsSelect(d string, s *discordgo.Session, i *discordgo.In
 	s.InteractionRespond(i.Interaction, r)
 }
 
-func (ui *Ui) EditChoreDetails(choreId uint, name string, necessaryWorkers, estimatedTimeMin, assignmentTimeoutMin uint, deadline *time.Time, capabilities []string) (storage.Chore, error) {
-	chore, err := ui.storage.GetChore(choreId)
-	if err != nil {
-		return chore, fmt.Errorf("failed to get chore: %w", err)
-	}
+// ChoreEdit holds the changed details of a chore, nil fields keep their current value.
+type ChoreEdit struct {
+	Name                 *string
+	NecessaryWorkers     *uint
+	EstimatedTimeMin     *uint
+	AssignmentTimeoutMin *uint
+	Deadline             *time.Time
+	Capabilities         []string
+	Categories           []string
+	Resources            []string
+	TeamMode             *bool
+	TrainingMode         *bool
+	NotBefore            *time.Time // Moves the publishing time of a draft, a past time publishes it right away.
+}
 
-	if name != "" {
-		chore.Name = name
-	}
-	if necessaryWorkers > 0 {
-		chore.NecessaryWorkers = necessaryWorkers
-	}
-	if estimatedTimeMin > 0 {
-		chore.EstimatedTimeMin = estimatedTimeMin
-	}
-	chore.AssignmentTimeoutMin = assignmentTimeoutMin
-	if deadline != nil {
-		chore.Deadline = deadline
+// EditChoreDetails applies the edit to the current state of the chore in one update. A non-zero version has to
+// match the version of the chore, otherwise nothing changes and storage.ErrVersionConflict is returned.
+func (ui *Ui) EditChoreDetails(choreId, version uint, edit ChoreEdit) (storage.Chore, error) {
+	if edit.Resources != nil {
+		if err := ui.CheckResources(edit.Resources); err != nil {
+			return storage.Chore{}, err
+		}
 	}
-	if capabilities != nil {
-		chore.SetCapabilities(capabilities)
+	chore, err := ui.storage.UpdateChore(choreId, func(c *storage.Chore) error {
+		if version != 0 && c.Version != version {
+			return storage.ErrVersionConflict
+		}
+		if edit.NotBefore != nil {
+			if c.Status != storage.ChoreDraft {
+				return fmt.Errorf("chore `id: %d`: %w", choreId, chores.ErrChorePublished)
+			}
+			c.NotBefore = edit.NotBefore
+		}
+		if edit.Name != nil {
+			c.Name = *edit.Name
+		}
+		if edit.NecessaryWorkers != nil {
+			c.NecessaryWorkers = *edit.NecessaryWorkers
+		}
+		if edit.EstimatedTimeMin != nil {
+			c.EstimatedTimeMin = *edit.EstimatedTimeMin
+		}
+		if edit.AssignmentTimeoutMin != nil {
+			c.AssignmentTimeoutMin = *edit.AssignmentTimeoutMin
+		}
+		if edit.Deadline != nil {
+			c.Deadline = edit.Deadline
+		}
+		if edit.Capabilities != nil {
+			c.SetCapabilities(edit.Capabilities)
+		}
+		if edit.Categories != nil {
+			c.SetCategories(edit.Categories)
+		}
+		if edit.Resources != nil {
+			c.SetResources(edit.Resources)
+		}
+		if edit.TeamMode != nil {
+			c.TeamMode = *edit.TeamMode
+		}
+		if edit.TrainingMode != nil {
+			c.TrainingMode = *edit.TrainingMode
+		}
+		// Training only makes sense for a team.
+		c.TeamMode = c.TeamMode || c.TrainingMode
+		return nil
+	})
+	if errors.Is(err, storage.ErrVersionConflict) || errors.Is(err, chores.ErrChorePublished) {
+		return chore, err
 	}
-
-	chore, err = ui.storage.SaveChore(chore)
 	if err != nil {
 		return chore, fmt.Errorf("failed to update chore: %w", err)
 	}
 
+	if edit.NotBefore != nil && !edit.NotBefore.After(time.Now()) {
+		chore, _, err = ui.PublishChore(chore)
+		return chore, err
+	}
 	_ = ui.UpdateChoreMessage(chore)
 	ui.EmitChoreEvent("chore_updated", chore)
 	return chore, nil
 }
 
+// validateChoreNumbers checks the numbers typed into the edit dialog, the same limits as the API enforces.
+func validateChoreNumbers(workers, estimatedMin, timeoutMin, deadlineMin int) error {
+	if workers < 1 {
+		return fmt.Errorf("a chore needs at least one worker")
+	}
+	if estimatedMin < 0 || timeoutMin < 0 || deadlineMin < 0 {
+		return fmt.Errorf("the times cannot be negative")
+	}
+	return nil
+}
+
 func (ui *Ui) editChore(s *discordgo.Session, i *discordgo.InteractionCreate) {
 	failedText := "Failed to edit chore."
 	data := i.Interaction.ModalSubmitData()
 
-	choreId, err := getChoreIdFromCustomID(data.CustomID)
+	choreId, version, err := getChoreVersionFromCustomID(data.CustomID)
 	if err != nil {
 		ui.logger.Error("failed to parse chore ID from modal", "error", err, "custom_id", data.CustomID)
 		s.InteractionRespond(i.Interaction, ui.errorInteractionResponse(failedText))
@@ -1332,8 +2718,25 @@ func (ui *Ui) editChore(s *discordgo.Session, i *discordgo.InteractionCreate) {
 		return
 	}
 
+	if err := validateChoreNumbers(updatedNecessaryWorkers, updatedEstimatedTimeMin, updatedAssignmentTimeoutMin, updatedDeadlineMin); err != nil {
+		s.InteractionRespond(i.Interaction, ui.errorInteractionResponse(err.Error()))
+		return
+	}
+
 	deadline := time.Now().Add(time.Duration(updatedDeadlineMin) * time.Minute)
-	chore, err := ui.EditChoreDetails(choreId, updatedName, uint(updatedNecessaryWorkers), uint(updatedEstimatedTimeMin), uint(updatedAssignmentTimeoutMin), &deadline, nil)
+	workers, estimated, timeout := uint(updatedNecessaryWorkers), uint(updatedEstimatedTimeMin), uint(updatedAssignmentTimeoutMin)
+	chore, err := ui.EditChoreDetails(choreId, version, ChoreEdit{
+		Name:                 &updatedName,
+		NecessaryWorkers:     &workers,
+		EstimatedTimeMin:     &estimated,
+		AssignmentTimeoutMin: &timeout,
+		Deadline:             &deadline,
+	})
+	if errors.Is(err, storage.ErrVersionConflict) {
+		ui.logger.Info("conflicting chore edit", "chore_id", choreId, "version", version)
+		s.InteractionRespond(i.Interaction, ui.errorInteractionResponse(fmt.Sprintf("Chore `id: %d` was changed by somebody else while you were editing it, nothing was saved. Open the edit dialog again to see the current details.", choreId)))
+		return
+	}
 	if err != nil {
 		ui.logger.Error("failed to update chore", "error", err, "chore_id", choreId)
 		s.InteractionRespond(i.Interaction, ui.errorInteractionResponse(failedText))
@@ -1352,6 +2755,16 @@ func (ui *Ui) editChore(s *discordgo.Session, i *discordgo.InteractionCreate) {
 		},
 	}
 	r.Data.Components = append(r.Data.Components, container)
+	if chore.Completed == nil && chore.Cancelled == nil {
+		r.Data.Components = append(r.Data.Components, &discordgo.Container{
+			Components: []discordgo.MessageComponent{
+				&discordgo.TextDisplay{
+					Content: "Assignees (the rest is picked automatically):",
+				},
+				ui.assigneesSelectMenu(chore),
+			},
+		})
+	}
 	s.InteractionRespond(i.Interaction, r)
 }
 
@@ -1406,14 +2819,14 @@ func (ui *Ui) ReportTimeSpent(choreId uint, userId string, timeSpentMin uint) (s
 			wl = storage.WorkLog{
 				ChoreId:      chore.ID,
 				UserId:       userId,
-				TimeSpentMin: timeSpentMin,
+				TimeSpentMin: chore.Credit(timeSpentMin),
 				SelfReported: true,
 			}
 		} else {
 			return wl, fmt.Errorf("failed to get work log: %w", err)
 		}
 	} else {
-		wl.TimeSpentMin = timeSpentMin
+		wl.TimeSpentMin = chore.Credit(timeSpentMin)
 	}
 
 	wl, err = ui.storage.SaveWorkLog(wl)
@@ -1647,6 +3060,14 @@ func (ui *Ui) choresList(i *discordgo.InteractionCreate) {
 	ui.discord.InteractionRespond(i.Interaction, r)
 }
 
+// bountyMd tells the worker how many minutes they got credited thanks to the bounty.
+func bountyMd(chore storage.Chore, wl storage.WorkLog) string {
+	if chore.BountyMultiplier() <= 1 {
+		return ""
+	}
+	return fmt.Sprintf("\nThanks to the `x%.2f` bounty you got `%d` minutes credited.", chore.BountyMultiplier(), wl.TimeSpentMin)
+}
+
 func (ui *Ui) HelpedChore(choreId uint, userId string) (storage.WorkLog, error) {
 	var wl storage.WorkLog
 	chore, err := ui.storage.GetChore(choreId)
@@ -1662,7 +3083,7 @@ func (ui *Ui) HelpedChore(choreId uint, userId string) (storage.WorkLog, error)
 	wl = storage.WorkLog{
 		ChoreId:      chore.ID,
 		UserId:       userId,
-		TimeSpentMin: chore.EstimatedTimeMin,
+		TimeSpentMin: chore.Credit(chore.EstimatedTimeMin),
 		SelfReported: true,
 	}
 	wl, err = ui.storage.SaveWorkLog(wl)
@@ -1674,7 +3095,7 @@ func (ui *Ui) HelpedChore(choreId uint, userId string) (storage.WorkLog, error)
 
 	if ui.discord != nil && userId != "" {
 		_ = ui.SendDM(userId, &discordgo.MessageSend{
-			Content: fmt.Sprintf("Chore `id: %d` `%s` has been completed %s. Thank you for your work!\nYou spent `%d` minutes on this chore (which was the estimate of the chore creator).", choreId, chore.Name, ui.GetChoreMessageUrl(chore), wl.TimeSpentMin),
+			Content: fmt.Sprintf("Chore `id: %d` `%s` has been completed %s. Thank you for your work!\nYou spent `%d` minutes on this chore (which was the estimate of the chore creator).%s", choreId, chore.Name, ui.GetChoreMessageUrl(chore), chore.EstimatedTimeMin, bountyMd(chore, wl)),
 			Components: []discordgo.MessageComponent{
 				discordgo.ActionsRow{
 					Components: []discordgo.MessageComponent{
@@ -1714,32 +3135,17 @@ func (ui *Ui) helpedChore(d string, s *discordgo.Session, i *discordgo.Interacti
 }
 
 func (ui *Ui) CompleteChore(choreId uint) (storage.Chore, error) {
-	chore, err := ui.storage.GetChore(choreId)
-	if err != nil {
-		return chore, fmt.Errorf("failed to get chore: %w", err)
-	}
-	if chore.Completed != nil {
-		return chore, fmt.Errorf("chore has already been completed")
-	}
-	if chore.Cancelled != nil {
-		return chore, fmt.Errorf("chore has been cancelled")
-	}
-
-	chore.Complete()
-	chore, err = ui.storage.SaveChore(chore)
+	chore, err := ui.transitionChore(choreId, storage.ChoreDone)
 	if err != nil {
-		return chore, fmt.Errorf("failed to save chore: %w", err)
+		return chore, err
 	}
+	_ = ui.storage.ClearWaitlist(choreId)
 
 	ass, err := ui.storage.GetChoreAssignments(choreId)
 	if err == nil {
 		for _, a := range ass {
-			if a.Refused != nil || a.Acked != nil {
-				continue
-			}
-			if a.Timeouted == nil {
-				a.Timeout()
-				_, _ = ui.storage.SaveChoreAssignment(a)
+			if a.Active() && a.Acked == nil {
+				_, _ = ui.storage.UpdateChoreAssignment(a.ID, storage.TimeoutPending)
 			}
 		}
 
@@ -1750,13 +3156,17 @@ func (ui *Ui) CompleteChore(choreId uint) (storage.Chore, error) {
 			wl := storage.WorkLog{
 				ChoreId:      chore.ID,
 				UserId:       a.UserId,
-				TimeSpentMin: chore.EstimatedTimeMin,
+				TimeSpentMin: chore.Credit(a.CreditedMin(chore)),
 			}
 			_, _ = ui.storage.SaveWorkLog(wl)
 
+			source := "which was the estimate of the chore creator"
+			if a.BidMin > 0 {
+				source = "which was your winning bid"
+			}
 			if ui.discord != nil && a.UserId != "" {
 				_ = ui.SendDM(a.UserId, &discordgo.MessageSend{
-					Content: fmt.Sprintf("Chore `id: %d` `%s` has been completed %s. Thank you for your work!\nYou spent `%d` minutes on this chore (which was the estimate of the chore creator).", choreId, chore.Name, ui.GetChoreMessageUrl(chore), wl.TimeSpentMin),
+					Content: fmt.Sprintf("Chore `id: %d` `%s` has been completed %s. Thank you for your work!\nYou spent `%d` minutes on this chore (%s).%s", choreId, chore.Name, ui.GetChoreMessageUrl(chore), a.CreditedMin(chore), source, bountyMd(chore, wl)),
 					Components: []discordgo.MessageComponent{
 						discordgo.ActionsRow{
 							Components: []discordgo.MessageComponent{
@@ -68,3 +68,55 @@ func TestGetChoreIdFromButton(t *testing.T) {
 		})
 	}
 }
+
+func TestGetChoreVersionFromCustomID(t *testing.T) {
+	tests := []struct {
+		name        string
+		customID    string
+		wantID      uint
+		wantVersion uint
+		expectErr   bool
+	}{
+		{"with version", "edit_modal_submit:12:3", 12, 3, false},
+		{"without version", "edit_modal_submit:12", 12, 0, false},
+		{"invalid version", "edit_modal_submit:12:abc", 0, 0, true},
+		{"too many parts", "edit_modal_submit:12:3:4", 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotID, gotVersion, err := getChoreVersionFromCustomID(tt.customID)
+			if (err != nil) != tt.expectErr {
+				t.Fatalf("getChoreVersionFromCustomID() error = %v, expectErr %v", err, tt.expectErr)
+			}
+			if gotID != tt.wantID || gotVersion != tt.wantVersion {
+				t.Errorf("getChoreVersionFromCustomID() = %d, %d, want %d, %d", gotID, gotVersion, tt.wantID, tt.wantVersion)
+			}
+		})
+	}
+}
+
+func TestValidateChoreNumbers(t *testing.T) {
+	tests := []struct {
+		name                                  string
+		workers, estimated, timeout, deadline int
+		expectErr                             bool
+	}{
+		{"valid", 2, 30, 15, 60, false},
+		{"zero times", 1, 0, 0, 0, false},
+		{"no workers", 0, 30, 15, 60, true},
+		{"negative workers", -1, 30, 15, 60, true},
+		{"negative estimate", 1, -5, 15, 60, true},
+		{"negative timeout", 1, 30, -1, 60, true},
+		{"negative deadline", 1, 30, 15, -60, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateChoreNumbers(tt.workers, tt.estimated, tt.timeout, tt.deadline)
+			if (err != nil) != tt.expectErr {
+				t.Errorf("validateChoreNumbers() error = %v, expectErr %v", err, tt.expectErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/gdg-garage/garage-trip-chores/chores"
+	"github.com/gdg-garage/garage-trip-chores/storage"
+)
+
+// promoteWaitlist acks the users waiting for the chore into the slots freed by a rejection or a release.
+func (ui *Ui) promoteWaitlist(c storage.Chore) (storage.Chore, error) {
+	promoted, err := ui.storage.PromoteWaitlist(c.ID, func(c *storage.Chore) error {
+		return chores.Transition(c, storage.ChoreInProgress)
+	})
+	if errors.Is(err, chores.ErrInvalidTransition) {
+		return c, nil
+	}
+	if err != nil {
+		return c, fmt.Errorf("failed to promote waitlist: %w", err)
+	}
+	if len(promoted) == 0 {
+		return c, nil
+	}
+	c = promoted[0].Chore
+	for _, a := range promoted {
+		ui.logger.Info("User promoted from waitlist", "chore_id", c.ID, "user_id", a.UserId)
+		if ui.discord != nil && a.UserId != "" {
+			ui.sendAckedDM(c, a.UserId, "A slot freed up, you were moved from the waitlist to the chore")
+		}
+	}
+	return c, nil
+}
+
+// sendAckedDM sends the acked user the buttons to finish or hand off the chore.
+func (ui *Ui) sendAckedDM(c storage.Chore, userId string, text string) {
+	_ = ui.SendDM(userId, &discordgo.MessageSend{
+		Content: fmt.Sprintf("%s `id: %d` `%s` %s.", text, c.ID, c.Name, ui.GetChoreMessageUrl(c)),
+		Components: []discordgo.MessageComponent{
+			discordgo.ActionsRow{
+				Components: []discordgo.MessageComponent{
+					&discordgo.Button{
+						Style:    discordgo.SuccessButton,
+						Label:    "Done!",
+						CustomID: DoneButtonClick + fmt.Sprint(c.ID),
+					},
+					&discordgo.Button{
+						Style:    discordgo.SecondaryButton,
+						Label:    "Hand off",
+						CustomID: HandoffButtonClick + fmt.Sprint(c.ID),
+					},
+				},
+			},
+		},
+	})
+}
+
+func (ui *Ui) generateWaitlistEmbed(entries []storage.WaitlistEntry) *discordgo.MessageEmbed {
+	if len(entries) == 0 {
+		return nil
+	}
+	md := ""
+	for i, e := range entries {
+		md += fmt.Sprintf("%d. <@%s>\n", i+1, e.UserId)
+	}
+	return &discordgo.MessageEmbed{
+		Type:        discordgo.EmbedTypeRich,
+		Title:       "Waitlist",
+		Description: md,
+		Color:       ui.colors.OrangeColor,
+	}
+}
@@ -0,0 +1,208 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gdg-garage/garage-trip-chores/storage"
+)
+
+type Config struct {
+	CheckPeriodSeconds int
+	TimeoutSeconds     int
+	MaxAttempts        uint // Failed attempts after which a delivery is dead-lettered.
+	BackoffSeconds     int  // Wait before the first retry, doubled with every further failure.
+}
+
+// Signature headers of the deliveries, the signature is the hex HMAC-SHA256 of "<timestamp>.<body>".
+const (
+	EventHeader     = "X-Chores-Event"
+	DeliveryHeader  = "X-Chores-Delivery"
+	TimestampHeader = "X-Chores-Timestamp"
+	SignatureHeader = "X-Chores-Signature"
+)
+
+// Dispatcher queues every event for the subscribed webhooks and delivers the queue.
+type Dispatcher struct {
+	storage *storage.Storage
+	logger  *slog.Logger
+	conf    Config
+	client  *http.Client
+}
+
+func NewDispatcher(storage *storage.Storage, logger *slog.Logger, conf Config) *Dispatcher {
+	return &Dispatcher{
+		storage: storage,
+		logger:  logger,
+		conf:    conf,
+		client:  &http.Client{Timeout: time.Duration(conf.TimeoutSeconds) * time.Second},
+	}
+}
+
+// Sign returns the signature of the body sent at the timestamp.
+func Sign(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Enqueue stores a delivery of the event for every webhook subscribed to its type.
+func (d *Dispatcher) Enqueue(e storage.Event) {
+	webhooks, err := d.storage.GetWebhooks()
+	if err != nil {
+		d.logger.Error("failed to get webhooks", "error", err)
+		return
+	}
+	payload, err := json.Marshal(e)
+	if err != nil {
+		d.logger.Error("failed to encode event", "error", err, "type", e.Type)
+		return
+	}
+	for _, w := range webhooks {
+		if !w.Wants(e.Type) {
+			continue
+		}
+		if _, err := d.storage.QueueDelivery(w.ID, e.Type, string(payload)); err != nil {
+			d.logger.Error("failed to queue webhook delivery", "error", err, "webhook_id", w.ID)
+		}
+	}
+}
+
+// DeliverDue sends the deliveries whose attempt has come. Every webhook is served concurrently,
+// so a slow endpoint does not hold up the others, and a webhook that fails waits for its backoff
+// before the rest of its queue is tried.
+func (d *Dispatcher) DeliverDue() {
+	due, err := d.storage.GetDueDeliveries(time.Now())
+	if err != nil {
+		d.logger.Error("failed to get due webhook deliveries", "error", err)
+		return
+	}
+	queues := map[uint][]storage.WebhookDelivery{}
+	for _, del := range due {
+		queues[del.WebhookId] = append(queues[del.WebhookId], del)
+	}
+
+	var wg sync.WaitGroup
+	for webhookId, queue := range queues {
+		w, err := d.storage.GetWebhook(webhookId)
+		if err != nil {
+			d.logger.Error("failed to get webhook", "error", err, "webhook_id", webhookId)
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, del := range queue {
+				if !d.attempt(w, del) {
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// attempt sends the delivery and reports whether it was delivered.
+func (d *Dispatcher) attempt(w storage.Webhook, del storage.WebhookDelivery) bool {
+	del.Attempts++
+	code, err := d.post(w, del)
+	del.ResponseCode = code
+	if err == nil {
+		now := time.Now()
+		del.Status = storage.DeliveryDelivered
+		del.Delivered = &now
+		del.LastError = ""
+	} else {
+		del.LastError = err.Error()
+		if del.Attempts >= d.conf.MaxAttempts {
+			del.Status = storage.DeliveryDead
+			d.logger.Warn("Webhook delivery dead-lettered", "delivery_id", del.ID, "webhook_id", w.ID, "error", err)
+		} else {
+			del.NextAttempt = time.Now().Add(d.backoff(del.Attempts))
+		}
+	}
+	if _, err := d.storage.SaveDelivery(del); err != nil {
+		d.logger.Error("failed to save webhook delivery", "error", err, "delivery_id", del.ID)
+	}
+	return del.Status == storage.DeliveryDelivered
+}
+
+// backoff returns the wait after the given number of failed attempts.
+func (d *Dispatcher) backoff(attempts uint) time.Duration {
+	wait := time.Duration(d.conf.BackoffSeconds) * time.Second
+	for i := uint(1); i < attempts && wait < 24*time.Hour; i++ {
+		wait *= 2
+	}
+	return wait
+}
+
+func (d *Dispatcher) post(w storage.Webhook, del storage.WebhookDelivery) (int, error) {
+	body := []byte(del.Payload)
+	req, err := http.NewRequest(http.MethodPost, w.Url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, string(del.EventType))
+	req.Header.Set(DeliveryHeader, strconv.FormatUint(uint64(del.ID), 10))
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(SignatureHeader, Sign(w.Secret, timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("webhook responded %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// RunDispatcher queues the events as they come and delivers the queue in the background,
+// so a slow webhook never holds up the events.
+func (d *Dispatcher) RunDispatcher(ctx context.Context, wg *sync.WaitGroup) {
+	wg.Add(1)
+	defer wg.Done()
+	sub := d.storage.Events.Subscribe()
+	defer d.storage.Events.Unsubscribe(sub)
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(time.Duration(d.conf.CheckPeriodSeconds) * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				d.DeliverDue()
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			d.logger.Debug("Webhook dispatcher stopped: context cancelled", "reason", ctx.Err())
+			return
+		case event := <-sub:
+			d.Enqueue(event)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package webhooks
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gdg-garage/garage-trip-chores/storage"
+)
+
+func setupStorage(t *testing.T) *storage.Storage {
+	t.Helper()
+	tmpDir := t.TempDir()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	s, err := storage.New(storage.Config{DbPath: tmpDir + "/test.sqlite", Timezone: "UTC"}, logger)
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	return s
+}
+
+func TestDeliveriesAreSignedRetriedAndDeadLettered(t *testing.T) {
+	s := setupStorage(t)
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	d := NewDispatcher(s, logger, Config{TimeoutSeconds: 5, MaxAttempts: 3, BackoffSeconds: 0})
+
+	var mu sync.Mutex
+	failing := true
+	received := []*http.Request{}
+	bodies := []string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		body, _ := io.ReadAll(r.Body)
+		received = append(received, r)
+		bodies = append(bodies, string(body))
+		if failing {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	all, _ := s.SaveWebhook(storage.Webhook{Url: srv.URL, Secret: "s3cret"})
+	acks := storage.Webhook{Url: srv.URL + "/acks"}
+	acks.SetEventTypes([]string{string(storage.TaskAcked)})
+	acks, _ = s.SaveWebhook(acks)
+
+	d.Enqueue(storage.Event{Type: storage.TaskCreated, Chore: &storage.Chore{ID: 7, Name: "Dig"}})
+	d.DeliverDue()
+	if len(received) != 1 {
+		t.Fatalf("Expected only the webhook for all events to be called, got %d calls", len(received))
+	}
+	r := received[0]
+	if r.Header.Get(EventHeader) != "task_created" || r.Header.Get(SignatureHeader) != Sign("s3cret", r.Header.Get(TimestampHeader), []byte(bodies[0])) {
+		t.Fatalf("Expected a signed task_created delivery, got headers %v", r.Header)
+	}
+
+	// The failures are retried until the attempts run out.
+	for range 5 {
+		d.DeliverDue()
+	}
+	dead, _ := s.GetDeliveries(all.ID, storage.DeliveryDead, 10)
+	if len(received) != 3 || len(dead) != 1 || dead[0].Attempts != 3 || dead[0].ResponseCode != http.StatusServiceUnavailable {
+		t.Fatalf("Expected 3 attempts and a dead delivery, got %d calls and %+v", len(received), dead)
+	}
+
+	failing = false
+	if _, err := s.RetryDelivery(dead[0].ID); err != nil {
+		t.Fatalf("Failed to retry delivery: %v", err)
+	}
+	d.Enqueue(storage.Event{Type: storage.TaskAcked, Assignment: &storage.ChoreAssignment{ChoreId: 7, UserId: "u1"}})
+	d.DeliverDue()
+	delivered, _ := s.GetDeliveries(all.ID, storage.DeliveryDelivered, 10)
+	ackDelivered, _ := s.GetDeliveries(acks.ID, storage.DeliveryDelivered, 10)
+	if len(delivered) != 2 || len(ackDelivered) != 1 {
+		t.Fatalf("Expected the retried and the acked event delivered, got %+v and %+v", delivered, ackDelivered)
+	}
+}
+
+func TestBackoffDoubles(t *testing.T) {
+	d := NewDispatcher(nil, nil, Config{BackoffSeconds: 10})
+	for attempts, want := range map[uint]time.Duration{1: 10 * time.Second, 2: 20 * time.Second, 4: 80 * time.Second} {
+		if got := d.backoff(attempts); got != want {
+			t.Errorf("Expected %v after %d attempts, got %v", want, attempts, got)
+		}
+	}
+}
+
+func TestSlowWebhookDoesNotDelayOthers(t *testing.T) {
+	s := setupStorage(t)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	d := NewDispatcher(s, logger, Config{TimeoutSeconds: 5, MaxAttempts: 3, BackoffSeconds: 60})
+
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer slow.Close()
+	fastCalled := make(chan struct{}, 10)
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fastCalled <- struct{}{}
+	}))
+	defer fast.Close()
+
+	slowHook, _ := s.SaveWebhook(storage.Webhook{Url: slow.URL})
+	s.SaveWebhook(storage.Webhook{Url: fast.URL})
+	d.Enqueue(storage.Event{Type: storage.TaskCreated, Chore: &storage.Chore{ID: 1}})
+	d.Enqueue(storage.Event{Type: storage.TaskDone, Chore: &storage.Chore{ID: 1}})
+
+	done := make(chan struct{})
+	go func() {
+		d.DeliverDue()
+		close(done)
+	}()
+	for range 2 {
+		select {
+		case <-fastCalled:
+		case <-time.After(2 * time.Second):
+			t.Fatal("Expected the fast webhook to be called while the slow one hangs")
+		}
+	}
+	close(release)
+	<-done
+
+	// The failed webhook waits for its backoff instead of trying the rest of its queue.
+	pending, _ := s.GetDeliveries(slowHook.ID, storage.DeliveryPending, 10)
+	attempted := 0
+	for _, del := range pending {
+		attempted += int(del.Attempts)
+	}
+	if len(pending) != 2 || attempted != 1 {
+		t.Fatalf("Expected one attempt of the slow webhook and both deliveries pending, got %+v", pending)
+	}
+}